@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"love-connection/backend/internal/database"
+	"love-connection/backend/internal/services"
+)
+
+// Разовое заполнение достижений для существующих пользователей.
+// Уведомления при этом не отправляются.
+func main() {
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	if err := database.RunMigrations(db); err != nil {
+		log.Fatal("Failed to run migrations:", err)
+	}
+
+	unlocked, err := services.BackfillAchievements(db)
+	if err != nil {
+		log.Fatal("Failed to backfill achievements:", err)
+	}
+
+	fmt.Printf("Achievements backfilled: %d unlocked\n", unlocked)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"love-connection/backend/internal/database"
+	"love-connection/backend/internal/services"
+	"os"
+)
+
+// Пересчет и проверка дневных и часовых агрегатов статистики.
+//
+//	go run ./cmd/rollups -rebuild   пересчитать агрегаты с нуля
+//	go run ./cmd/rollups -verify    сравнить агрегаты с love_events
+func main() {
+	rebuild := flag.Bool("rebuild", false, "rebuild all stats rollups from love_events")
+	verify := flag.Bool("verify", false, "verify that stats rollups match raw love_events aggregates")
+	flag.Parse()
+
+	if !*rebuild && !*verify {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	if err := database.RunMigrations(db); err != nil {
+		log.Fatal("Failed to run migrations:", err)
+	}
+
+	if *rebuild {
+		if err := services.RebuildRollups(db); err != nil {
+			log.Fatal("Failed to rebuild rollups:", err)
+		}
+		fmt.Println("Rollups rebuilt")
+	}
+
+	if *verify {
+		mismatches, err := services.VerifyRollups(db)
+		if err != nil {
+			log.Fatal("Failed to verify rollups:", err)
+		}
+
+		for _, mismatch := range mismatches {
+			fmt.Println(mismatch)
+		}
+
+		if len(mismatches) > 0 {
+			fmt.Printf("%d rollup rows do not match love_events\n", len(mismatches))
+			os.Exit(1)
+		}
+		fmt.Println("Rollups match love_events")
+	}
+}
@@ -1,15 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"love-connection/backend/internal/api"
 	"love-connection/backend/internal/database"
+	"love-connection/backend/internal/i18n"
+	"love-connection/backend/internal/services"
 	"love-connection/backend/internal/websocket"
+	"love-connection/backend/pkg/storage"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 )
 
+// shutdownTimeout — сколько ждать завершения запросов и начатых отправок пушей при остановке.
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	db, err := database.Connect()
 	if err != nil {
@@ -21,19 +34,70 @@ func main() {
 		log.Fatal("Failed to run migrations:", err)
 	}
 
+	// Ошибка в LOCALES_DIR должна остановить запуск, а не всплыть при первом пуше
+	if _, err := i18n.Default(); err != nil {
+		log.Fatal("Failed to load notification locales:", err)
+	}
+
 	hub := websocket.NewHub()
+
+	exportStore, err := storage.NewFromEnv("EXPORT", "data/exports")
+	if err != nil {
+		log.Fatal("Failed to set up export storage:", err)
+	}
+	if !services.ExportsEnabled() {
+		log.Printf("Data exports are disabled: set EXPORT_SIGNING_SECRET or JWT_SECRET")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go services.NewLoveScheduler(db, hub).Run(ctx)
+	go services.NewReminderScheduler(db).Run(ctx)
+	go services.NewRollupWorker(db).Run(ctx)
+	go services.NewStreakWatcher(db).Run(ctx)
+	go services.NewRecapWorker(db).Run(ctx)
+	go services.NewMemoryScheduler(db).Run(ctx)
+	go services.NewExportWorker(db, exportStore).Run(ctx)
+
+	outboxDone := make(chan struct{})
+	go func() {
+		services.NewOutboxWorker(db).Run(ctx)
+		close(outboxDone)
+	}()
+
 	r := gin.Default()
 
-	api.SetupRoutes(r, db, hub)
+	api.SetupRoutes(r, db, hub, exportStore)
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("Shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Failed to shut down server gracefully: %v", err)
+	}
+
+	// Неотправленные уведомления остаются в outbox и уйдут после перезапуска
+	select {
+	case <-outboxDone:
+	case <-shutdownCtx.Done():
+		log.Printf("Notification outbox did not drain in %s", shutdownTimeout)
 	}
 }
 
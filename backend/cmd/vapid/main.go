@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"love-connection/backend/pkg/webpush"
+)
+
+// Генерирует пару VAPID-ключей для Web Push.
+func main() {
+	publicKey, privateKey, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		log.Fatal("Failed to generate VAPID keys:", err)
+	}
+
+	fmt.Printf("VAPID_PUBLIC_KEY=%s\n", publicKey)
+	fmt.Printf("VAPID_PRIVATE_KEY=%s\n", privateKey)
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"database/sql"
+	"love-connection/backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AchievementHandler struct {
+	db *sql.DB
+}
+
+func NewAchievementHandler(db *sql.DB) *AchievementHandler {
+	return &AchievementHandler{db: db}
+}
+
+func (h *AchievementHandler) GetAchievements(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	achievements, err := services.ListAchievements(h.db, currentUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch achievements"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    achievements,
+	})
+}
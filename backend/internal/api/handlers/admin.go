@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"database/sql"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type AdminHandler struct {
+	db *sql.DB
+}
+
+func NewAdminHandler(db *sql.DB) *AdminHandler {
+	return &AdminHandler{db: db}
+}
+
+// GetDevices показывает устройства со счетчиками ошибок доставки пушей.
+func (h *AdminHandler) GetDevices(c *gin.Context) {
+	var filter models.AdminDeviceFilter
+
+	if value := c.Query("user_id"); value != "" {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	filter.FailingOnly = c.Query("failing") == "true"
+
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if limit != nil {
+		filter.Limit = *limit
+	}
+
+	devices, err := services.ListAdminDevices(h.db, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    devices,
+	})
+}
+
+// GetOutbox показывает уведомления outbox с заданным статусом (по умолчанию dead).
+func (h *AdminHandler) GetOutbox(c *gin.Context) {
+	status := c.DefaultQuery("status", models.OutboxStatusDead)
+	switch status {
+	case models.OutboxStatusPending, models.OutboxStatusProcessing, models.OutboxStatusSent,
+		models.OutboxStatusSkipped, models.OutboxStatusDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count := 0
+	if limit != nil {
+		count = *limit
+	}
+
+	messages, err := services.ListOutboxMessages(h.db, status, count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch outbox"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    messages,
+	})
+}
+
+// RetryOutbox возвращает уведомление из dead letters в очередь.
+func (h *AdminHandler) RetryOutbox(c *gin.Context) {
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	err = services.RetryOutboxMessage(h.db, messageID)
+	if err == services.ErrOutboxMessageNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Dead message not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry message"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type DeviceHandler struct {
+	db *sql.DB
+}
+
+func NewDeviceHandler(db *sql.DB) *DeviceHandler {
+	return &DeviceHandler{db: db}
+}
+
+func (h *DeviceHandler) GetDevices(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	devices, err := services.ListDevices(h.db, currentUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch devices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    devices,
+	})
+}
+
+// RegisterDevice вызывается приложением при каждом запуске: так обновляются токен и last_seen_at.
+func (h *DeviceHandler) RegisterDevice(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	var req models.RegisterDeviceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, err := services.RegisterDevice(h.db, currentUserID, req)
+	if errors.Is(err, services.ErrInvalidDevice) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    device,
+	})
+}
+
+func (h *DeviceHandler) UnregisterDevice(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid device ID"})
+		return
+	}
+
+	err = services.UnregisterDevice(h.db, currentUserID, deviceID)
+	if err == services.ErrDeviceNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Device not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unregister device"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
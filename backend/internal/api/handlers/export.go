@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"love-connection/backend/internal/services"
+	"love-connection/backend/pkg/storage"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type ExportHandler struct {
+	db    *sql.DB
+	store storage.Storage
+}
+
+func NewExportHandler(db *sql.DB, store storage.Storage) *ExportHandler {
+	return &ExportHandler{db: db, store: store}
+}
+
+func (h *ExportHandler) RequestExport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	export, err := services.RequestDataExport(h.db, currentUserID)
+	if err == services.ErrExportRateLimited {
+		retryAt := services.ExportRetryAt(export)
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(retryAt).Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":    "Data export was already requested recently",
+			"retry_at": retryAt,
+			"data":     export,
+		})
+		return
+	}
+
+	if err == services.ErrExportsDisabled {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Data export is not configured"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request data export"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    export,
+	})
+}
+
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	exportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	export, err := services.GetDataExport(h.db, currentUserID, exportID)
+	if err == services.ErrExportNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+
+	if err == services.ErrExportsDisabled {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Data export is not configured"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch data export"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    export,
+	})
+}
+
+// Download отдает архив по подписанной ссылке; авторизация не требуется.
+func (h *ExportHandler) Download(c *gin.Context) {
+	exportID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	err = services.VerifyExportSignature(exportID, c.Query("expires"), c.Query("signature"))
+	if err == services.ErrExportsDisabled {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Data export is not configured"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Download link is invalid or expired"})
+		return
+	}
+
+	archive, err := services.OpenDataExport(h.db, h.store, exportID)
+	if err == services.ErrExportNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open data export"})
+		return
+	}
+	defer archive.Close()
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="love-connection-export-%s.zip"`, exportID))
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, archive); err != nil {
+		fmt.Printf("Failed to stream data export %s: %v\n", exportID, err)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (h *PairHandler) GetGoals(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	pairID, _, err := services.FindPartner(h.db, currentUserID)
+	if err == services.ErrNoPair {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pair found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find pair"})
+		return
+	}
+
+	goals, err := services.ListPairGoals(h.db, pairID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch goals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    goals,
+	})
+}
+
+func (h *PairHandler) CreateGoal(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	var req models.CreateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	goal, err := services.CreatePairGoal(h.db, currentUserID, req)
+	if !h.handleGoalError(c, err, "Failed to create goal") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    goal,
+	})
+}
+
+func (h *PairHandler) UpdateGoal(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	goalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal ID"})
+		return
+	}
+
+	var req models.UpdateGoalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	goal, err := services.UpdatePairGoal(h.db, currentUserID, goalID, req)
+	if !h.handleGoalError(c, err, "Failed to update goal") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    goal,
+	})
+}
+
+func (h *PairHandler) DeleteGoal(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	goalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal ID"})
+		return
+	}
+
+	err = services.DeletePairGoal(h.db, currentUserID, goalID)
+	if !h.handleGoalError(c, err, "Failed to delete goal") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Goal deleted",
+	})
+}
+
+func (h *PairHandler) GetGoalHistory(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	goalID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal ID"})
+		return
+	}
+
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	periods := 0
+	if limit != nil {
+		periods = *limit
+	}
+
+	history, err := services.GetGoalHistory(h.db, currentUserID, goalID, periods)
+	if !h.handleGoalError(c, err, "Failed to fetch goal history") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    history,
+	})
+}
+
+// handleGoalError отвечает клиенту при ошибке и возвращает true, если ошибки нет.
+func (h *PairHandler) handleGoalError(c *gin.Context, err error, message string) bool {
+	switch {
+	case err == nil:
+		return true
+	case err == services.ErrNoPair:
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pair found"})
+	case err == services.ErrGoalNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Goal not found"})
+	case err == services.ErrTooManyGoals:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many active goals"})
+	case errors.Is(err, services.ErrInvalidGoal):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+	return false
+}
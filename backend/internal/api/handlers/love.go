@@ -6,6 +6,7 @@ import (
 	"love-connection/backend/internal/services"
 	"love-connection/backend/internal/websocket"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -30,148 +31,395 @@ func (h *LoveHandler) SendLove(c *gin.Context) {
 		return
 	}
 
-	// БЕЗОПАСНОСТЬ: Находим пару для отправителя и проверяем, что он действительно в этой паре
-	// Это гарантирует, что пользователь может отправлять сердечки только своему партнеру
-	var pairID uuid.UUID
-	var user1ID, user2ID uuid.UUID
-	err := h.db.QueryRow(
-		"SELECT id, user1_id, user2_id FROM pairs WHERE user1_id = $1 OR user2_id = $1",
-		senderID,
-	).Scan(&pairID, &user1ID, &user2ID)
-
-	if err == sql.ErrNoRows {
+	// БЕЗОПАСНОСТЬ: Пара и партнер определяются по senderID из токена (проверен middleware),
+	// поэтому пользователь может отправлять сердечки только своему партнеру
+	event, partnerID, err := services.CreateLoveEvent(h.db, senderID, req.DurationSeconds, models.LoveEventTypeStandard)
+	if err == services.ErrNoPair {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No pair found"})
 		return
 	}
 
+	if err == services.ErrInvalidPairMembers {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid pair configuration"})
+		return
+	}
+
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create love event"})
 		return
 	}
 
-	// ДОПОЛНИТЕЛЬНАЯ ПРОВЕРКА: Убеждаемся, что senderID действительно является участником этой пары
-	// Это защита от потенциальных гонок условий или ошибок в логике
-	if senderID != user1ID && senderID != user2ID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this pair"})
+	// Отправляем уведомление и broadcast только своему партнеру
+	services.DeliverLoveEvent(h.db, h.hub, event, partnerID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    event,
+	})
+}
+
+func (h *LoveHandler) GetHistory(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	query := models.HistoryQuery{
+		Cursor:    c.Query("cursor"),
+		Direction: c.Query("direction"),
+		EventType: c.Query("type"),
+	}
+
+	if query.Direction != "" && query.Direction != models.HistoryDirectionSent && query.Direction != models.HistoryDirectionReceived {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be 'sent' or 'received'"})
 		return
 	}
 
-	// БЕЗОПАСНОСТЬ: Вставляем событие только с pairID, который мы получили из проверки выше
-	// senderID берется из токена (проверен middleware), поэтому он не может быть подделан
-	var eventID uuid.UUID
-	err = h.db.QueryRow(
-		"INSERT INTO love_events (pair_id, sender_id, duration_seconds) VALUES ($1, $2, $3) RETURNING id",
-		pairID, senderID, req.DurationSeconds,
-	).Scan(&eventID)
+	switch query.EventType {
+	case "", models.LoveEventTypeStandard, models.LoveEventTypeLive, models.LoveEventTypeScheduled:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be 'standard', 'live' or 'scheduled'"})
+		return
+	}
 
+	limit, err := queryInt(c, "limit")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create love event"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if limit != nil {
+		query.Limit = *limit
+	}
 
-	var event models.LoveEvent
-	var sender models.User
-	var pairIDNullable sql.NullString
-	err = h.db.QueryRow(
-		`SELECT e.id, e.pair_id, e.sender_id, e.duration_seconds, e.created_at,
-			u.id, u.email, u.apple_id, u.username, u.created_at
-		FROM love_events e
-		JOIN users u ON e.sender_id = u.id
-		WHERE e.id = $1`,
-		eventID,
-	).Scan(
-		&event.ID, &pairIDNullable, &event.SenderID, &event.DurationSeconds, &event.CreatedAt,
-		&sender.ID, &sender.Email, &sender.AppleID, &sender.Username, &sender.CreatedAt,
-	)
+	if query.From, err = queryTime(c, "from", time.UTC); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.To, err = queryTime(c, "to", time.UTC); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.MinDuration, err = queryInt(c, "min_duration"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.MaxDuration, err = queryInt(c, "max_duration"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Получаем события из пары пользователя (как отправленные им, так и полученные от партнера)
+	events, nextCursor, err := services.LoveHistory(h.db, currentUserID, query)
+	if err == services.ErrInvalidCursor {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch event"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
 		return
 	}
 
-	if pairIDNullable.Valid {
-		parsedID, err := uuid.Parse(pairIDNullable.String)
-		if err == nil {
-			event.PairID = &parsedID
-		}
+	eventIDs := make([]uuid.UUID, len(events))
+	for i, event := range events {
+		eventIDs[i] = event.ID
 	}
 
-	event.Sender = &sender
+	reactions, err := services.ReactionsForEvents(h.db, eventIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reactions"})
+		return
+	}
 
-	// БЕЗОПАСНОСТЬ: Определяем партнера из уже проверенной пары
-	// Используем уже полученные user1ID и user2ID для определения партнера
-	// Это безопасно, потому что мы уже проверили, что senderID является участником пары
-	var partnerID uuid.UUID
-	if senderID == user1ID {
-		partnerID = user2ID
-	} else {
-		partnerID = user1ID
+	for i := range events {
+		events[i].Reactions = reactions[events[i].ID]
 	}
 
-	// БЕЗОПАСНОСТЬ: Дополнительная проверка - партнер должен быть другим пользователем
-	// Это защита от некорректных данных в базе (хотя есть CHECK constraint в схеме)
-	if partnerID == senderID {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid pair configuration"})
+	var cursor *string
+	if nextCursor != "" {
+		cursor = &nextCursor
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"data":        events,
+		"next_cursor": cursor,
+	})
+}
+
+// PartnerID используется хабом, чтобы транслировать удержание сердечка партнеру.
+func (h *LoveHandler) PartnerID(userID uuid.UUID) (uuid.UUID, error) {
+	_, partnerID, err := services.FindPartner(h.db, userID)
+	return partnerID, err
+}
+
+// CompleteHold создает love_event по длительности, измеренной сервером по live-удержанию.
+func (h *LoveHandler) CompleteHold(senderID uuid.UUID, durationSeconds int) error {
+	event, partnerID, err := services.CreateLoveEvent(h.db, senderID, durationSeconds, models.LoveEventTypeLive)
+	if err != nil {
+		return err
+	}
+
+	h.hub.BroadcastLoveEvent(event, senderID)
+	services.DeliverLoveEvent(h.db, h.hub, event, partnerID)
+
+	return nil
+}
+
+func (h *LoveHandler) MarkSeen(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	var req models.MarkSeenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Отправляем уведомление и broadcast только своему партнеру
-	go services.SendNotification(h.db, partnerID, sender.Username, req.DurationSeconds)
-	h.hub.BroadcastLoveEvent(event, partnerID)
+	seen, senderID, err := services.MarkSeen(h.db, currentUserID, req.EventID)
+	if err == services.ErrLoveEventNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Love event not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark love events as seen"})
+		return
+	}
+
+	if seen.Count > 0 {
+		h.hub.SendToUser(senderID, "love_seen", seen)
+	}
+
+	unread, err := services.UnreadCount(h.db, currentUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread love events"})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    event,
+		"data": gin.H{
+			"seen_count":   seen.Count,
+			"unread_count": unread,
+		},
 	})
 }
 
-func (h *LoveHandler) GetHistory(c *gin.Context) {
+func (h *LoveHandler) GetUnreadCount(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	currentUserID := userID.(uuid.UUID)
 
-	// Получаем все события из пары пользователя (как отправленные им, так и полученные от партнера)
-	rows, err := h.db.Query(
-		`SELECT e.id, e.pair_id, e.sender_id, e.duration_seconds, e.created_at,
-			u.id, u.email, u.apple_id, u.username, u.created_at
-		FROM love_events e
-		JOIN users u ON e.sender_id = u.id
-		JOIN pairs p ON e.pair_id = p.id
-		WHERE (p.user1_id = $1 OR p.user2_id = $1)
-		ORDER BY e.created_at DESC
-		LIMIT 100`,
-		currentUserID,
-	)
+	unread, err := services.UnreadCount(h.db, currentUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count unread love events"})
+		return
+	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"unread_count": unread,
+		},
+	})
+}
+
+func (h *LoveHandler) SetReaction(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	eventID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid love event ID"})
+		return
+	}
+
+	var req models.SetReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reaction, senderID, err := services.SetReaction(h.db, currentUserID, eventID, req.Emoji)
+	if err == services.ErrInvalidReaction {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported reaction", "allowed": models.AllowedReactions})
 		return
 	}
-	defer rows.Close()
-
-	var events []models.LoveEvent
-	for rows.Next() {
-		var event models.LoveEvent
-		var sender models.User
-		var pairID sql.NullString
-		err := rows.Scan(
-			&event.ID, &pairID, &event.SenderID, &event.DurationSeconds, &event.CreatedAt,
-			&sender.ID, &sender.Email, &sender.AppleID, &sender.Username, &sender.CreatedAt,
-		)
-		if err != nil {
-			continue
-		}
-		if pairID.Valid {
-			parsedID, err := uuid.Parse(pairID.String)
-			if err == nil {
-				event.PairID = &parsedID
-			}
-		}
-		event.Sender = &sender
-		events = append(events, event)
+
+	if err == services.ErrLoveEventNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Love event not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reaction"})
+		return
+	}
+
+	h.hub.SendToUser(senderID, "love_reaction", models.ReactionEvent{
+		LoveEventID: eventID,
+		UserID:      currentUserID,
+		Reaction:    &reaction,
+	})
+
+	var reactorUsername string
+	if err := h.db.QueryRow("SELECT username FROM users WHERE id = $1", currentUserID).Scan(&reactorUsername); err == nil {
+		go services.SendReactionNotification(h.db, senderID, reactorUsername, reaction.Emoji)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    reaction,
+	})
+}
+
+func (h *LoveHandler) RemoveReaction(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	eventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid love event ID"})
+		return
+	}
+
+	senderID, err := services.RemoveReaction(h.db, currentUserID, eventID)
+	if err == services.ErrLoveEventNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Love event not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove reaction"})
+		return
+	}
+
+	h.hub.SendToUser(senderID, "love_reaction", models.ReactionEvent{
+		LoveEventID: eventID,
+		UserID:      currentUserID,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Reaction removed",
+	})
+}
+
+func (h *LoveHandler) DeleteLove(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	eventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid love event ID"})
+		return
+	}
+
+	result, err := services.DeleteLoveEvent(h.db, currentUserID, eventID)
+	if err == services.ErrLoveEventNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Love event not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete love event"})
+		return
+	}
+
+	if !result.Deleted {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"deleted": false,
+				"hidden":  true,
+			},
+		})
+		return
+	}
+
+	if result.PartnerID != uuid.Nil {
+		h.hub.SendToUser(result.PartnerID, "love_deleted", gin.H{"love_event_id": eventID})
+		go services.SendLoveDeletedNotification(h.db, result.PartnerID, eventID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"deleted": true,
+			"hidden":  false,
+		},
+	})
+}
+
+func (h *LoveHandler) ScheduleLove(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	senderID := userID.(uuid.UUID)
+
+	var req models.ScheduleLoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	scheduled, err := services.ScheduleLove(h.db, senderID, req.DurationSeconds, req.DeliverAt)
+	if err == services.ErrDeliverAtInPast {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "deliver_at must be in the future"})
+		return
+	}
+
+	if err == services.ErrNoPair {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No pair found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to schedule love"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    scheduled,
+	})
+}
+
+func (h *LoveHandler) GetScheduledLoves(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	senderID := userID.(uuid.UUID)
+
+	scheduled, err := services.ListScheduledLoves(h.db, senderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch scheduled loves"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    scheduled,
+	})
+}
+
+func (h *LoveHandler) CancelScheduledLove(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	senderID := userID.(uuid.UUID)
+
+	scheduledID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled love ID"})
+		return
+	}
+
+	err = services.CancelScheduledLove(h.db, senderID, scheduledID)
+	if err == services.ErrScheduledLoveNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Scheduled love not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel scheduled love"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    events,
+		"message": "Scheduled love cancelled",
 	})
 }
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Неверные параметры отклоняются до обращения к базе, поэтому db не нужна.
+func TestGetHistoryRejectsInvalidQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, query := range []string{
+		"type=hug",
+		"type=STANDARD",
+		"direction=both",
+		"limit=many",
+		"from=yesterday",
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/love/history?"+query, nil)
+		c.Set("user_id", uuid.New())
+
+		NewLoveHandler(nil, nil).GetHistory(c)
+
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusBadRequest || body.Error == "" {
+			t.Errorf("%s: status %d, body %s; want 400 with an error", query, w.Code, w.Body)
+		}
+	}
+}
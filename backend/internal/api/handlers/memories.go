@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"database/sql"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type MemoryHandler struct {
+	db *sql.DB
+}
+
+func NewMemoryHandler(db *sql.DB) *MemoryHandler {
+	return &MemoryHandler{db: db}
+}
+
+func (h *MemoryHandler) GetToday(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	memories, err := services.MemoriesToday(h.db, currentUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch memories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    memories,
+	})
+}
+
+func (h *MemoryHandler) GetSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	settings, err := services.GetMemorySettings(h.db, currentUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch memory settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    settings,
+	})
+}
+
+func (h *MemoryHandler) UpdateSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	var req models.UpdateMemorySettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := services.UpdateMemorySettings(h.db, currentUserID, req)
+	if err == services.ErrInvalidTime {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "push_at must be in HH:MM format"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update memory settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    settings,
+	})
+}
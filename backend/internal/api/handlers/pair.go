@@ -63,7 +63,7 @@ func (h *PairHandler) CreatePairRequest(c *gin.Context) {
 
 	var existingCurrentUserPair uuid.UUID
 	err = h.db.QueryRow(
-		`SELECT id FROM pairs WHERE user1_id = $1 OR user2_id = $1`,
+		`SELECT id FROM pairs WHERE (user1_id = $1 OR user2_id = $1) AND archived_at IS NULL`,
 		currentUserID,
 	).Scan(&existingCurrentUserPair)
 
@@ -74,7 +74,7 @@ func (h *PairHandler) CreatePairRequest(c *gin.Context) {
 
 	var existingPartnerPair uuid.UUID
 	err = h.db.QueryRow(
-		`SELECT id FROM pairs WHERE user1_id = $1 OR user2_id = $1`,
+		`SELECT id FROM pairs WHERE (user1_id = $1 OR user2_id = $1) AND archived_at IS NULL`,
 		partnerID,
 	).Scan(&existingPartnerPair)
 
@@ -85,7 +85,7 @@ func (h *PairHandler) CreatePairRequest(c *gin.Context) {
 
 	var existingPairID uuid.UUID
 	err = h.db.QueryRow(
-		`SELECT id FROM pairs WHERE (user1_id = $1 AND user2_id = $2) OR (user1_id = $2 AND user2_id = $1)`,
+		`SELECT id FROM pairs WHERE ((user1_id = $1 AND user2_id = $2) OR (user1_id = $2 AND user2_id = $1)) AND archived_at IS NULL`,
 		currentUserID, partnerID,
 	).Scan(&existingPairID)
 
@@ -119,15 +119,15 @@ func (h *PairHandler) CreatePairRequest(c *gin.Context) {
 		return
 	}
 
-	var requesterUsername string
-	err = h.db.QueryRow("SELECT username FROM users WHERE id = $1", currentUserID).Scan(&requesterUsername)
+	tx, err := h.db.Begin()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get requester info"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pair request"})
 		return
 	}
+	defer tx.Rollback()
 
 	var requestID uuid.UUID
-	err = h.db.QueryRow(
+	err = tx.QueryRow(
 		"INSERT INTO pair_requests (requester_id, requested_id, status) VALUES ($1, $2, 'pending') RETURNING id",
 		currentUserID, partnerID,
 	).Scan(&requestID)
@@ -137,6 +137,18 @@ func (h *PairHandler) CreatePairRequest(c *gin.Context) {
 		return
 	}
 
+	// Пуш пишется в outbox в той же транзакции, что и запрос
+	if err := services.EnqueuePairRequestNotification(tx, partnerID, requestID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pair request"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pair request"})
+		return
+	}
+	services.WakeOutbox()
+
 	var pairRequest models.PairRequest
 	var requester, requested models.User
 	err = h.db.QueryRow(
@@ -162,8 +174,6 @@ func (h *PairHandler) CreatePairRequest(c *gin.Context) {
 	pairRequest.Requester = &requester
 	pairRequest.Requested = &requested
 
-	go services.SendPairRequestNotification(h.db, partnerID, requesterUsername)
-
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    pairRequest,
@@ -225,7 +235,7 @@ func (h *PairHandler) RespondPairRequest(c *gin.Context) {
 	if req.Accept {
 		var existingRequesterPair uuid.UUID
 		err = h.db.QueryRow(
-			`SELECT id FROM pairs WHERE user1_id = $1 OR user2_id = $1`,
+			`SELECT id FROM pairs WHERE (user1_id = $1 OR user2_id = $1) AND archived_at IS NULL`,
 			pairRequest.RequesterID,
 		).Scan(&existingRequesterPair)
 
@@ -236,7 +246,7 @@ func (h *PairHandler) RespondPairRequest(c *gin.Context) {
 
 		var existingRequestedPair uuid.UUID
 		err = h.db.QueryRow(
-			`SELECT id FROM pairs WHERE user1_id = $1 OR user2_id = $1`,
+			`SELECT id FROM pairs WHERE (user1_id = $1 OR user2_id = $1) AND archived_at IS NULL`,
 			pairRequest.RequestedID,
 		).Scan(&existingRequestedPair)
 
@@ -350,7 +360,7 @@ func (h *PairHandler) GetCurrentPair(c *gin.Context) {
 		FROM pairs p
 		JOIN users u1 ON p.user1_id = u1.id
 		JOIN users u2 ON p.user2_id = u2.id
-		WHERE p.user1_id = $1 OR p.user2_id = $1`,
+		WHERE (p.user1_id = $1 OR p.user2_id = $1) AND p.archived_at IS NULL`,
 		currentUserID,
 	).Scan(
 		&pair.ID, &pair.User1ID, &pair.User2ID, &pair.CreatedAt,
@@ -374,6 +384,12 @@ func (h *PairHandler) GetCurrentPair(c *gin.Context) {
 	pair.User1 = &user1
 	pair.User2 = &user2
 
+	pair.Goals, err = services.ListPairGoals(h.db, pair.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch goals"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data":    pair,
@@ -386,7 +402,7 @@ func (h *PairHandler) DeletePair(c *gin.Context) {
 
 	var pairID uuid.UUID
 	err := h.db.QueryRow(
-		`SELECT id FROM pairs WHERE user1_id = $1 OR user2_id = $1`,
+		`SELECT id FROM pairs WHERE (user1_id = $1 OR user2_id = $1) AND archived_at IS NULL`,
 		currentUserID,
 	).Scan(&pairID)
 
@@ -400,7 +416,8 @@ func (h *PairHandler) DeletePair(c *gin.Context) {
 		return
 	}
 
-	_, err = h.db.Exec("DELETE FROM pairs WHERE id = $1", pairID)
+	// Пара архивируется, а не удаляется: история и статистика остаются доступны
+	_, err = h.db.Exec("UPDATE pairs SET archived_at = NOW() WHERE id = $1", pairID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete pair"})
 		return
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"database/sql"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/internal/services"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type RecapHandler struct {
+	db *sql.DB
+}
+
+func NewRecapHandler(db *sql.DB) *RecapHandler {
+	return &RecapHandler{db: db}
+}
+
+// resolvePair возвращает текущую пару или пару из параметра pair_id (в том числе архивную).
+// При ошибке ответ уже отправлен и возвращается false.
+func (h *RecapHandler) resolvePair(c *gin.Context, userID uuid.UUID, value string) (models.Pair, bool) {
+	var pairID *uuid.UUID
+	if value != "" {
+		parsedID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pair ID"})
+			return models.Pair{}, false
+		}
+		pairID = &parsedID
+	}
+
+	// БЕЗОПАСНОСТЬ: пара ищется только среди пар текущего пользователя
+	pair, err := services.ResolvePair(h.db, userID, pairID)
+	if err == services.ErrNoPair {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pair found"})
+		return pair, false
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find pair"})
+		return pair, false
+	}
+
+	return pair, true
+}
+
+func (h *RecapHandler) GetRecaps(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	period := c.Query("period")
+	if period != "" && !services.IsRecapPeriod(period) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidRecapPeriod.Error()})
+		return
+	}
+
+	limit, err := queryInt(c, "limit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pair, ok := h.resolvePair(c, currentUserID, c.Query("pair_id"))
+	if !ok {
+		return
+	}
+
+	count := 0
+	if limit != nil {
+		count = *limit
+	}
+
+	recaps, err := services.ListRecaps(h.db, pair.ID, period, count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recaps"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    recaps,
+	})
+}
+
+func (h *RecapHandler) GetRecap(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	recapID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recap ID"})
+		return
+	}
+
+	recap, err := services.GetRecap(h.db, currentUserID, recapID)
+	if err == services.ErrRecapNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recap not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recap"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    recap,
+	})
+}
+
+// GenerateRecap создает (или возвращает уже готовый) отчет за прошедший период.
+func (h *RecapHandler) GenerateRecap(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	var req models.GenerateRecapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	day, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "date must be a YYYY-MM-DD date"})
+		return
+	}
+
+	pair, ok := h.resolvePair(c, currentUserID, c.Query("pair_id"))
+	if !ok {
+		return
+	}
+
+	recap, _, err := services.GenerateRecap(h.db, pair, req.Period, day)
+	if err == services.ErrInvalidRecapPeriod || err == services.ErrRecapPeriodNotOver {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate recap"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    recap,
+	})
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"database/sql"
 	"love-connection/backend/internal/models"
+	"love-connection/backend/internal/services"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,17 +23,7 @@ func (h *StatsHandler) GetStats(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	currentUserID := userID.(uuid.UUID)
 
-	var stats models.Stats
-	err := h.db.QueryRow(
-		`SELECT
-			COUNT(*) as total_events,
-			COALESCE(SUM(duration_seconds), 0) as total_duration_seconds,
-			COALESCE(AVG(duration_seconds), 0) as average_duration_seconds
-		FROM love_events
-		WHERE sender_id = $1`,
-		currentUserID,
-	).Scan(&stats.TotalEvents, &stats.TotalDurationSeconds, &stats.AverageDurationSeconds)
-
+	stats, err := services.GetUserStats(h.db, currentUserID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stats"})
 		return
@@ -43,3 +35,168 @@ func (h *StatsHandler) GetStats(c *gin.Context) {
 	})
 }
 
+
+func (h *StatsHandler) GetPairStats(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	var pairID *uuid.UUID
+	if value := c.Query("pair_id"); value != "" {
+		parsedID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pair ID"})
+			return
+		}
+		pairID = &parsedID
+	}
+
+	// БЕЗОПАСНОСТЬ: пара (в том числе архивная) ищется только среди пар текущего пользователя
+	pair, err := services.ResolvePair(h.db, currentUserID, pairID)
+	if err == services.ErrNoPair {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pair found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find pair"})
+		return
+	}
+
+	stats, err := services.GetPairStats(h.db, pair, currentUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pair stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// Максимальное число периодов в одном ответе /stats/timeseries.
+const maxTimeSeriesPoints = 400
+
+func (h *StatsHandler) GetTimeSeries(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	bucket := c.DefaultQuery("bucket", models.StatsBucketDay)
+	if bucket != models.StatsBucketDay && bucket != models.StatsBucketWeek && bucket != models.StatsBucketMonth {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be 'day', 'week' or 'month'"})
+		return
+	}
+
+	scope := c.DefaultQuery("scope", models.StatsScopeUser)
+	if scope != models.StatsScopeUser && scope != models.StatsScopePair {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope must be 'user' or 'pair'"})
+		return
+	}
+
+	loc, err := services.UserLocation(h.db, currentUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+	if name := c.Query("timezone"); name != "" {
+		loc, err = services.ValidateTimezone(h.db, name)
+		if err == services.ErrInvalidTimezone {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "timezone must be a valid IANA time zone"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check timezone"})
+			return
+		}
+	}
+
+	filter := services.StatsFilter{UserID: currentUserID}
+
+	if scope == models.StatsScopePair {
+		var pairID *uuid.UUID
+		if value := c.Query("pair_id"); value != "" {
+			parsedID, err := uuid.Parse(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pair ID"})
+				return
+			}
+			pairID = &parsedID
+		}
+
+		pair, err := services.ResolvePair(h.db, currentUserID, pairID)
+		if err == services.ErrNoPair {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No pair found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find pair"})
+			return
+		}
+		filter.PairID = &pair.ID
+	}
+
+	from, err := queryTime(c, "from", loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	to, err := queryTime(c, "to", loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// По умолчанию: до конца текущего периода, 30 дней / 12 недель / 12 месяцев назад
+	if to == nil {
+		end := services.TruncateToBucket(time.Now().In(loc), bucket)
+		switch bucket {
+		case models.StatsBucketWeek:
+			end = end.AddDate(0, 0, 7)
+		case models.StatsBucketMonth:
+			end = end.AddDate(0, 1, 0)
+		default:
+			end = end.AddDate(0, 0, 1)
+		}
+		to = &end
+	}
+	if from == nil {
+		var start time.Time
+		switch bucket {
+		case models.StatsBucketWeek:
+			start = to.AddDate(0, 0, -7*12)
+		case models.StatsBucketMonth:
+			start = to.AddDate(0, -12, 0)
+		default:
+			start = to.AddDate(0, 0, -30)
+		}
+		from = &start
+	}
+
+	if !from.Before(*to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	days := to.Sub(*from).Hours() / 24
+	if (bucket == models.StatsBucketDay && days > maxTimeSeriesPoints) ||
+		(bucket == models.StatsBucketWeek && days > 7*maxTimeSeriesPoints) ||
+		(bucket == models.StatsBucketMonth && days > 31*maxTimeSeriesPoints) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requested range is too large"})
+		return
+	}
+
+	filter.From = *from
+	filter.To = *to
+
+	stats, err := services.GetTimeSeries(h.db, filter, bucket, loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch time series"})
+		return
+	}
+	stats.Scope = scope
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
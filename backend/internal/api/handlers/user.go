@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
 	"love-connection/backend/internal/models"
+	"love-connection/backend/internal/services"
 	"net/http"
 	"net/url"
 	"strings"
@@ -25,9 +27,9 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 
 	var user models.User
 	err := h.db.QueryRow(
-		"SELECT id, email, apple_id, username, created_at FROM users WHERE id = $1",
+		"SELECT id, email, apple_id, username, timezone, locale, created_at FROM users WHERE id = $1",
 		uid,
-	).Scan(&user.ID, &user.Email, &user.AppleID, &user.Username, &user.CreatedAt)
+	).Scan(&user.ID, &user.Email, &user.AppleID, &user.Username, &user.Timezone, &user.Locale, &user.CreatedAt)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
@@ -40,12 +42,15 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 	})
 }
 
+// UpdateDeviceToken — старый способ регистрации устройства, оставлен для уже выпущенных версий приложения.
 func (h *UserHandler) UpdateDeviceToken(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	uid := userID.(uuid.UUID)
 
 	var req struct {
 		DeviceToken string `json:"device_token" binding:"required"`
+		// Environment — окружение APNs токена: "production" (по умолчанию) или "development".
+		Environment string `json:"environment"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -53,10 +58,15 @@ func (h *UserHandler) UpdateDeviceToken(c *gin.Context) {
 		return
 	}
 
-	_, err := h.db.Exec(
-		"UPDATE users SET device_token = $1 WHERE id = $2",
-		req.DeviceToken, uid,
-	)
+	_, err := services.RegisterDevice(h.db, uid, models.RegisterDeviceRequest{
+		Token:       req.DeviceToken,
+		Platform:    models.DevicePlatformIOS,
+		Environment: req.Environment,
+	})
+	if errors.Is(err, services.ErrInvalidDevice) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update device token"})
@@ -66,6 +76,43 @@ func (h *UserHandler) UpdateDeviceToken(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"success": true})
 }
 
+// UpdateLocale задает язык уведомлений. Пустая строка возвращает выбор по языку устройства.
+func (h *UserHandler) UpdateLocale(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	uid := userID.(uuid.UUID)
+
+	var req struct {
+		Locale *string `json:"locale" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	locale, err := services.SetUserLocale(h.db, uid, *req.Locale)
+	if errors.Is(err, services.ErrUnsupportedLocale) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":             err.Error(),
+			"supported_locales": services.SupportedLocales(),
+		})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update locale"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"locale":            locale,
+			"supported_locales": services.SupportedLocales(),
+		},
+	})
+}
+
 func (h *UserHandler) UpdateMe(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	uid := userID.(uuid.UUID)
@@ -216,3 +263,51 @@ func (h *UserHandler) GenerateInviteLink(c *gin.Context) {
 	})
 }
 
+
+func (h *UserHandler) GetReminderSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	uid := userID.(uuid.UUID)
+
+	settings, err := services.GetReminderSettings(h.db, uid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminder settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    settings,
+	})
+}
+
+func (h *UserHandler) UpdateReminderSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	uid := userID.(uuid.UUID)
+
+	var req models.UpdateReminderSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := services.UpdateReminderSettings(h.db, uid, req)
+	if err == services.ErrInvalidTime {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "remind_at must be in HH:MM format"})
+		return
+	}
+
+	if err == services.ErrInvalidTimezone {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "timezone must be a valid IANA time zone"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reminder settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    settings,
+	})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WebPushHandler struct {
+	db *sql.DB
+}
+
+func NewWebPushHandler(db *sql.DB) *WebPushHandler {
+	return &WebPushHandler{db: db}
+}
+
+// GetPublicKey отдает VAPID-ключ, который браузер передает в pushManager.subscribe.
+func (h *WebPushHandler) GetPublicKey(c *gin.Context) {
+	publicKey, err := services.WebPushPublicKey()
+	if err == services.ErrWebPushNotConfigured {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Web Push is not configured"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"public_key": publicKey},
+	})
+}
+
+func (h *WebPushHandler) Subscribe(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	var req models.WebPushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	device, err := services.RegisterWebPushSubscription(h.db, currentUserID, req)
+	if errors.Is(err, services.ErrInvalidDevice) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    device,
+	})
+}
+
+func (h *WebPushHandler) Unsubscribe(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	currentUserID := userID.(uuid.UUID)
+
+	var req models.DeleteWebPushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := services.UnregisterWebPushSubscription(h.db, currentUserID, req.Endpoint)
+	if err == services.ErrDeviceNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -7,12 +7,13 @@ import (
 	"love-connection/backend/internal/api/handlers"
 	"love-connection/backend/internal/api/middleware"
 	"love-connection/backend/internal/websocket"
+	"love-connection/backend/pkg/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-func SetupRoutes(r *gin.Engine, db *sql.DB, hub *websocket.Hub) {
+func SetupRoutes(r *gin.Engine, db *sql.DB, hub *websocket.Hub, exportStore storage.Storage) {
 	r.Use(middleware.CORS())
 
 	go hub.Run()
@@ -21,6 +22,9 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, hub *websocket.Hub) {
 	r.GET("/health", healthHandler.HealthCheck)
 	r.GET("/api/feature-flags", handlers.GetFeatureFlags)
 
+	webPushHandler := handlers.NewWebPushHandler(db)
+	r.GET("/api/webpush/public-key", webPushHandler.GetPublicKey)
+
 	// Universal Links: Apple App Site Association file
 	// Важно: файл должен быть доступен по HTTPS без расширения .json
 	r.GET("/.well-known/apple-app-site-association", func(c *gin.Context) {
@@ -134,6 +138,10 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, hub *websocket.Hub) {
 		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
 	})
 
+	// Скачивание выгрузки данных по подписанной ссылке, без токена
+	exportHandler := handlers.NewExportHandler(db, exportStore)
+	r.GET("/exports/:id/download", exportHandler.Download)
+
 	api := r.Group("/api")
 	{
 		auth := api.Group("/auth")
@@ -144,14 +152,34 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, hub *websocket.Hub) {
 			auth.POST("/apple", authHandler.AppleSignIn)
 		}
 
+		admin := api.Group("/admin", middleware.Admin())
+		{
+			adminHandler := handlers.NewAdminHandler(db)
+			admin.GET("/devices", adminHandler.GetDevices)
+			admin.GET("/outbox", adminHandler.GetOutbox)
+			admin.POST("/outbox/:id/retry", adminHandler.RetryOutbox)
+		}
+
 		api.Use(middleware.Auth())
 		{
 			userHandler := handlers.NewUserHandler(db)
 			api.GET("/user/me", userHandler.GetMe)
 			api.PATCH("/user/me", userHandler.UpdateMe)
 			api.POST("/user/device-token", userHandler.UpdateDeviceToken)
+			api.PUT("/user/locale", userHandler.UpdateLocale)
 			api.GET("/user/search", userHandler.SearchUser)
 			api.GET("/user/invite-link", userHandler.GenerateInviteLink)
+			api.GET("/user/reminder-settings", userHandler.GetReminderSettings)
+			api.PUT("/user/reminder-settings", userHandler.UpdateReminderSettings)
+			api.POST("/user/export", exportHandler.RequestExport)
+			api.GET("/user/export/:id", exportHandler.GetExport)
+
+			deviceHandler := handlers.NewDeviceHandler(db)
+			api.GET("/devices", deviceHandler.GetDevices)
+			api.POST("/devices", deviceHandler.RegisterDevice)
+			api.DELETE("/devices/:id", deviceHandler.UnregisterDevice)
+			api.POST("/webpush/subscriptions", webPushHandler.Subscribe)
+			api.DELETE("/webpush/subscriptions", webPushHandler.Unsubscribe)
 
 			pairHandler := handlers.NewPairHandler(db)
 			api.POST("/pairs/request", pairHandler.CreatePairRequest)
@@ -159,13 +187,42 @@ func SetupRoutes(r *gin.Engine, db *sql.DB, hub *websocket.Hub) {
 			api.GET("/pairs/requests", pairHandler.GetPairRequests)
 			api.GET("/pairs/current", pairHandler.GetCurrentPair)
 			api.DELETE("/pairs/current", pairHandler.DeletePair)
+			api.GET("/pairs/current/goals", pairHandler.GetGoals)
+			api.POST("/pairs/current/goals", pairHandler.CreateGoal)
+			api.PUT("/pairs/current/goals/:id", pairHandler.UpdateGoal)
+			api.DELETE("/pairs/current/goals/:id", pairHandler.DeleteGoal)
+			api.GET("/pairs/current/goals/:id/history", pairHandler.GetGoalHistory)
 
 			loveHandler := handlers.NewLoveHandler(db, hub)
+			hub.SetHoldHandler(loveHandler)
 			api.POST("/love/send", loveHandler.SendLove)
 			api.GET("/love/history", loveHandler.GetHistory)
+			api.GET("/love/unread", loveHandler.GetUnreadCount)
+			api.POST("/love/seen", loveHandler.MarkSeen)
+			api.POST("/love/schedule", loveHandler.ScheduleLove)
+			api.GET("/love/scheduled", loveHandler.GetScheduledLoves)
+			api.DELETE("/love/scheduled/:id", loveHandler.CancelScheduledLove)
+			api.DELETE("/love/:id", loveHandler.DeleteLove)
+			api.PUT("/love/:id/reaction", loveHandler.SetReaction)
+			api.DELETE("/love/:id/reaction", loveHandler.RemoveReaction)
 
 			statsHandler := handlers.NewStatsHandler(db)
 			api.GET("/stats", statsHandler.GetStats)
+			api.GET("/stats/pair", statsHandler.GetPairStats)
+			api.GET("/stats/timeseries", statsHandler.GetTimeSeries)
+
+			achievementHandler := handlers.NewAchievementHandler(db)
+			api.GET("/achievements", achievementHandler.GetAchievements)
+
+			recapHandler := handlers.NewRecapHandler(db)
+			api.GET("/recaps", recapHandler.GetRecaps)
+			api.POST("/recaps", recapHandler.GenerateRecap)
+			api.GET("/recaps/:id", recapHandler.GetRecap)
+
+			memoryHandler := handlers.NewMemoryHandler(db)
+			api.GET("/memories/today", memoryHandler.GetToday)
+			api.GET("/memories/settings", memoryHandler.GetSettings)
+			api.PUT("/memories/settings", memoryHandler.UpdateSettings)
 		}
 	}
 
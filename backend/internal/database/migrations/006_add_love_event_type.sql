@@ -0,0 +1,2 @@
+-- Distinguish hearts sent after release from hearts streamed live over the websocket
+ALTER TABLE love_events ADD COLUMN IF NOT EXISTS event_type VARCHAR(20) NOT NULL DEFAULT 'standard';
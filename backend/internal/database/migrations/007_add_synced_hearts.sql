@@ -0,0 +1,15 @@
+-- Overlapping holds of both partners ("both holding at once")
+CREATE TABLE IF NOT EXISTS synced_hearts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    pair_id UUID NOT NULL REFERENCES pairs(id) ON DELETE CASCADE,
+    first_event_id UUID NOT NULL REFERENCES love_events(id) ON DELETE CASCADE,
+    second_event_id UUID NOT NULL REFERENCES love_events(id) ON DELETE CASCADE,
+    overlap_started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    overlap_seconds INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(first_event_id, second_event_id),
+    CHECK (first_event_id != second_event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_synced_hearts_pair ON synced_hearts(pair_id);
+CREATE INDEX IF NOT EXISTS idx_synced_hearts_second_event ON synced_hearts(second_event_id);
@@ -0,0 +1,5 @@
+-- Read receipts: each love event has exactly one recipient (the sender's partner)
+ALTER TABLE love_events ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE love_events ADD COLUMN IF NOT EXISTS seen_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_love_events_unseen ON love_events(pair_id, sender_id) WHERE seen_at IS NULL;
@@ -0,0 +1,12 @@
+-- One reaction per recipient per love event; changing it updates the row
+CREATE TABLE IF NOT EXISTS love_reactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    love_event_id UUID NOT NULL REFERENCES love_events(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    emoji VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(love_event_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_love_reactions_event ON love_reactions(love_event_id);
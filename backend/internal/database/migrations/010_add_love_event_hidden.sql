@@ -0,0 +1,7 @@
+-- Love events a user removed from their own history after the unsend window expired
+CREATE TABLE IF NOT EXISTS love_event_hidden (
+    love_event_id UUID NOT NULL REFERENCES love_events(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (love_event_id, user_id)
+);
@@ -0,0 +1,2 @@
+-- Keyset pagination of a pair's history on (created_at, id)
+CREATE INDEX IF NOT EXISTS idx_love_events_pair_created_id ON love_events(pair_id, created_at DESC, id DESC);
@@ -0,0 +1,17 @@
+-- Hearts scheduled for future delivery; the scheduler claims due rows with FOR UPDATE SKIP LOCKED
+CREATE TABLE IF NOT EXISTS scheduled_loves (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    pair_id UUID NOT NULL REFERENCES pairs(id) ON DELETE CASCADE,
+    duration_seconds INTEGER NOT NULL,
+    deliver_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    love_event_id UUID REFERENCES love_events(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (status IN ('pending', 'delivered', 'cancelled', 'failed')),
+    CHECK (duration_seconds > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_loves_sender ON scheduled_loves(sender_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_loves_due ON scheduled_loves(deliver_at) WHERE status = 'pending';
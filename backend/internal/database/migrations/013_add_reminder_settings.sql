@@ -0,0 +1,16 @@
+-- IANA time zone of the user, used for local-time features such as reminders
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- Opt-in daily "send some love" reminders. last_sent_on is the user's local date
+-- of the last reminder and is claimed before sending, so a reminder is never sent twice.
+CREATE TABLE IF NOT EXISTS reminder_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    remind_at TIME NOT NULL DEFAULT '20:00',
+    skip_if_partner_sent BOOLEAN NOT NULL DEFAULT FALSE,
+    last_sent_on DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminder_settings_enabled ON reminder_settings(user_id) WHERE enabled;
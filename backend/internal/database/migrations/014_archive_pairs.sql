@@ -0,0 +1,6 @@
+-- Unpairing archives the pair instead of deleting it, so its history and stats stay available
+ALTER TABLE pairs ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+
+-- The same two users may pair again after archiving; only one active pair per couple
+ALTER TABLE pairs DROP CONSTRAINT IF EXISTS pairs_user1_id_user2_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pairs_active_users ON pairs(user1_id, user2_id) WHERE archived_at IS NULL;
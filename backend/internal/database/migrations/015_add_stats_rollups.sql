@@ -0,0 +1,29 @@
+-- Daily stats rollups (days in UTC). Maintained in the transaction that inserts or deletes
+-- a love event; love_events.rolled_up marks events already counted so a catch-up worker
+-- can pick up rows inserted by other means.
+ALTER TABLE love_events ADD COLUMN IF NOT EXISTS rolled_up BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_love_events_not_rolled_up ON love_events(created_at) WHERE NOT rolled_up;
+
+-- Hearts sent by a user per day, across all of their pairs
+CREATE TABLE IF NOT EXISTS love_user_daily_rollups (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    event_count INTEGER NOT NULL DEFAULT 0,
+    total_duration_seconds BIGINT NOT NULL DEFAULT 0,
+    max_duration_seconds INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, day)
+);
+
+-- Hearts sent within a pair per sender and day
+CREATE TABLE IF NOT EXISTS love_pair_daily_rollups (
+    pair_id UUID NOT NULL REFERENCES pairs(id) ON DELETE CASCADE,
+    sender_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    event_count INTEGER NOT NULL DEFAULT 0,
+    total_duration_seconds BIGINT NOT NULL DEFAULT 0,
+    max_duration_seconds INTEGER NOT NULL DEFAULT 0,
+    first_event_at TIMESTAMP WITH TIME ZONE,
+    last_event_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (pair_id, sender_id, day)
+);
@@ -0,0 +1,12 @@
+-- Streaks are computed on demand from love_events. This table only records which streak
+-- pushes were already sent: period is the first day of the streak for milestones and the
+-- user's local date for "about to break" pushes. A row is claimed before sending.
+CREATE TABLE IF NOT EXISTS streak_notifications (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL,
+    period DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, kind, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_love_events_sender_created ON love_events(sender_id, created_at);
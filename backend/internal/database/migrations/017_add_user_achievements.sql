@@ -0,0 +1,8 @@
+-- Unlocked achievements. Achievement definitions live in code (services/achievements.go),
+-- so only the code of the rule and the unlock time are stored.
+CREATE TABLE IF NOT EXISTS user_achievements (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code VARCHAR(64) NOT NULL,
+    unlocked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, code)
+);
@@ -0,0 +1,18 @@
+-- Shared goals of a pair, e.g. 5 hearts a day or 600 seconds of holds a week.
+-- Goals are never updated in place: editing archives the old row and inserts a new one,
+-- so history of past periods can be computed against the target that was active then.
+-- Periods are counted in the time zone of the partner who set the goal.
+CREATE TABLE IF NOT EXISTS pair_goals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    pair_id UUID NOT NULL REFERENCES pairs(id) ON DELETE CASCADE,
+    metric VARCHAR(20) NOT NULL CHECK (metric IN ('hearts', 'hold_seconds')),
+    period VARCHAR(10) NOT NULL CHECK (period IN ('day', 'week')),
+    target INTEGER NOT NULL CHECK (target > 0),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    replaced_by UUID REFERENCES pair_goals(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    archived_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_pair_goals_active ON pair_goals(pair_id) WHERE archived_at IS NULL;
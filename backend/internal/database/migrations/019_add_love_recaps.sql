@@ -0,0 +1,15 @@
+-- Weekly, monthly and yearly recaps of a pair. The report is computed once when the period
+-- is over and never updated. Periods are counted in the time zone stored with the recap.
+CREATE TABLE IF NOT EXISTS love_recaps (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    pair_id UUID NOT NULL REFERENCES pairs(id) ON DELETE CASCADE,
+    period VARCHAR(10) NOT NULL CHECK (period IN ('week', 'month', 'year')),
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    timezone VARCHAR(64) NOT NULL,
+    report JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (pair_id, period, period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_love_recaps_pair ON love_recaps(pair_id, period_start DESC);
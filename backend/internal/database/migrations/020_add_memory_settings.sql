@@ -0,0 +1,13 @@
+-- "On this day" memories settings. The morning push is opt-in; last_sent_on is the user's
+-- local date of the last push and is claimed before sending.
+CREATE TABLE IF NOT EXISTS memory_settings (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    push_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    push_at TIME NOT NULL DEFAULT '09:00',
+    include_archived_pairs BOOLEAN NOT NULL DEFAULT TRUE,
+    last_sent_on DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_memory_settings_push ON memory_settings(user_id) WHERE push_enabled;
@@ -0,0 +1,4 @@
+-- APNs environment of users.device_token: Xcode builds get sandbox tokens, App Store and
+-- TestFlight builds get production ones. Existing tokens came from production builds.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS device_environment VARCHAR(16) NOT NULL DEFAULT 'production'
+    CHECK (device_environment IN ('production', 'development'));
@@ -0,0 +1,17 @@
+-- Push devices: a user may have several (iPhone, iPad, ...), each with its own APNs
+-- environment. A token belongs to one user at a time; re-registering moves it.
+CREATE TABLE IF NOT EXISTS devices (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL UNIQUE,
+    platform VARCHAR(16) NOT NULL DEFAULT 'ios',
+    environment VARCHAR(16) NOT NULL DEFAULT 'production'
+        CHECK (environment IN ('production', 'development')),
+    app_version VARCHAR(32),
+    locale VARCHAR(35),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_devices_user ON devices(user_id) WHERE active;
@@ -0,0 +1,10 @@
+-- Delivery state of push devices. APNs Unregistered/BadDeviceToken deactivate a device;
+-- invalidated_at is the time Apple reports the token invalid since, so a registration seen
+-- after it (last_seen_at) wins over a late failure.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS failure_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS last_failure_reason VARCHAR(64);
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS last_failure_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS last_delivered_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS invalidated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_devices_failures ON devices(failure_count DESC) WHERE failure_count > 0;
@@ -0,0 +1,21 @@
+-- Transactional outbox for push notifications. Rows are written in the same transaction as
+-- the love event or pair request and delivered at least once by OutboxWorker; dedupe_key
+-- keeps a retried enqueue from producing a second notification.
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL,
+    dedupe_key TEXT NOT NULL UNIQUE,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CHECK (status IN ('pending', 'processing', 'sent', 'skipped', 'dead'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_due ON notification_outbox(next_attempt_at) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_dead ON notification_outbox(created_at DESC) WHERE status = 'dead';
@@ -0,0 +1,4 @@
+-- Web Push subscriptions are devices with platform 'web': token holds the push service
+-- endpoint, and the browser's encryption keys are stored alongside.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS web_push_p256dh TEXT;
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS web_push_auth TEXT;
@@ -0,0 +1,3 @@
+-- Language of the user's notifications (BCP 47 tag, e.g. "en" or "ru").
+-- NULL means the locale of the most recently seen device, then DEFAULT_LOCALE.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35);
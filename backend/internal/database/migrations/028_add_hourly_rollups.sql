@@ -0,0 +1,40 @@
+-- Hourly stats rollups. Daily rollups use UTC days, so they cannot answer "per local day"
+-- questions; hourly buckets can be regrouped into days, weeks and months of any time zone
+-- with a whole-hour offset. Filled by the same code paths as the daily rollups.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.tables WHERE table_name = 'love_user_hourly_rollups'
+    ) THEN
+        -- Hearts sent by a user per hour, across all of their pairs
+        CREATE TABLE love_user_hourly_rollups (
+            user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+            hour TIMESTAMP WITH TIME ZONE NOT NULL,
+            event_count INTEGER NOT NULL DEFAULT 0,
+            total_duration_seconds BIGINT NOT NULL DEFAULT 0,
+            PRIMARY KEY (user_id, hour)
+        );
+
+        -- Hearts sent within a pair per hour, both partners together
+        CREATE TABLE love_pair_hourly_rollups (
+            pair_id UUID NOT NULL REFERENCES pairs(id) ON DELETE CASCADE,
+            hour TIMESTAMP WITH TIME ZONE NOT NULL,
+            event_count INTEGER NOT NULL DEFAULT 0,
+            total_duration_seconds BIGINT NOT NULL DEFAULT 0,
+            PRIMARY KEY (pair_id, hour)
+        );
+
+        -- One-time backfill of events already counted in the daily rollups
+        INSERT INTO love_user_hourly_rollups (user_id, hour, event_count, total_duration_seconds)
+        SELECT sender_id, date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC', COUNT(*), SUM(duration_seconds)
+        FROM love_events
+        WHERE rolled_up
+        GROUP BY 1, 2;
+
+        INSERT INTO love_pair_hourly_rollups (pair_id, hour, event_count, total_duration_seconds)
+        SELECT pair_id, date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC', COUNT(*), SUM(duration_seconds)
+        FROM love_events
+        WHERE rolled_up AND pair_id IS NOT NULL
+        GROUP BY 1, 2;
+    END IF;
+END $$;
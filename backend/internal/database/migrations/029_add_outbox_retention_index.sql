@@ -0,0 +1,2 @@
+-- OutboxWorker deletes sent and skipped messages once they are older than OUTBOX_RETENTION.
+CREATE INDEX IF NOT EXISTS idx_notification_outbox_completed ON notification_outbox(completed_at) WHERE status IN ('sent', 'skipped');
@@ -0,0 +1,18 @@
+-- users.device_token is superseded by devices: move the remaining tokens and drop the column.
+-- Guarded by the column itself, so the move runs once and later starts skip it.
+-- users.device_environment stays: 022 adds it back on every start, and nothing reads it.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'users' AND column_name = 'device_token'
+    ) THEN
+        INSERT INTO devices (user_id, token, environment)
+        SELECT id, device_token, device_environment
+        FROM users
+        WHERE device_token IS NOT NULL AND device_token <> ''
+        ON CONFLICT (token) DO NOTHING;
+
+        ALTER TABLE users DROP COLUMN device_token;
+    END IF;
+END $$;
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+)
+
+// Миграции выполняются при каждом запуске, поэтому повторный прогон не должен ничего ломать,
+// а токены из users.device_token переносятся в devices один раз.
+func TestMigrationsMoveLegacyDeviceTokens(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := RunMigrations(db); err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+
+	// База до переноса: токен лежит в users
+	if _, err := db.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS device_token TEXT"); err != nil {
+		t.Fatal(err)
+	}
+	var userID string
+	err = db.QueryRow(
+		`INSERT INTO users (email, username, device_token, device_environment)
+		VALUES ('legacy-device@example.com', 'legacy-device', 'legacy-device-token', 'development')
+		RETURNING id`,
+	).Scan(&userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Exec("DELETE FROM users WHERE id = $1", userID)
+
+	for run := 1; run <= 2; run++ {
+		if err := RunMigrations(db); err != nil {
+			t.Fatalf("migrations, run %d: %v", run, err)
+		}
+	}
+
+	var environment string
+	err = db.QueryRow("SELECT environment FROM devices WHERE user_id = $1 AND token = 'legacy-device-token'", userID).Scan(&environment)
+	if err != nil {
+		t.Fatalf("moved device: %v", err)
+	}
+	if environment != "development" {
+		t.Errorf("environment = %s, want development", environment)
+	}
+
+	var columns int
+	err = db.QueryRow(
+		"SELECT COUNT(*) FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'device_token'",
+	).Scan(&columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if columns != 0 {
+		t.Error("users.device_token was not dropped")
+	}
+}
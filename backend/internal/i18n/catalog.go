@@ -0,0 +1,319 @@
+package i18n
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+// DefaultLocale — язык, на который падают неизвестные локали и отсутствующие ключи,
+// если DEFAULT_LOCALE не задан. Исторически все уведомления были на русском.
+const DefaultLocale = "ru"
+
+var ErrUnknownMessage = errors.New("unknown message")
+
+// Catalog — набор локалей с шаблонами сообщений. Сообщение — шаблон text/template
+// или набор форм множественного числа ("one", "few", "many", "other", ...).
+type Catalog struct {
+	locales  map[string]*locale
+	fallback string
+}
+
+type locale struct {
+	tag      string
+	plural   pluralRule
+	messages map[string]*message
+}
+
+type message struct {
+	text  *template.Template
+	forms map[string]*template.Template
+}
+
+// localeFile — формат locales/<tag>.json.
+type localeFile struct {
+	// Plural — семейство правил множественного числа из pluralRules.
+	Plural   string                     `json:"plural"`
+	Messages map[string]json.RawMessage `json:"messages"`
+}
+
+var (
+	embeddedOnce    sync.Once
+	embeddedCatalog *Catalog
+	defaultOnce     sync.Once
+	defaultCatalog  *Catalog
+	defaultErr      error
+)
+
+// Embedded — каталог из встроенных в бинарник локалей. Ошибка в них — ошибка сборки, поэтому паника.
+func Embedded() *Catalog {
+	embeddedOnce.Do(func() {
+		catalog, err := Load(DefaultLocale, embeddedLocales())
+		if err != nil {
+			panic(fmt.Sprintf("i18n: embedded locales are invalid: %v", err))
+		}
+		embeddedCatalog = catalog
+	})
+	return embeddedCatalog
+}
+
+func embeddedLocales() fs.FS {
+	sub, err := fs.Sub(localesFS, "locales")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// Default — встроенные локали плюс файлы из LOCALES_DIR: так можно добавить язык или поправить
+// тексты без пересборки. Файл из LOCALES_DIR заменяет встроенную локаль с тем же именем.
+// Локаль по умолчанию задается DEFAULT_LOCALE.
+func Default() (*Catalog, error) {
+	defaultOnce.Do(func() {
+		fallback := normalizeTag(os.Getenv("DEFAULT_LOCALE"))
+		if fallback == "" {
+			fallback = DefaultLocale
+		}
+
+		fsyss := []fs.FS{embeddedLocales()}
+		if dir := os.Getenv("LOCALES_DIR"); dir != "" {
+			fsyss = append(fsyss, os.DirFS(dir))
+		}
+
+		defaultCatalog, defaultErr = Load(fallback, fsyss...)
+		if defaultErr != nil {
+			return
+		}
+
+		for _, tag := range defaultCatalog.Locales() {
+			if missing := defaultCatalog.MissingKeys(tag); len(missing) > 0 {
+				fmt.Printf("⚠️ Locale %s has no translation for %s, %s is used instead\n", tag, strings.Join(missing, ", "), fallback)
+			}
+		}
+	})
+	return defaultCatalog, defaultErr
+}
+
+// Load читает *.json из каждого fsys по очереди; локаль из более позднего fsys заменяет раннюю.
+func Load(fallback string, fsyss ...fs.FS) (*Catalog, error) {
+	c := &Catalog{locales: make(map[string]*locale), fallback: fallback}
+
+	for _, fsys := range fsyss {
+		names, err := fs.Glob(fsys, "*.json")
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, err
+			}
+			tag := normalizeTag(strings.TrimSuffix(path.Base(name), ".json"))
+			loc, err := c.parseLocale(tag, data)
+			if err != nil {
+				return nil, fmt.Errorf("locale %s: %w", tag, err)
+			}
+			c.locales[tag] = loc
+		}
+	}
+
+	if _, ok := c.locales[fallback]; !ok {
+		return nil, fmt.Errorf("fallback locale %s not found", fallback)
+	}
+	return c, nil
+}
+
+func (c *Catalog) parseLocale(tag string, data []byte) (*locale, error) {
+	var file localeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	rule, ok := pluralRules[file.Plural]
+	if !ok {
+		return nil, fmt.Errorf("unknown plural rule %q", file.Plural)
+	}
+
+	loc := &locale{tag: tag, plural: rule, messages: make(map[string]*message, len(file.Messages))}
+	funcs := c.funcs(loc)
+
+	for key, raw := range file.Messages {
+		parse := func(name, text string) (*template.Template, error) {
+			return template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+		}
+
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			tmpl, err := parse(key, text)
+			if err != nil {
+				return nil, err
+			}
+			loc.messages[key] = &message{text: tmpl}
+			continue
+		}
+
+		var forms map[string]string
+		if err := json.Unmarshal(raw, &forms); err != nil {
+			return nil, fmt.Errorf("message %s must be a string or an object of plural forms", key)
+		}
+		for _, form := range rule.forms() {
+			if _, ok := forms[form]; !ok {
+				return nil, fmt.Errorf("message %s has no %q form", key, form)
+			}
+		}
+		msg := &message{forms: make(map[string]*template.Template, len(forms))}
+		for form, text := range forms {
+			tmpl, err := parse(key+"."+form, text)
+			if err != nil {
+				return nil, err
+			}
+			msg.forms[form] = tmpl
+		}
+		loc.messages[key] = msg
+	}
+
+	return loc, nil
+}
+
+// funcs — функции, доступные в шаблонах локали:
+//
+//	{{plural "days" .Days}} — форма сообщения "days" для числа, внутри формы число доступно как {{.N}};
+//	{{duration .Seconds}} — длительность по шаблону "duration" с полями .Minutes и .Seconds;
+//	{{t "key" .}} — другое сообщение с теми же параметрами.
+func (c *Catalog) funcs(loc *locale) template.FuncMap {
+	return template.FuncMap{
+		"plural": func(key string, n int) (string, error) {
+			return c.render(loc, key, map[string]interface{}{"N": n})
+		},
+		"duration": func(seconds int) (string, error) {
+			return c.render(loc, "duration", map[string]interface{}{"Minutes": seconds / 60, "Seconds": seconds % 60})
+		},
+		"t": func(key string, params map[string]interface{}) (string, error) {
+			return c.render(loc, key, params)
+		},
+	}
+}
+
+// Locales — теги всех загруженных локалей.
+func (c *Catalog) Locales() []string {
+	tags := make([]string, 0, len(c.locales))
+	for tag := range c.locales {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Match выбирает первую поддерживаемую локаль из кандидатов ("en-US" подходит к "en").
+// Если ни одна не подходит, возвращает локаль по умолчанию.
+func (c *Catalog) Match(tags ...string) string {
+	if tag, ok := c.Supported(tags...); ok {
+		return tag
+	}
+	return c.fallback
+}
+
+// Supported — как Match, но сообщает, нашлась ли поддерживаемая локаль.
+func (c *Catalog) Supported(tags ...string) (string, bool) {
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" {
+			continue
+		}
+		if _, ok := c.locales[tag]; ok {
+			return tag, true
+		}
+		if base, _, found := strings.Cut(tag, "-"); found {
+			if _, ok := c.locales[base]; ok {
+				return base, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Has сообщает, есть ли сообщение key в локали tag или в локали по умолчанию.
+func (c *Catalog) Has(tag, key string) bool {
+	return c.lookup(c.Match(tag), key) != nil
+}
+
+// Render подставляет params в сообщение key локали tag. Если в локали нет такого сообщения,
+// используется локаль по умолчанию. Для сообщений с формами множественного числа нужен params["N"].
+func (c *Catalog) Render(tag, key string, params map[string]interface{}) (string, error) {
+	return c.render(c.locales[c.Match(tag)], key, params)
+}
+
+// MissingKeys — сообщения локали по умолчанию, которых нет в локали tag.
+func (c *Catalog) MissingKeys(tag string) []string {
+	loc, ok := c.locales[tag]
+	if !ok || tag == c.fallback {
+		return nil
+	}
+
+	var missing []string
+	for key := range c.locales[c.fallback].messages {
+		if _, ok := loc.messages[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func (c *Catalog) lookup(tag, key string) *message {
+	if loc, ok := c.locales[tag]; ok {
+		if msg, ok := loc.messages[key]; ok {
+			return msg
+		}
+	}
+	return c.locales[c.fallback].messages[key]
+}
+
+func (c *Catalog) render(loc *locale, key string, params map[string]interface{}) (string, error) {
+	msg := c.lookup(loc.tag, key)
+	if msg == nil {
+		return "", fmt.Errorf("%w %q", ErrUnknownMessage, key)
+	}
+
+	tmpl := msg.text
+	if msg.forms != nil {
+		n, ok := params["N"].(int)
+		if !ok {
+			return "", fmt.Errorf("message %q needs an integer N", key)
+		}
+		// Форма выбирается по правилам той локали, из которой взято сообщение
+		rule := loc.plural
+		if _, own := loc.messages[key]; !own {
+			rule = c.locales[c.fallback].plural
+		}
+		tmpl = msg.forms[rule(n)]
+		if tmpl == nil {
+			tmpl = msg.forms["other"]
+		}
+		if tmpl == nil {
+			return "", fmt.Errorf("message %q has no %q form", key, rule(n))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, params); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+}
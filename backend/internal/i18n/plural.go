@@ -0,0 +1,82 @@
+package i18n
+
+// pluralRule выбирает категорию CLDR (zero, one, two, few, many, other) для целого числа.
+type pluralRule func(n int) string
+
+// pluralRules — семейства правил CLDR для целых чисел. Локаль выбирает семейство по имени
+// в поле "plural" своего JSON-файла, так что новый язык обычно не требует изменений в коде.
+var pluralRules = map[string]pluralRule{
+	// Английский, немецкий, испанский, итальянский, нидерландский, шведский и др.
+	"one_other": func(n int) string {
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+	// Французский, португальский (Бразилия): 0 и 1 — one.
+	"zero_one_other": func(n int) string {
+		if n == 0 || n == 1 {
+			return "one"
+		}
+		return "other"
+	},
+	// Русский, украинский, белорусский.
+	"east_slavic": func(n int) string {
+		n = abs(n)
+		switch {
+		case n%10 == 1 && n%100 != 11:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	},
+	// Чешский, словацкий.
+	"west_slavic": func(n int) string {
+		switch {
+		case n == 1:
+			return "one"
+		case n >= 2 && n <= 4:
+			return "few"
+		default:
+			return "other"
+		}
+	},
+	// Польский.
+	"polish": func(n int) string {
+		n = abs(n)
+		switch {
+		case n == 1:
+			return "one"
+		case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	},
+	// Японский, китайский, корейский, вьетнамский и др.: формы числа нет.
+	"none": func(int) string {
+		return "other"
+	},
+}
+
+// forms — категории, которые правило выдает для целых чисел; каждая нужна в сообщении с формами.
+func (r pluralRule) forms() []string {
+	seen := make(map[string]bool)
+	var forms []string
+	for n := 0; n < 200; n++ {
+		if form := r(n); !seen[form] {
+			seen[form] = true
+			forms = append(forms, form)
+		}
+	}
+	return forms
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DataExportStatusPending    = "pending"
+	DataExportStatusProcessing = "processing"
+	DataExportStatusReady      = "ready"
+	DataExportStatusFailed     = "failed"
+	DataExportStatusExpired    = "expired"
+)
+
+type DataExport struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Status      string     `json:"status" db:"status"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	// DownloadURL — подписанная ссылка, действует DownloadURLExpiresAt.
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+// ExportStats — stats.json в архиве данных. Считается по love_events, а не по агрегатам,
+// чтобы совпадать с выгруженными событиями.
+type ExportStats struct {
+	Sent     HeartTotals `json:"sent"`
+	Received HeartTotals `json:"received"`
+	Streak   Streak      `json:"streak"`
+}
+
+// HeartTotals — итоги по отправленным или полученным сердечкам.
+type HeartTotals struct {
+	TotalEvents            int        `json:"total_events"`
+	TotalDurationSeconds   int        `json:"total_duration_seconds"`
+	AverageDurationSeconds float64    `json:"average_duration_seconds"`
+	LongestHoldSeconds     int        `json:"longest_hold_seconds"`
+	FirstEventAt           *time.Time `json:"first_event_at,omitempty"`
+	LastEventAt            *time.Time `json:"last_event_at,omitempty"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DevicePlatformIOS     = "ios"
+	DevicePlatformAndroid = "android"
+	DevicePlatformWeb     = "web"
+)
+
+type Device struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	Token       string    `json:"token" db:"token"`
+	Platform    string    `json:"platform" db:"platform"`
+	Environment string    `json:"environment" db:"environment"`
+	AppVersion  *string   `json:"app_version,omitempty" db:"app_version"`
+	Locale      *string   `json:"locale,omitempty" db:"locale"`
+	Active      bool      `json:"active" db:"active"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" db:"last_seen_at"`
+	// Состояние доставки: причина последней ошибки APNs и время, с которого токен недействителен.
+	FailureCount      int        `json:"failure_count" db:"failure_count"`
+	LastFailureReason *string    `json:"last_failure_reason,omitempty" db:"last_failure_reason"`
+	LastFailureAt     *time.Time `json:"last_failure_at,omitempty" db:"last_failure_at"`
+	LastDeliveredAt   *time.Time `json:"last_delivered_at,omitempty" db:"last_delivered_at"`
+	InvalidatedAt     *time.Time `json:"invalidated_at,omitempty" db:"invalidated_at"`
+	// Ключи шифрования подписки Web Push; Token у таких устройств — endpoint push-сервиса.
+	WebPushP256dh *string `json:"-" db:"web_push_p256dh"`
+	WebPushAuth   *string `json:"-" db:"web_push_auth"`
+}
+
+// AdminDevice — устройство с владельцем для админского списка.
+type AdminDevice struct {
+	Device
+	UserID   uuid.UUID `json:"user_id" db:"user_id"`
+	Username string    `json:"username" db:"username"`
+}
+
+type AdminDeviceFilter struct {
+	UserID *uuid.UUID
+	// FailingOnly оставляет устройства, у которых была хотя бы одна ошибка доставки.
+	FailingOnly bool
+	Limit       int
+}
+
+type RegisterDeviceRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Platform string `json:"platform"`
+	// Environment — окружение APNs токена: "production" (по умолчанию) или "development".
+	Environment string  `json:"environment"`
+	AppVersion  *string `json:"app_version" binding:"omitempty,max=32"`
+	Locale      *string `json:"locale" binding:"omitempty,max=35"`
+}
+
+// WebPushSubscriptionRequest повторяет PushSubscription.toJSON() браузера.
+type WebPushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys" binding:"required"`
+	Locale *string `json:"locale" binding:"omitempty,max=35"`
+}
+
+type DeleteWebPushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+}
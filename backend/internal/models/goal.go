@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	GoalMetricHearts      = "hearts"
+	GoalMetricHoldSeconds = "hold_seconds"
+)
+
+type PairGoal struct {
+	ID         uuid.UUID     `json:"id" db:"id"`
+	PairID     uuid.UUID     `json:"pair_id" db:"pair_id"`
+	Metric     string        `json:"metric" db:"metric"`
+	Period     string        `json:"period" db:"period"`
+	Target     int           `json:"target" db:"target"`
+	Timezone   string        `json:"timezone" db:"timezone"`
+	CreatedBy  *uuid.UUID    `json:"created_by,omitempty" db:"created_by"`
+	ReplacedBy *uuid.UUID    `json:"replaced_by,omitempty" db:"replaced_by"`
+	CreatedAt  time.Time     `json:"created_at" db:"created_at"`
+	ArchivedAt *time.Time    `json:"archived_at,omitempty" db:"archived_at"`
+	Progress   *GoalProgress `json:"progress,omitempty"`
+}
+
+// GoalProgress — прогресс цели за один период (день или неделя).
+type GoalProgress struct {
+	// GoalID — версия цели, по которой посчитан период.
+	GoalID      uuid.UUID `json:"goal_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	Value       int       `json:"value"`
+	Target      int       `json:"target"`
+	Completed   bool      `json:"completed"`
+}
+
+type GoalHistory struct {
+	Goal PairGoal `json:"goal"`
+	// Versions — все версии цели по цепочке replaced_by, от новой к старой.
+	Versions []PairGoal     `json:"versions"`
+	Periods  []GoalProgress `json:"periods"`
+}
+
+type CreateGoalRequest struct {
+	Metric string `json:"metric" binding:"required"`
+	Period string `json:"period" binding:"required"`
+	Target int    `json:"target" binding:"required,min=1"`
+}
+
+type UpdateGoalRequest struct {
+	Metric *string `json:"metric"`
+	Period *string `json:"period"`
+	Target *int    `json:"target"`
+}
+
+// GoalProgressEvent — websocket-сообщение goal_progress после нового сердечка в паре.
+type GoalProgressEvent struct {
+	PairID uuid.UUID  `json:"pair_id"`
+	Goals  []PairGoal `json:"goals"`
+}
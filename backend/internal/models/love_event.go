@@ -12,16 +12,112 @@ type LoveEvent struct {
 	SenderID       uuid.UUID  `json:"sender_id" db:"sender_id"`
 	Sender         *User      `json:"sender,omitempty"`
 	DurationSeconds int       `json:"duration_seconds" db:"duration_seconds"`
+	EventType      string     `json:"event_type" db:"event_type"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	SeenAt         *time.Time `json:"seen_at,omitempty" db:"seen_at"`
 	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	Reactions      []Reaction `json:"reactions,omitempty"`
 }
 
+const (
+	LoveEventTypeStandard = "standard"
+	LoveEventTypeLive     = "live"
+	LoveEventTypeScheduled = "scheduled"
+)
+
 type SendLoveRequest struct {
 	DurationSeconds int `json:"duration_seconds" binding:"required,min=1"`
 }
 
+type MarkSeenRequest struct {
+	EventID uuid.UUID `json:"event_id" binding:"required"`
+}
+
+type LoveSeenEvent struct {
+	UpToEventID uuid.UUID `json:"up_to_event_id"`
+	SeenAt      time.Time `json:"seen_at"`
+	Count       int       `json:"count"`
+}
+
 type Stats struct {
 	TotalEvents          int     `json:"total_events"`
 	TotalDurationSeconds int     `json:"total_duration_seconds"`
 	AverageDurationSeconds float64 `json:"average_duration_seconds"`
+	SyncedHearts         int     `json:"synced_hearts"`
+	SyncedDurationSeconds int    `json:"synced_duration_seconds"`
+	Streak               Streak  `json:"streak"`
+}
+
+
+const (
+	HistoryDirectionSent     = "sent"
+	HistoryDirectionReceived = "received"
+)
+
+// HistoryQuery — параметры страницы истории: курсор и фильтры.
+type HistoryQuery struct {
+	Cursor      string
+	Limit       int
+	Direction   string
+	From        *time.Time
+	To          *time.Time
+	MinDuration *int
+	MaxDuration *int
+	EventType   string
 }
 
+type PartnerStats struct {
+	UserID                 uuid.UUID  `json:"user_id"`
+	Username               string     `json:"username"`
+	TotalEvents            int        `json:"total_events"`
+	TotalDurationSeconds   int        `json:"total_duration_seconds"`
+	AverageDurationSeconds float64    `json:"average_duration_seconds"`
+	LongestHoldSeconds     int        `json:"longest_hold_seconds"`
+	FirstEventAt           *time.Time `json:"first_event_at"`
+	LastEventAt            *time.Time `json:"last_event_at"`
+}
+
+// PairStats — статистика пары с точки зрения текущего пользователя:
+// Me — отправленные им сердечки, Partner — полученные от партнера.
+type PairStats struct {
+	PairID               uuid.UUID    `json:"pair_id"`
+	PairCreatedAt        time.Time    `json:"pair_created_at"`
+	ArchivedAt           *time.Time   `json:"archived_at,omitempty"`
+	Me                   PartnerStats `json:"me"`
+	Partner              PartnerStats `json:"partner"`
+	TotalEvents          int          `json:"total_events"`
+	TotalDurationSeconds int          `json:"total_duration_seconds"`
+	FirstEventAt         *time.Time   `json:"first_event_at"`
+	LastEventAt          *time.Time   `json:"last_event_at"`
+	// SentReceivedRatio — отправлено / получено; null, пока ничего не получено.
+	SentReceivedRatio *float64 `json:"sent_received_ratio"`
+	Streak            Streak   `json:"streak"`
+}
+
+const (
+	StatsBucketDay   = "day"
+	StatsBucketWeek  = "week"
+	StatsBucketMonth = "month"
+	StatsBucketYear  = "year"
+
+	StatsScopeUser = "user"
+	StatsScopePair = "pair"
+)
+
+type TimeSeriesPoint struct {
+	// Bucket — начало периода в часовом поясе запроса, YYYY-MM-DD.
+	Bucket               string `json:"bucket"`
+	Count                int    `json:"count"`
+	TotalDurationSeconds int    `json:"total_duration_seconds"`
+}
+
+type TimeSeriesStats struct {
+	Bucket   string            `json:"bucket"`
+	Scope    string            `json:"scope"`
+	Timezone string            `json:"timezone"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Points   []TimeSeriesPoint `json:"points"`
+	// Heatmap[день недели][час]: понедельник = 0, часы 0-23 по локальному времени.
+	Heatmap [7][24]int `json:"heatmap"`
+}
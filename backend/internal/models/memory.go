@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MemoryKindFirstHeart = "first_heart"
+	MemoryKindPaired     = "paired"
+	MemoryKindLongHold   = "long_hold"
+)
+
+// Memory — событие, случившееся в этот же день несколько лет назад.
+type Memory struct {
+	Kind            string     `json:"kind"`
+	YearsAgo        int        `json:"years_ago"`
+	Date            string     `json:"date"`
+	PairID          uuid.UUID  `json:"pair_id"`
+	PartnerUsername string     `json:"partner_username"`
+	LoveEventID     *uuid.UUID `json:"love_event_id,omitempty"`
+	SenderID        *uuid.UUID `json:"sender_id,omitempty"`
+	DurationSeconds int        `json:"duration_seconds,omitempty"`
+	OccurredAt      time.Time  `json:"occurred_at"`
+}
+
+type MemoriesToday struct {
+	Date     string   `json:"date"`
+	Timezone string   `json:"timezone"`
+	Memories []Memory `json:"memories"`
+}
+
+type MemorySettings struct {
+	PushEnabled          bool   `json:"push_enabled"`
+	PushAt               string `json:"push_at"`
+	IncludeArchivedPairs bool   `json:"include_archived_pairs"`
+}
+
+type UpdateMemorySettingsRequest struct {
+	PushEnabled          *bool   `json:"push_enabled"`
+	PushAt               *string `json:"push_at"`
+	IncludeArchivedPairs *bool   `json:"include_archived_pairs"`
+}
@@ -13,6 +13,8 @@ type Pair struct {
 	User1     *User     `json:"user1,omitempty"`
 	User2     *User     `json:"user2,omitempty"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty" db:"archived_at"`
+	Goals     []PairGoal `json:"goals,omitempty"`
 }
 
 type CreatePairRequest struct {
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AllowedReactions — фиксированный набор реакций на полученное сердечко.
+var AllowedReactions = []string{"❤️", "😍", "🥰", "😘", "🤗", "😊", "🥺", "🔥"}
+
+func IsAllowedReaction(emoji string) bool {
+	for _, allowed := range AllowedReactions {
+		if emoji == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+type Reaction struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	LoveEventID uuid.UUID `json:"love_event_id" db:"love_event_id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	Emoji       string    `json:"emoji" db:"emoji"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type SetReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+type ReactionEvent struct {
+	LoveEventID uuid.UUID `json:"love_event_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Reaction    *Reaction `json:"reaction"`
+}
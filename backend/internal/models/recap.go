@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LoveRecap struct {
+	ID          uuid.UUID   `json:"id" db:"id"`
+	PairID      uuid.UUID   `json:"pair_id" db:"pair_id"`
+	Period      string      `json:"period" db:"period"`
+	PeriodStart string      `json:"period_start" db:"period_start"`
+	PeriodEnd   string      `json:"period_end" db:"period_end"`
+	Timezone    string      `json:"timezone" db:"timezone"`
+	Report      RecapReport `json:"report" db:"report"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+}
+
+// RecapReport — содержимое отчета, хранится в love_recaps.report как JSON.
+type RecapReport struct {
+	TotalHearts          int               `json:"total_hearts"`
+	TotalDurationSeconds int               `json:"total_duration_seconds"`
+	Partners             []RecapPartner    `json:"partners"`
+	LongestHold          *RecapLongestHold `json:"longest_hold"`
+	// BusiestHour — час (0–23), в который отправлено больше всего сердечек; null без сердечек.
+	BusiestHour  *int `json:"busiest_hour"`
+	ActiveDays   int  `json:"active_days"`
+	SyncedHearts int  `json:"synced_hearts"`
+	// LongestStreak — самая длинная серия пары внутри периода.
+	LongestStreak int                 `json:"longest_streak"`
+	Previous      RecapPreviousPeriod `json:"previous"`
+}
+
+type RecapPartner struct {
+	UserID               uuid.UUID `json:"user_id"`
+	Username             string    `json:"username"`
+	Hearts               int       `json:"hearts"`
+	TotalDurationSeconds int       `json:"total_duration_seconds"`
+}
+
+type RecapLongestHold struct {
+	SenderID        uuid.UUID `json:"sender_id"`
+	DurationSeconds int       `json:"duration_seconds"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// RecapPreviousPeriod — сравнение с предыдущим периодом той же длины.
+type RecapPreviousPeriod struct {
+	PeriodStart          string `json:"period_start"`
+	TotalHearts          int    `json:"total_hearts"`
+	TotalDurationSeconds int    `json:"total_duration_seconds"`
+	// HeartsChangePercent — изменение числа сердечек в процентах; null, если в прошлом периоде их не было.
+	HeartsChangePercent *float64 `json:"hearts_change_percent"`
+}
+
+type GenerateRecapRequest struct {
+	Period string `json:"period" binding:"required"`
+	// Date — любой день периода (YYYY-MM-DD); период должен уже закончиться.
+	Date string `json:"date" binding:"required"`
+}
@@ -0,0 +1,15 @@
+package models
+
+type ReminderSettings struct {
+	Enabled           bool   `json:"enabled"`
+	RemindAt          string `json:"remind_at"`
+	Timezone          string `json:"timezone"`
+	SkipIfPartnerSent bool   `json:"skip_if_partner_sent"`
+}
+
+type UpdateReminderSettingsRequest struct {
+	Enabled           *bool   `json:"enabled"`
+	RemindAt          *string `json:"remind_at"`
+	Timezone          *string `json:"timezone"`
+	SkipIfPartnerSent *bool   `json:"skip_if_partner_sent"`
+}
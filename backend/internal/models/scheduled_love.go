@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ScheduledLoveStatusPending   = "pending"
+	ScheduledLoveStatusDelivered = "delivered"
+	ScheduledLoveStatusCancelled = "cancelled"
+	ScheduledLoveStatusFailed    = "failed"
+)
+
+type ScheduledLove struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	SenderID        uuid.UUID  `json:"sender_id" db:"sender_id"`
+	PairID          uuid.UUID  `json:"pair_id" db:"pair_id"`
+	DurationSeconds int        `json:"duration_seconds" db:"duration_seconds"`
+	DeliverAt       time.Time  `json:"deliver_at" db:"deliver_at"`
+	Status          string     `json:"status" db:"status"`
+	LoveEventID     *uuid.UUID `json:"love_event_id,omitempty" db:"love_event_id"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+type ScheduleLoveRequest struct {
+	DurationSeconds int       `json:"duration_seconds" binding:"required,min=1"`
+	DeliverAt       time.Time `json:"deliver_at" binding:"required"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// Streak — серия дней подряд с отправленными сердечками (дни считаются в часовом поясе пользователя).
+type Streak struct {
+	Current int `json:"current"`
+	Longest int `json:"longest"`
+	// StartedOn — первый день текущей серии (YYYY-MM-DD), пусто, если серии нет.
+	StartedOn string `json:"started_on,omitempty"`
+	// AtRisk — серия еще жива, но сегодня сердечко не отправлено.
+	AtRisk bool `json:"at_risk"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SyncedHeart struct {
+	ID               uuid.UUID `json:"id" db:"id"`
+	PairID           uuid.UUID `json:"pair_id" db:"pair_id"`
+	FirstEventID     uuid.UUID `json:"first_event_id" db:"first_event_id"`
+	SecondEventID    uuid.UUID `json:"second_event_id" db:"second_event_id"`
+	OverlapStartedAt time.Time `json:"overlap_started_at" db:"overlap_started_at"`
+	OverlapSeconds   int       `json:"overlap_seconds" db:"overlap_seconds"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
@@ -12,7 +12,9 @@ type User struct {
 	AppleID     *string   `json:"apple_id,omitempty" db:"apple_id"`
 	Username    string    `json:"username" db:"username"`
 	PasswordHash *string  `json:"-" db:"password_hash"`
-	DeviceToken *string   `json:"-" db:"device_token"`
+	Timezone    string    `json:"timezone,omitempty" db:"timezone"`
+	// Locale — выбранный язык уведомлений; nil — язык устройства.
+	Locale      *string   `json:"locale,omitempty" db:"locale"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
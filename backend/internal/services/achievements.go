@@ -0,0 +1,352 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// achievementProgress — показатели пользователя, по которым проверяются правила.
+type achievementProgress struct {
+	Now                  time.Time
+	TotalEvents          int
+	TotalDurationSeconds int
+	LongestStreak        int
+	// PairedSince — начало текущей пары, nil без пары.
+	PairedSince *time.Time
+	// StreakDays и Location — закрытые дни и часовой пояс, по которым посчитана LongestStreak.
+	StreakDays map[time.Time]bool
+	Location   *time.Location
+}
+
+// achievementRule — правило достижения. Название и описание берутся из каталога
+// по ключам achievement.<code> и achievement.<code>.description на языке пользователя.
+type achievementRule struct {
+	Code     string
+	Unlocked func(p achievementProgress) bool
+	// ReachedAt — когда условие было выполнено на самом деле; это время сохраняется как unlocked_at,
+	// чтобы достижения, открытые задним числом (backfill), получали дату события, а не дату запуска.
+	ReachedAt func(db queryer, userID uuid.UUID, p achievementProgress) (time.Time, error)
+}
+
+func pairedFor(p achievementProgress, years, months int) bool {
+	return p.PairedSince != nil && !p.PairedSince.AddDate(years, months, 0).After(p.Now)
+}
+
+func pairedAt(years, months int) func(queryer, uuid.UUID, achievementProgress) (time.Time, error) {
+	return func(_ queryer, _ uuid.UUID, p achievementProgress) (time.Time, error) {
+		return p.PairedSince.AddDate(years, months, 0), nil
+	}
+}
+
+// nthHeartAt — время отправки n-го сердечка пользователя.
+func nthHeartAt(n int) func(queryer, uuid.UUID, achievementProgress) (time.Time, error) {
+	return func(db queryer, userID uuid.UUID, _ achievementProgress) (time.Time, error) {
+		var at time.Time
+		err := db.QueryRow(
+			"SELECT created_at FROM love_events WHERE sender_id = $1 ORDER BY created_at, id OFFSET $2 LIMIT 1",
+			userID, n-1,
+		).Scan(&at)
+		return at, err
+	}
+}
+
+// heldForAt — время сердечка, на котором суммарное удержание достигло seconds.
+func heldForAt(seconds int) func(queryer, uuid.UUID, achievementProgress) (time.Time, error) {
+	return func(db queryer, userID uuid.UUID, _ achievementProgress) (time.Time, error) {
+		var at time.Time
+		err := db.QueryRow(
+			`SELECT created_at FROM (
+				SELECT created_at, SUM(duration_seconds) OVER (ORDER BY created_at, id) AS total
+				FROM love_events
+				WHERE sender_id = $1
+			) t
+			WHERE total >= $2
+			ORDER BY created_at
+			LIMIT 1`,
+			userID, seconds,
+		).Scan(&at)
+		return at, err
+	}
+}
+
+// streakReachedAt — время первого сердечка, закрывшего n-й день первой серии длиной n.
+// День, закрытый сердечком вскоре после следующей полуночи, получает время этого сердечка.
+func streakReachedAt(n int) func(queryer, uuid.UUID, achievementProgress) (time.Time, error) {
+	return func(db queryer, userID uuid.UUID, p achievementProgress) (time.Time, error) {
+		day, ok := firstRunReaching(p.StreakDays, n)
+		if !ok {
+			return time.Time{}, fmt.Errorf("streak of %d days not found", n)
+		}
+
+		var at time.Time
+		err := db.QueryRow(
+			"SELECT MIN(created_at) FROM love_events WHERE sender_id = $1 AND created_at >= $2",
+			userID, time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, p.Location),
+		).Scan(&at)
+		return at, err
+	}
+}
+
+// achievementRules — все достижения в порядке показа. Код правила хранится в user_achievements,
+// поэтому менять его у существующих правил нельзя.
+var achievementRules = []achievementRule{
+	{
+		Code:      "first_heart",
+		Unlocked:  func(p achievementProgress) bool { return p.TotalEvents >= 1 },
+		ReachedAt: nthHeartAt(1),
+	},
+	{
+		Code:      "hearts_100",
+		Unlocked:  func(p achievementProgress) bool { return p.TotalEvents >= 100 },
+		ReachedAt: nthHeartAt(100),
+	},
+	{
+		Code:      "hearts_1000",
+		Unlocked:  func(p achievementProgress) bool { return p.TotalEvents >= 1000 },
+		ReachedAt: nthHeartAt(1000),
+	},
+	{
+		Code:      "hold_hour",
+		Unlocked:  func(p achievementProgress) bool { return p.TotalDurationSeconds >= 3600 },
+		ReachedAt: heldForAt(3600),
+	},
+	{
+		Code:      "hold_day",
+		Unlocked:  func(p achievementProgress) bool { return p.TotalDurationSeconds >= 24*3600 },
+		ReachedAt: heldForAt(24 * 3600),
+	},
+	{
+		Code:      "streak_7",
+		Unlocked:  func(p achievementProgress) bool { return p.LongestStreak >= 7 },
+		ReachedAt: streakReachedAt(7),
+	},
+	{
+		Code:      "streak_30",
+		Unlocked:  func(p achievementProgress) bool { return p.LongestStreak >= 30 },
+		ReachedAt: streakReachedAt(30),
+	},
+	{
+		Code:      "paired_month",
+		Unlocked:  func(p achievementProgress) bool { return pairedFor(p, 0, 1) },
+		ReachedAt: pairedAt(0, 1),
+	},
+	{
+		Code:      "paired_year",
+		Unlocked:  func(p achievementProgress) bool { return pairedFor(p, 1, 0) },
+		ReachedAt: pairedAt(1, 0),
+	},
+}
+
+func loadAchievementProgress(db *sql.DB, userID uuid.UUID) (achievementProgress, error) {
+	progress := achievementProgress{Now: time.Now()}
+
+	err := db.QueryRow(
+		`SELECT COALESCE(SUM(event_count), 0), COALESCE(SUM(total_duration_seconds), 0)
+		FROM (
+			SELECT event_count, total_duration_seconds FROM love_user_daily_rollups WHERE user_id = $1
+			UNION ALL
+			SELECT 1, duration_seconds FROM love_events WHERE sender_id = $1 AND NOT rolled_up
+		) r`,
+		userID,
+	).Scan(&progress.TotalEvents, &progress.TotalDurationSeconds)
+	if err != nil {
+		return progress, err
+	}
+
+	// Та же серия, что в UserStreak, но дни нужны еще и для даты достижения
+	progress.Location, err = UserLocation(db, userID)
+	if err != nil {
+		return progress, err
+	}
+	progress.StreakDays, err = streakDays(db, progress.Location, StreakGrace(), userID, nil)
+	if err != nil {
+		return progress, err
+	}
+	progress.LongestStreak = longestRun(progress.StreakDays)
+
+	var pairedSince time.Time
+	err = db.QueryRow(
+		"SELECT created_at FROM pairs WHERE (user1_id = $1 OR user2_id = $1) AND archived_at IS NULL",
+		userID,
+	).Scan(&pairedSince)
+	if err == nil {
+		progress.PairedSince = &pairedSince
+	} else if err != sql.ErrNoRows {
+		return progress, err
+	}
+
+	return progress, nil
+}
+
+// EvaluateAchievements проверяет все правила и сохраняет новые достижения.
+// Возвращает только те, что открылись при этом вызове.
+func EvaluateAchievements(db *sql.DB, userID uuid.UUID) (unlocked []models.Achievement, err error) {
+	progress, err := loadAchievementProgress(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	owned, err := unlockedAchievements(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Открытые до ошибки достижения тоже возвращаются, поэтому тексты подставляются на любом выходе
+	defer func() {
+		if len(unlocked) == 0 {
+			return
+		}
+		locale := userLocale(db, userID)
+		for i := range unlocked {
+			localizeAchievement(locale, &unlocked[i])
+		}
+	}()
+
+	for _, rule := range achievementRules {
+		if _, ok := owned[rule.Code]; ok || !rule.Unlocked(progress) {
+			continue
+		}
+
+		unlockedAt, err := rule.ReachedAt(db, userID, progress)
+		if err != nil {
+			return unlocked, fmt.Errorf("%s: %w", rule.Code, err)
+		}
+
+		err = db.QueryRow(
+			`INSERT INTO user_achievements (user_id, code, unlocked_at) VALUES ($1, $2, $3)
+			ON CONFLICT DO NOTHING
+			RETURNING unlocked_at`,
+			userID, rule.Code, unlockedAt,
+		).Scan(&unlockedAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return unlocked, err
+		}
+
+		unlocked = append(unlocked, models.Achievement{
+			Code:       rule.Code,
+			Unlocked:   true,
+			UnlockedAt: &unlockedAt,
+		})
+	}
+
+	return unlocked, nil
+}
+
+// HandleAchievements проверяет достижения обоих партнеров после нового сердечка
+// и объявляет открытые через websocket (achievement_unlocked) и пуш.
+func HandleAchievements(db *sql.DB, broadcaster Broadcaster, event models.LoveEvent, partnerID uuid.UUID) {
+	for _, userID := range []uuid.UUID{event.SenderID, partnerID} {
+		unlocked, err := EvaluateAchievements(db, userID)
+		if err != nil {
+			fmt.Printf("Failed to evaluate achievements for user %s: %v\n", userID, err)
+		}
+
+		for _, achievement := range unlocked {
+			broadcaster.SendToUser(userID, "achievement_unlocked", achievement)
+			SendAchievementNotification(db, userID, achievement.Title)
+		}
+	}
+}
+
+// localizeAchievement подставляет название и описание достижения на языке locale.
+// Если в каталоге их нет, название заменяется кодом достижения.
+func localizeAchievement(locale string, achievement *models.Achievement) {
+	catalog := notificationCatalog()
+	key := "achievement." + achievement.Code
+
+	title, err := catalog.Render(locale, key, nil)
+	if err != nil {
+		fmt.Printf("Failed to render achievement %s title: %v\n", achievement.Code, err)
+		title = achievement.Code
+	}
+	description, err := catalog.Render(locale, key+".description", nil)
+	if err != nil {
+		fmt.Printf("Failed to render achievement %s description: %v\n", achievement.Code, err)
+	}
+
+	achievement.Title = title
+	achievement.Description = description
+}
+
+// unlockedAchievements возвращает время открытия уже сохраненных достижений по коду.
+func unlockedAchievements(db queryer, userID uuid.UUID) (map[string]time.Time, error) {
+	rows, err := db.Query("SELECT code, unlocked_at FROM user_achievements WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unlockedAt := make(map[string]time.Time)
+	for rows.Next() {
+		var code string
+		var at time.Time
+		if err := rows.Scan(&code, &at); err != nil {
+			return nil, err
+		}
+		unlockedAt[code] = at
+	}
+	return unlockedAt, rows.Err()
+}
+
+// ListAchievements возвращает все достижения с отметкой, открыты ли они у пользователя.
+func ListAchievements(db *sql.DB, userID uuid.UUID) ([]models.Achievement, error) {
+	unlockedAt, err := unlockedAchievements(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	locale := userLocale(db, userID)
+	achievements := make([]models.Achievement, 0, len(achievementRules))
+	for _, rule := range achievementRules {
+		achievement := models.Achievement{Code: rule.Code}
+		localizeAchievement(locale, &achievement)
+		if at, ok := unlockedAt[rule.Code]; ok {
+			achievement.Unlocked = true
+			achievement.UnlockedAt = &at
+		}
+		achievements = append(achievements, achievement)
+	}
+
+	return achievements, nil
+}
+
+// BackfillAchievements проверяет правила для всех пользователей без уведомлений.
+// Возвращает число открытых достижений.
+func BackfillAchievements(db *sql.DB) (int, error) {
+	rows, err := db.Query("SELECT id FROM users ORDER BY created_at")
+	if err != nil {
+		return 0, err
+	}
+
+	var userIDs []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, userID := range userIDs {
+		unlocked, err := EvaluateAchievements(db, userID)
+		if err != nil {
+			return total, fmt.Errorf("user %s: %w", userID, err)
+		}
+		total += len(unlocked)
+	}
+
+	return total, nil
+}
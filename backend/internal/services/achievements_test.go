@@ -0,0 +1,25 @@
+package services
+
+import (
+	"love-connection/backend/internal/i18n"
+	"testing"
+)
+
+// У каждого правила должны быть название и описание во всех встроенных локалях.
+func TestAchievementTexts(t *testing.T) {
+	catalog := i18n.Embedded()
+
+	for _, locale := range catalog.Locales() {
+		if missing := catalog.MissingKeys(locale); len(missing) > 0 {
+			t.Errorf("locale %s misses %v", locale, missing)
+		}
+		for _, rule := range achievementRules {
+			for _, key := range []string{"achievement." + rule.Code, "achievement." + rule.Code + ".description"} {
+				text, err := catalog.Render(locale, key, nil)
+				if err != nil || text == "" {
+					t.Errorf("locale %s: %s = %q, %v", locale, key, text, err)
+				}
+			}
+		}
+	}
+}
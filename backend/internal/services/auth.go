@@ -11,6 +11,9 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// defaultJWTSecret используется, когда JWT_SECRET не задан; он опубликован в репозитории.
+const defaultJWTSecret = "your-secret-key-change-in-production"
+
 var jwtSecret = []byte(getJWTSecret())
 
 type Claims struct {
@@ -21,7 +24,7 @@ type Claims struct {
 func getJWTSecret() string {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
-		return "your-secret-key-change-in-production"
+		return defaultJWTSecret
 	}
 	return secret
 }
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/pkg/apns"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrDeviceNotFound = errors.New("device not found")
+	ErrInvalidDevice  = errors.New("invalid device")
+)
+
+const (
+	deviceColumns = `id, token, platform, environment, app_version, locale, active, created_at, last_seen_at,
+		failure_count, last_failure_reason, last_failure_at, last_delivered_at, invalidated_at,
+		web_push_p256dh, web_push_auth`
+
+	defaultAdminDeviceLimit = 100
+	maxAdminDeviceLimit     = 500
+)
+
+func scanDevice(row interface{ Scan(...interface{}) error }, extra ...interface{}) (models.Device, error) {
+	var device models.Device
+	dest := []interface{}{
+		&device.ID, &device.Token, &device.Platform, &device.Environment, &device.AppVersion,
+		&device.Locale, &device.Active, &device.CreatedAt, &device.LastSeenAt,
+		&device.FailureCount, &device.LastFailureReason, &device.LastFailureAt, &device.LastDeliveredAt, &device.InvalidatedAt,
+		&device.WebPushP256dh, &device.WebPushAuth,
+	}
+	err := row.Scan(append(dest, extra...)...)
+	return device, err
+}
+
+// RegisterDevice добавляет устройство пользователя или обновляет уже известный токен.
+// Токен, зарегистрированный другим пользователем (смена аккаунта на телефоне), переходит к новому.
+// Повторная регистрация снова включает устройство, отключенное после ответа APNs.
+func RegisterDevice(db *sql.DB, userID uuid.UUID, req models.RegisterDeviceRequest) (models.Device, error) {
+	token := strings.TrimSpace(req.Token)
+	if token == "" {
+		return models.Device{}, fmt.Errorf("%w: token is required", ErrInvalidDevice)
+	}
+
+	platform := strings.ToLower(strings.TrimSpace(req.Platform))
+	if platform == "" {
+		platform = models.DevicePlatformIOS
+	}
+	if platform != models.DevicePlatformIOS && platform != models.DevicePlatformAndroid {
+		return models.Device{}, fmt.Errorf("%w: unsupported platform %q", ErrInvalidDevice, req.Platform)
+	}
+
+	// Окружение есть только у токенов APNs
+	env := apns.Production
+	if platform == models.DevicePlatformIOS {
+		var err error
+		env, err = apns.ParseEnvironment(req.Environment)
+		if err != nil {
+			return models.Device{}, fmt.Errorf("%w: environment must be production or development", ErrInvalidDevice)
+		}
+	}
+
+	return scanDevice(db.QueryRow(
+		`INSERT INTO devices (user_id, token, platform, environment, app_version, locale)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (token) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			platform = EXCLUDED.platform,
+			environment = EXCLUDED.environment,
+			app_version = EXCLUDED.app_version,
+			locale = EXCLUDED.locale,
+			active = TRUE,
+			last_seen_at = NOW()
+		RETURNING `+deviceColumns,
+		userID, token, platform, string(env), req.AppVersion, req.Locale,
+	))
+}
+
+func ListDevices(db *sql.DB, userID uuid.UUID) ([]models.Device, error) {
+	rows, err := db.Query(
+		`SELECT `+deviceColumns+` FROM devices
+		WHERE user_id = $1
+		ORDER BY last_seen_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []models.Device{}
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+
+	return devices, rows.Err()
+}
+
+// activeDevices — устройства, на которые рассылаются пуши пользователя.
+func activeDevices(db *sql.DB, userID uuid.UUID) ([]models.Device, error) {
+	rows, err := db.Query(
+		`SELECT `+deviceColumns+` FROM devices
+		WHERE user_id = $1 AND active
+		ORDER BY last_seen_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []models.Device
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+
+	return devices, rows.Err()
+}
+
+// UnregisterDevice удаляет устройство, например при выходе из аккаунта.
+func UnregisterDevice(db *sql.DB, userID, deviceID uuid.UUID) error {
+	result, err := db.Exec("DELETE FROM devices WHERE id = $1 AND user_id = $2", deviceID, userID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+// recordDelivery сохраняет результат отправки пуша на устройство. Окружение, уточненное
+// транспортом (APNs fallback), запоминается, чтобы следующие пуши сразу шли на нужный хост.
+// Если транспорт счел токен недействительным, устройство отключается, если не было
+// зарегистрировано заново позже времени, которое сообщил сервис.
+func recordDelivery(db *sql.DB, device models.Device, sendErr error) {
+	var err error
+	if sendErr == nil {
+		_, err = db.Exec(
+			`UPDATE devices SET last_delivered_at = NOW(), environment = $3
+			WHERE id = $1 AND token = $2`,
+			device.ID, device.Token, device.Environment,
+		)
+	} else {
+		reason, invalid, invalidatedAt := deliveryFailure(sendErr)
+		if invalidatedAt.IsZero() {
+			invalidatedAt = time.Now()
+		}
+		if invalid && device.Platform == models.DevicePlatformWeb {
+			// Браузер выдаст новую подписку с другим endpoint, старую хранить незачем
+			fmt.Printf("🗑️ Removing web push subscription %s: push service answered %s\n", device.ID, reason)
+			if _, err := db.Exec("DELETE FROM devices WHERE id = $1 AND token = $2", device.ID, device.Token); err != nil {
+				fmt.Printf("Failed to remove web push subscription %s: %v\n", device.ID, err)
+			}
+			return
+		}
+		if invalid {
+			fmt.Printf("🗑️ Deactivating device %s: push service answered %s\n", device.ID, reason)
+		}
+
+		_, err = db.Exec(
+			`UPDATE devices SET
+				failure_count = failure_count + 1,
+				last_failure_reason = $3,
+				last_failure_at = NOW(),
+				active = CASE WHEN $4 AND last_seen_at < $5 THEN FALSE ELSE active END,
+				invalidated_at = CASE WHEN $4 AND last_seen_at < $5 THEN $5 ELSE invalidated_at END
+			WHERE id = $1 AND token = $2`,
+			device.ID, device.Token, reason, invalid, invalidatedAt,
+		)
+	}
+	if err != nil {
+		fmt.Printf("Failed to record delivery to device %s: %v\n", device.ID, err)
+	}
+}
+
+// ListAdminDevices — устройства с их состоянием доставки, самые проблемные сначала.
+func ListAdminDevices(db *sql.DB, filter models.AdminDeviceFilter) ([]models.AdminDevice, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultAdminDeviceLimit
+	}
+	if limit > maxAdminDeviceLimit {
+		limit = maxAdminDeviceLimit
+	}
+
+	var userID uuid.NullUUID
+	if filter.UserID != nil {
+		userID = uuid.NullUUID{UUID: *filter.UserID, Valid: true}
+	}
+
+	rows, err := db.Query(
+		`SELECT `+prefixColumns("d", deviceColumns)+`, d.user_id, u.username
+		FROM devices d
+		JOIN users u ON u.id = d.user_id
+		WHERE ($1::uuid IS NULL OR d.user_id = $1) AND (NOT $2 OR d.failure_count > 0)
+		ORDER BY d.failure_count DESC, d.last_failure_at DESC NULLS LAST, d.last_seen_at DESC
+		LIMIT $3`,
+		userID, filter.FailingOnly, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []models.AdminDevice{}
+	for rows.Next() {
+		var device models.AdminDevice
+		device.Device, err = scanDevice(rows, &device.UserID, &device.Username)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+
+	return devices, rows.Err()
+}
+
+// prefixColumns добавляет псевдоним таблицы к списку колонок вида "a, b, c".
+func prefixColumns(alias, columns string) string {
+	fields := strings.Split(columns, ",")
+	for i, field := range fields {
+		fields[i] = alias + "." + strings.TrimSpace(field)
+	}
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/pkg/apns"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPrefixColumns(t *testing.T) {
+	if got := prefixColumns("d", "id, token,\n\t\tactive"); got != "d.id, d.token, d.active" {
+		t.Errorf("prefixColumns = %q", got)
+	}
+}
+
+func TestRegisterDeviceValidation(t *testing.T) {
+	for _, req := range []models.RegisterDeviceRequest{
+		{Token: "  "},
+		{Token: "abc", Platform: "windows"},
+		{Token: "abc", Platform: models.DevicePlatformWeb},
+		{Token: "abc", Environment: "staging"},
+	} {
+		if _, err := RegisterDevice(nil, uuid.New(), req); !errors.Is(err, ErrInvalidDevice) {
+			t.Errorf("RegisterDevice(%+v): err = %v, want ErrInvalidDevice", req, err)
+		}
+	}
+}
+
+func TestRegisterDevice(t *testing.T) {
+	db := openTestDB(t)
+	userID := createTestUser(t, db, "UTC")
+	token := "test-token-" + uuid.NewString()
+
+	device, err := RegisterDevice(db, userID, models.RegisterDeviceRequest{Token: " " + token + " ", Environment: "sandbox"})
+	if err != nil {
+		t.Fatalf("RegisterDevice: %v", err)
+	}
+	if device.Token != token || device.Platform != models.DevicePlatformIOS || device.Environment != string(apns.Development) || !device.Active {
+		t.Errorf("device = %+v", device)
+	}
+
+	// Устройство, отключенное после ответа APNs, снова включается при регистрации
+	if _, err := db.Exec("UPDATE devices SET active = FALSE WHERE id = $1", device.ID); err != nil {
+		t.Fatal(err)
+	}
+	again, err := RegisterDevice(db, userID, models.RegisterDeviceRequest{Token: token})
+	if err != nil {
+		t.Fatalf("RegisterDevice: %v", err)
+	}
+	if again.ID != device.ID || !again.Active || again.Environment != string(apns.Production) {
+		t.Errorf("re-registered device = %+v, want the same active device", again)
+	}
+
+	// Токен переходит к пользователю, вошедшему на том же телефоне
+	otherID := createTestUser(t, db, "UTC")
+	moved, err := RegisterDevice(db, otherID, models.RegisterDeviceRequest{Token: token})
+	if err != nil {
+		t.Fatalf("RegisterDevice: %v", err)
+	}
+	if moved.ID != device.ID {
+		t.Errorf("moved device %s, want %s", moved.ID, device.ID)
+	}
+	if devices, err := ListDevices(db, userID); err != nil || len(devices) != 0 {
+		t.Errorf("previous owner devices = %+v, %v", devices, err)
+	}
+	if devices, err := ListDevices(db, otherID); err != nil || len(devices) != 1 {
+		t.Errorf("new owner devices = %+v, %v", devices, err)
+	}
+
+	if err := UnregisterDevice(db, userID, device.ID); err != ErrDeviceNotFound {
+		t.Errorf("unregister by previous owner: err = %v, want ErrDeviceNotFound", err)
+	}
+	if err := UnregisterDevice(db, otherID, device.ID); err != nil {
+		t.Errorf("UnregisterDevice: %v", err)
+	}
+	if err := UnregisterDevice(db, otherID, device.ID); err != ErrDeviceNotFound {
+		t.Errorf("second unregister: err = %v, want ErrDeviceNotFound", err)
+	}
+}
+
+// Устройство отключается, только если APNs считает токен недействительным с момента
+// после последней регистрации.
+func TestRecordDeliveryInvalidToken(t *testing.T) {
+	db := openTestDB(t)
+	userID := createTestUser(t, db, "UTC")
+
+	device, err := RegisterDevice(db, userID, models.RegisterDeviceRequest{Token: "test-token-" + uuid.NewString()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recordDelivery(db, device, &apns.Error{StatusCode: 410, Reason: "Unregistered", Timestamp: device.LastSeenAt.Add(-time.Hour)})
+	devices, err := ListAdminDevices(db, models.AdminDeviceFilter{UserID: &userID, FailingOnly: true})
+	if err != nil || len(devices) != 1 {
+		t.Fatalf("ListAdminDevices = %+v, %v", devices, err)
+	}
+	if d := devices[0].Device; !d.Active || d.FailureCount != 1 || d.LastFailureReason == nil || *d.LastFailureReason != "Unregistered" || d.InvalidatedAt != nil {
+		t.Errorf("after a stale Unregistered: %+v, want active", d)
+	}
+
+	invalidatedAt := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	recordDelivery(db, device, &apns.Error{StatusCode: 410, Reason: "Unregistered", Timestamp: invalidatedAt})
+	devices, err = ListAdminDevices(db, models.AdminDeviceFilter{UserID: &userID})
+	if err != nil || len(devices) != 1 {
+		t.Fatalf("ListAdminDevices = %+v, %v", devices, err)
+	}
+	if d := devices[0].Device; d.Active || d.FailureCount != 2 || d.InvalidatedAt == nil || !d.InvalidatedAt.Equal(invalidatedAt) {
+		t.Errorf("after Unregistered: %+v, want inactive since %s", d, invalidatedAt)
+	}
+
+	// Успешная доставка не сбрасывает статистику ошибок
+	again, err := RegisterDevice(db, userID, models.RegisterDeviceRequest{Token: device.Token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordDelivery(db, again, nil)
+	list, err := ListDevices(db, userID)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListDevices = %+v, %v", list, err)
+	}
+	if d := list[0]; !d.Active || d.LastDeliveredAt == nil || d.FailureCount != 2 {
+		t.Errorf("after re-registration and delivery: %+v", d)
+	}
+}
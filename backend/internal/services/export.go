@@ -0,0 +1,562 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/pkg/storage"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	exportPollInterval = 10 * time.Second
+	// Одна выгрузка на пользователя за этот период (неудачные не считаются).
+	exportRateLimit = 24 * time.Hour
+	// Сколько хранится готовый архив.
+	exportRetention = 7 * 24 * time.Hour
+	// Сколько действует подписанная ссылка на скачивание.
+	exportURLTTL = 15 * time.Minute
+	// Выгрузка, зависшая в processing дольше этого (например, после рестарта), запускается заново.
+	exportStaleAfter = 30 * time.Minute
+)
+
+var (
+	ErrExportNotFound    = errors.New("export not found")
+	ErrExportRateLimited = errors.New("export rate limited")
+	ErrInvalidSignature  = errors.New("invalid or expired signature")
+	// ErrExportsDisabled — не задан секрет для подписи ссылок. Подпись общеизвестным
+	// секретом по умолчанию позволила бы скачать чужую выгрузку.
+	ErrExportsDisabled = errors.New("export signing secret is not configured")
+)
+
+// exportSigningSecret возвращает EXPORT_SIGNING_SECRET или, если он не задан, JWT_SECRET.
+// Секрет JWT по умолчанию для подписи не используется.
+func exportSigningSecret() ([]byte, error) {
+	if secret := os.Getenv("EXPORT_SIGNING_SECRET"); secret != "" {
+		return []byte(secret), nil
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" && secret != defaultJWTSecret {
+		return []byte(secret), nil
+	}
+	return nil, ErrExportsDisabled
+}
+
+// ExportsEnabled сообщает, задан ли секрет для подписи ссылок на выгрузки.
+func ExportsEnabled() bool {
+	_, err := exportSigningSecret()
+	return err == nil
+}
+
+func exportSignature(exportID uuid.UUID, expires int64) (string, error) {
+	secret, err := exportSigningSecret()
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s:%d", exportID, expires)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// ExportDownloadURL возвращает подписанную ссылку на архив и время, до которого она действует.
+// Если задан PUBLIC_BASE_URL, ссылка абсолютная.
+func ExportDownloadURL(exportID uuid.UUID) (string, time.Time, error) {
+	expiresAt := time.Now().Add(exportURLTTL)
+	expires := expiresAt.Unix()
+	signature, err := exportSignature(exportID, expires)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	path := fmt.Sprintf("/exports/%s/download?expires=%d&signature=%s", exportID, expires, signature)
+	return strings.TrimRight(os.Getenv("PUBLIC_BASE_URL"), "/") + path, expiresAt, nil
+}
+
+// VerifyExportSignature проверяет подпись ссылки на скачивание и срок ее действия.
+func VerifyExportSignature(exportID uuid.UUID, expires, signature string) error {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return ErrInvalidSignature
+	}
+	want, err := exportSignature(exportID, expiresAt)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(signature), []byte(want)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+const exportColumns = "id, status, created_at, completed_at, expires_at"
+
+func scanExport(row interface{ Scan(...interface{}) error }) (models.DataExport, error) {
+	var export models.DataExport
+	err := row.Scan(&export.ID, &export.Status, &export.CreatedAt, &export.CompletedAt, &export.ExpiresAt)
+	return export, err
+}
+
+// withDownloadURL добавляет подписанную ссылку к готовой выгрузке.
+func withDownloadURL(export models.DataExport) (models.DataExport, error) {
+	if export.Status == models.DataExportStatusReady {
+		url, expiresAt, err := ExportDownloadURL(export.ID)
+		if err != nil {
+			return export, err
+		}
+		export.DownloadURL = url
+		export.DownloadURLExpiresAt = &expiresAt
+	}
+	return export, nil
+}
+
+// RequestDataExport ставит выгрузку данных пользователя в очередь. Если лимит исчерпан,
+// возвращает ErrExportRateLimited вместе с последней выгрузкой. Без секрета для подписи ссылок
+// выгрузку нельзя было бы скачать, поэтому она не создается (ErrExportsDisabled).
+func RequestDataExport(db *sql.DB, userID uuid.UUID) (models.DataExport, error) {
+	if !ExportsEnabled() {
+		return models.DataExport{}, ErrExportsDisabled
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.DataExport{}, err
+	}
+	defer tx.Rollback()
+
+	// Блокировка пользователя не дает двум параллельным запросам обойти лимит
+	if _, err := tx.Exec("SELECT 1 FROM users WHERE id = $1 FOR UPDATE", userID); err != nil {
+		return models.DataExport{}, err
+	}
+
+	latest, err := scanExport(tx.QueryRow(
+		`SELECT `+exportColumns+` FROM data_exports
+		WHERE user_id = $1 AND status <> 'failed' AND created_at > $2
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		userID, time.Now().Add(-exportRateLimit),
+	))
+	if err == nil {
+		latest, err = withDownloadURL(latest)
+		if err != nil {
+			return latest, err
+		}
+		return latest, ErrExportRateLimited
+	}
+	if err != sql.ErrNoRows {
+		return latest, err
+	}
+
+	export, err := scanExport(tx.QueryRow(
+		"INSERT INTO data_exports (user_id) VALUES ($1) RETURNING "+exportColumns,
+		userID,
+	))
+	if err != nil {
+		return export, err
+	}
+
+	return export, tx.Commit()
+}
+
+// ExportRetryAt — когда пользователь сможет запросить следующую выгрузку после export.
+func ExportRetryAt(export models.DataExport) time.Time {
+	return export.CreatedAt.Add(exportRateLimit)
+}
+
+func GetDataExport(db *sql.DB, userID, exportID uuid.UUID) (models.DataExport, error) {
+	export, err := scanExport(db.QueryRow(
+		"SELECT "+exportColumns+" FROM data_exports WHERE id = $1 AND user_id = $2",
+		exportID, userID,
+	))
+	if err == sql.ErrNoRows {
+		return export, ErrExportNotFound
+	}
+	if err != nil {
+		return export, err
+	}
+	return withDownloadURL(export)
+}
+
+// OpenDataExport открывает готовый архив для скачивания по подписанной ссылке.
+func OpenDataExport(db *sql.DB, store storage.Storage, exportID uuid.UUID) (io.ReadCloser, error) {
+	var key string
+	err := db.QueryRow(
+		"SELECT storage_key FROM data_exports WHERE id = $1 AND status = 'ready' AND expires_at > NOW()",
+		exportID,
+	).Scan(&key)
+	if err == sql.ErrNoRows {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := store.Open(key)
+	if err == storage.ErrNotFound {
+		return nil, ErrExportNotFound
+	}
+	return r, err
+}
+
+// ExportWorker собирает архивы из очереди data_exports и удаляет истекшие.
+type ExportWorker struct {
+	db    *sql.DB
+	store storage.Storage
+}
+
+func NewExportWorker(db *sql.DB, store storage.Storage) *ExportWorker {
+	return &ExportWorker{db: db, store: store}
+}
+
+func (w *ExportWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(exportPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			processed, err := w.processNext()
+			if err != nil {
+				fmt.Printf("Failed to process data export: %v\n", err)
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		if err := w.removeExpired(); err != nil {
+			fmt.Printf("Failed to remove expired data exports: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *ExportWorker) processNext() (bool, error) {
+	var exportID, userID uuid.UUID
+	err := w.db.QueryRow(
+		`UPDATE data_exports SET status = 'processing', started_at = NOW()
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = 'pending' OR (status = 'processing' AND started_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id`,
+		time.Now().Add(-exportStaleAfter),
+	).Scan(&exportID, &userID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	key := fmt.Sprintf("%s/%s.zip", userID, exportID)
+	if err := w.build(userID, key); err != nil {
+		fmt.Printf("Failed to build data export %s: %v\n", exportID, err)
+		_, updateErr := w.db.Exec(
+			"UPDATE data_exports SET status = 'failed', error = $2, completed_at = NOW() WHERE id = $1",
+			exportID, err.Error(),
+		)
+		return true, updateErr
+	}
+
+	_, err = w.db.Exec(
+		`UPDATE data_exports SET status = 'ready', storage_key = $2, completed_at = NOW(), expires_at = $3
+		WHERE id = $1`,
+		exportID, key, time.Now().Add(exportRetention),
+	)
+	if err != nil {
+		return true, err
+	}
+
+	SendDataExportReadyNotification(w.db, userID, exportID)
+	return true, nil
+}
+
+// build собирает архив во временный файл и сохраняет его в хранилище.
+func (w *ExportWorker) build(userID uuid.UUID, key string) error {
+	tmp, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := WriteDataExport(w.db, userID, tmp); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	return w.store.Put(key, tmp)
+}
+
+func (w *ExportWorker) removeExpired() error {
+	rows, err := w.db.Query("SELECT id, storage_key FROM data_exports WHERE status = 'ready' AND expires_at <= NOW()")
+	if err != nil {
+		return err
+	}
+
+	expired := make(map[uuid.UUID]string)
+	for rows.Next() {
+		var id uuid.UUID
+		var key string
+		if err := rows.Scan(&id, &key); err != nil {
+			rows.Close()
+			return err
+		}
+		expired[id] = key
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, key := range expired {
+		if err := w.store.Delete(key); err != nil {
+			fmt.Printf("Failed to delete data export %s: %v\n", id, err)
+			continue
+		}
+		if _, err := w.db.Exec("UPDATE data_exports SET status = 'expired' WHERE id = $1", id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteDataExport пишет ZIP с данными пользователя: JSON для всех разделов и CSV для табличных.
+func WriteDataExport(db *sql.DB, userID uuid.UUID, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	var user models.User
+	var hasPassword bool
+	err := db.QueryRow(
+		"SELECT id, email, apple_id, username, timezone, password_hash IS NOT NULL, created_at FROM users WHERE id = $1",
+		userID,
+	).Scan(&user.ID, &user.Email, &user.AppleID, &user.Username, &user.Timezone, &hasPassword, &user.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	profile := map[string]interface{}{
+		"id":         user.ID,
+		"email":      user.Email,
+		"username":   user.Username,
+		"timezone":   user.Timezone,
+		"created_at": user.CreatedAt,
+	}
+	if err := writeJSONFile(archive, "profile.json", profile); err != nil {
+		return err
+	}
+
+	identities := []map[string]interface{}{}
+	if user.Email != nil {
+		identities = append(identities, map[string]interface{}{"provider": "email", "identifier": *user.Email, "has_password": hasPassword})
+	}
+	if user.AppleID != nil {
+		identities = append(identities, map[string]interface{}{"provider": "apple", "identifier": *user.AppleID})
+	}
+	if err := writeJSONFile(archive, "identities.json", identities); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		name  string
+		query string
+	}{
+		{
+			name: "pairs",
+			query: `SELECT p.id, u.id AS partner_id, u.username AS partner_username, p.created_at, p.archived_at
+			FROM pairs p
+			JOIN users u ON u.id = CASE WHEN p.user1_id = $1 THEN p.user2_id ELSE p.user1_id END
+			WHERE p.user1_id = $1 OR p.user2_id = $1
+			ORDER BY p.created_at`,
+		},
+		{
+			name: "pair_requests",
+			query: `SELECT r.id, CASE WHEN r.requester_id = $1 THEN 'sent' ELSE 'received' END AS direction,
+				u.id AS other_user_id, u.username AS other_username, r.status, r.created_at, r.updated_at
+			FROM pair_requests r
+			JOIN users u ON u.id = CASE WHEN r.requester_id = $1 THEN r.requested_id ELSE r.requester_id END
+			WHERE r.requester_id = $1 OR r.requested_id = $1
+			ORDER BY r.created_at`,
+		},
+		{
+			name: "love_events",
+			query: `SELECT e.id, e.pair_id, CASE WHEN e.sender_id = $1 THEN 'sent' ELSE 'received' END AS direction,
+				e.sender_id, e.duration_seconds, e.event_type, e.created_at, e.delivered_at, e.seen_at
+			FROM love_events e
+			WHERE e.sender_id = $1
+				OR e.pair_id IN (SELECT id FROM pairs WHERE user1_id = $1 OR user2_id = $1)
+			ORDER BY e.created_at, e.id`,
+		},
+	}
+
+	for _, section := range sections {
+		columns, records, err := queryRecords(db, section.query, userID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", section.name, err)
+		}
+		if err := writeTableFiles(archive, section.name, columns, records); err != nil {
+			return err
+		}
+	}
+
+	stats, err := exportStats(db, userID)
+	if err != nil {
+		return err
+	}
+	if stats.Streak, err = UserStreak(db, userID); err != nil {
+		return err
+	}
+	if err := writeJSONFile(archive, "stats.json", stats); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// exportStats считает итоги по тем же событиям, что попадают в love_events архива:
+// отправленные пользователем и полученные им в любой из его пар.
+func exportStats(db *sql.DB, userID uuid.UUID) (models.ExportStats, error) {
+	var stats models.ExportStats
+
+	rows, err := db.Query(
+		`SELECT e.sender_id = $1, COUNT(*), COALESCE(SUM(e.duration_seconds), 0),
+			COALESCE(AVG(e.duration_seconds)::float8, 0), COALESCE(MAX(e.duration_seconds), 0),
+			MIN(e.created_at), MAX(e.created_at)
+		FROM love_events e
+		WHERE e.sender_id = $1
+			OR e.pair_id IN (SELECT id FROM pairs WHERE user1_id = $1 OR user2_id = $1)
+		GROUP BY 1`,
+		userID,
+	)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sent bool
+		var totals models.HeartTotals
+		err := rows.Scan(
+			&sent, &totals.TotalEvents, &totals.TotalDurationSeconds, &totals.AverageDurationSeconds,
+			&totals.LongestHoldSeconds, &totals.FirstEventAt, &totals.LastEventAt,
+		)
+		if err != nil {
+			return stats, err
+		}
+
+		if sent {
+			stats.Sent = totals
+		} else {
+			stats.Received = totals
+		}
+	}
+
+	return stats, rows.Err()
+}
+
+// queryRecords читает результат запроса как строки; NULL становится nil.
+func queryRecords(db *sql.DB, query string, args ...interface{}) ([]string, [][]interface{}, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var records [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, nil, err
+		}
+		for i, value := range values {
+			switch v := value.(type) {
+			case []byte:
+				values[i] = string(v)
+			case time.Time:
+				values[i] = v.UTC().Format(time.RFC3339)
+			}
+		}
+		records = append(records, values)
+	}
+
+	return columns, records, rows.Err()
+}
+
+func writeJSONFile(archive *zip.Writer, name string, value interface{}) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// writeTableFiles пишет раздел в <name>.json (массив объектов) и <name>.csv.
+func writeTableFiles(archive *zip.Writer, name string, columns []string, records [][]interface{}) error {
+	objects := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		object := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			object[column] = record[i]
+		}
+		objects = append(objects, object)
+	}
+	if err := writeJSONFile(archive, name+".json", objects); err != nil {
+		return err
+	}
+
+	f, err := archive.Create(name + ".csv")
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(f)
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := make([]string, len(record))
+		for i, value := range record {
+			if value != nil {
+				row[i] = fmt.Sprint(value)
+			}
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/pkg/storage"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestExportSigningSecret(t *testing.T) {
+	tests := []struct {
+		name         string
+		exportSecret string
+		jwtSecret    string
+		want         string
+		wantDisabled bool
+	}{
+		{"export secret", "export-secret", "jwt-secret", "export-secret", false},
+		{"jwt secret", "", "jwt-secret", "jwt-secret", false},
+		{"nothing set", "", "", "", true},
+		{"default jwt secret", "", defaultJWTSecret, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("EXPORT_SIGNING_SECRET", tt.exportSecret)
+			t.Setenv("JWT_SECRET", tt.jwtSecret)
+
+			secret, err := exportSigningSecret()
+			if tt.wantDisabled {
+				if err != ErrExportsDisabled {
+					t.Errorf("secret = %q, err = %v; want ErrExportsDisabled", secret, err)
+				}
+				if ExportsEnabled() {
+					t.Error("ExportsEnabled() = true")
+				}
+				return
+			}
+			if err != nil || string(secret) != tt.want {
+				t.Errorf("secret = %q, err = %v; want %q", secret, err, tt.want)
+			}
+		})
+	}
+}
+
+// Без настроенного секрета ссылки не подписываются и не принимаются, в том числе подписанные
+// секретом по умолчанию.
+func TestExportSignatureRequiresSecret(t *testing.T) {
+	t.Setenv("EXPORT_SIGNING_SECRET", "")
+	t.Setenv("JWT_SECRET", "")
+	exportID := uuid.New()
+
+	if _, _, err := ExportDownloadURL(exportID); err != ErrExportsDisabled {
+		t.Errorf("ExportDownloadURL: err = %v, want ErrExportsDisabled", err)
+	}
+
+	t.Setenv("JWT_SECRET", defaultJWTSecret)
+	expires := time.Now().Add(time.Minute).Unix()
+	mac := hmac.New(sha256.New, []byte(defaultJWTSecret))
+	fmt.Fprintf(mac, "%s:%d", exportID, expires)
+	forged := hex.EncodeToString(mac.Sum(nil))
+	if err := VerifyExportSignature(exportID, strconv.FormatInt(expires, 10), forged); err != ErrExportsDisabled {
+		t.Errorf("VerifyExportSignature: err = %v, want ErrExportsDisabled", err)
+	}
+}
+
+func TestExportDownloadURL(t *testing.T) {
+	t.Setenv("EXPORT_SIGNING_SECRET", "export-secret")
+	t.Setenv("PUBLIC_BASE_URL", "https://love.example.com/")
+	exportID := uuid.New()
+
+	link, expiresAt, err := ExportDownloadURL(exportID)
+	if err != nil {
+		t.Fatalf("ExportDownloadURL: %v", err)
+	}
+	if until := time.Until(expiresAt); until <= 0 || until > exportURLTTL {
+		t.Errorf("expires in %s, want within %s", until, exportURLTTL)
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "love.example.com" || u.Path != "/exports/"+exportID.String()+"/download" {
+		t.Errorf("link = %s", link)
+	}
+	expires, signature := u.Query().Get("expires"), u.Query().Get("signature")
+
+	if err := VerifyExportSignature(exportID, expires, signature); err != nil {
+		t.Errorf("VerifyExportSignature: %v", err)
+	}
+	past := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+	for _, tt := range []struct {
+		name               string
+		exportID           uuid.UUID
+		expires, signature string
+	}{
+		{"other export", uuid.New(), expires, signature},
+		{"later expiry", exportID, expires + "0", signature},
+		{"bad signature", exportID, expires, signature[1:] + "0"},
+		{"no expiry", exportID, "", signature},
+		{"expired", exportID, past, signature},
+	} {
+		if err := VerifyExportSignature(tt.exportID, tt.expires, tt.signature); err != ErrInvalidSignature {
+			t.Errorf("%s: err = %v, want ErrInvalidSignature", tt.name, err)
+		}
+	}
+
+	t.Setenv("EXPORT_SIGNING_SECRET", "other-secret")
+	if err := VerifyExportSignature(exportID, expires, signature); err != ErrInvalidSignature {
+		t.Errorf("other secret: err = %v, want ErrInvalidSignature", err)
+	}
+}
+
+// readZipFile возвращает содержимое файла name из ZIP-архива data.
+func readZipFile(t *testing.T, data []byte, name string) []byte {
+	t.Helper()
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := archive.Open(name)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestWriteTableFiles(t *testing.T) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	records := [][]interface{}{
+		{"a", int64(3), nil},
+		{"b, \"quoted\"", int64(0), true},
+	}
+	if err := writeTableFiles(archive, "events", []string{"id", "count", "seen"}, records); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTableFiles(archive, "empty", []string{"id"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(readZipFile(t, buf.Bytes(), "events.json"), &objects); err != nil {
+		t.Fatal(err)
+	}
+	wantObjects := []map[string]interface{}{
+		{"id": "a", "count": float64(3), "seen": nil},
+		{"id": "b, \"quoted\"", "count": float64(0), "seen": true},
+	}
+	if !reflect.DeepEqual(objects, wantObjects) {
+		t.Errorf("events.json = %v, want %v", objects, wantObjects)
+	}
+
+	// NULL в CSV — пустая ячейка
+	rows, err := csv.NewReader(bytes.NewReader(readZipFile(t, buf.Bytes(), "events.csv"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRows := [][]string{{"id", "count", "seen"}, {"a", "3", ""}, {"b, \"quoted\"", "0", "true"}}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("events.csv = %q, want %q", rows, wantRows)
+	}
+
+	// Пустой раздел — пустой массив, а не null
+	if empty := string(bytes.TrimSpace(readZipFile(t, buf.Bytes(), "empty.json"))); empty != "[]" {
+		t.Errorf("empty.json = %s, want []", empty)
+	}
+}
+
+func TestDataExportLifecycle(t *testing.T) {
+	db := openTestDB(t)
+	t.Setenv("EXPORT_SIGNING_SECRET", "export-secret")
+	pairID, userID, partnerID := createTestPair(t, db)
+	sent := insertTestLoveEvent(t, db, pairID, userID, 4, time.Now().Add(-time.Hour))
+	received := insertTestLoveEvent(t, db, pairID, partnerID, 9, time.Now().Add(-time.Minute))
+
+	export, err := RequestDataExport(db, userID)
+	if err != nil {
+		t.Fatalf("RequestDataExport: %v", err)
+	}
+	if export.Status != models.DataExportStatusPending || export.DownloadURL != "" {
+		t.Errorf("export = %+v, want pending without a link", export)
+	}
+	if latest, err := RequestDataExport(db, userID); err != ErrExportRateLimited || latest.ID != export.ID {
+		t.Errorf("second request = %s, %v; want ErrExportRateLimited with the first export", latest.ID, err)
+	}
+
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker := NewExportWorker(db, store)
+	for export.Status != models.DataExportStatusReady {
+		processed, err := worker.processNext()
+		if err != nil {
+			t.Fatalf("processNext: %v", err)
+		}
+		if !processed {
+			t.Fatalf("export = %+v, queue is empty", export)
+		}
+		if export, err = GetDataExport(db, userID, export.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if export.DownloadURL == "" || export.ExpiresAt == nil {
+		t.Errorf("ready export = %+v, want a download link", export)
+	}
+	if _, err := GetDataExport(db, partnerID, export.ID); err != ErrExportNotFound {
+		t.Errorf("partner GetDataExport: err = %v, want ErrExportNotFound", err)
+	}
+
+	r, err := OpenDataExport(db, store, export.ID)
+	if err != nil {
+		t.Fatalf("OpenDataExport: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var profile map[string]interface{}
+	if err := json.Unmarshal(readZipFile(t, data, "profile.json"), &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile["id"] != userID.String() {
+		t.Errorf("profile.json = %v", profile)
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(readZipFile(t, data, "love_events.csv"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[1][0] != sent.ID.String() || rows[1][2] != "sent" || rows[2][0] != received.ID.String() || rows[2][2] != "received" {
+		t.Errorf("love_events.csv = %q", rows)
+	}
+
+	// После истечения архив удаляется из хранилища
+	if _, err := db.Exec("UPDATE data_exports SET expires_at = NOW() WHERE id = $1", export.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := worker.removeExpired(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDataExport(db, store, export.ID); err != ErrExportNotFound {
+		t.Errorf("OpenDataExport after expiry: err = %v, want ErrExportNotFound", err)
+	}
+	if _, err := store.Open(fmt.Sprintf("%s/%s.zip", userID, export.ID)); err != storage.ErrNotFound {
+		t.Errorf("stored archive after expiry: err = %v, want storage.ErrNotFound", err)
+	}
+}
@@ -0,0 +1,431 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxActiveGoals     = 10
+	maxGoalTarget      = 1000000
+	defaultGoalPeriods = 12
+	maxGoalPeriods     = 104
+)
+
+var (
+	ErrGoalNotFound = errors.New("goal not found")
+	ErrInvalidGoal  = errors.New("invalid goal")
+	ErrTooManyGoals = errors.New("too many goals")
+)
+
+const goalColumns = "id, pair_id, metric, period, target, timezone, created_by, replaced_by, created_at, archived_at"
+
+func scanGoal(row interface{ Scan(...interface{}) error }) (models.PairGoal, error) {
+	var goal models.PairGoal
+	var createdBy, replacedBy uuid.NullUUID
+	err := row.Scan(
+		&goal.ID, &goal.PairID, &goal.Metric, &goal.Period, &goal.Target, &goal.Timezone,
+		&createdBy, &replacedBy, &goal.CreatedAt, &goal.ArchivedAt,
+	)
+	if createdBy.Valid {
+		goal.CreatedBy = &createdBy.UUID
+	}
+	if replacedBy.Valid {
+		goal.ReplacedBy = &replacedBy.UUID
+	}
+	return goal, err
+}
+
+func validateGoal(metric, period string, target int) error {
+	if metric != models.GoalMetricHearts && metric != models.GoalMetricHoldSeconds {
+		return fmt.Errorf("%w: metric must be 'hearts' or 'hold_seconds'", ErrInvalidGoal)
+	}
+	if period != models.StatsBucketDay && period != models.StatsBucketWeek {
+		return fmt.Errorf("%w: period must be 'day' or 'week'", ErrInvalidGoal)
+	}
+	if target < 1 || target > maxGoalTarget {
+		return fmt.Errorf("%w: target must be between 1 and %d", ErrInvalidGoal, maxGoalTarget)
+	}
+	return nil
+}
+
+func goalLocation(goal models.PairGoal) *time.Location {
+	loc, err := LoadTimezone(goal.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// goalPeriods считает прогресс цели по периодам, начиная с периода, в который попадает last,
+// и дальше в прошлое, но не раньше периода создания цели. Не больше limit периодов.
+func goalPeriods(db queryer, goal models.PairGoal, last time.Time, limit int) ([]models.GoalProgress, error) {
+	loc := goalLocation(goal)
+	first := TruncateToBucket(goal.CreatedAt.In(loc), goal.Period)
+
+	var starts []time.Time
+	for start := TruncateToBucket(last.In(loc), goal.Period); !start.Before(first) && len(starts) < limit; {
+		starts = append(starts, start)
+		if goal.Period == models.StatsBucketWeek {
+			start = start.AddDate(0, 0, -7)
+		} else {
+			start = start.AddDate(0, 0, -1)
+		}
+	}
+	if len(starts) == 0 {
+		return nil, nil
+	}
+
+	rows, err := db.Query(
+		`SELECT to_char(date_trunc($1, created_at AT TIME ZONE $2), 'YYYY-MM-DD'),
+			COUNT(*), COALESCE(SUM(duration_seconds), 0)
+		FROM love_events
+		WHERE pair_id = $3 AND created_at >= $4 AND created_at < $5
+		GROUP BY 1`,
+		goal.Period, loc.String(), goal.PairID, starts[len(starts)-1], nextBucket(starts[0], goal.Period),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make(map[string]int)
+	for rows.Next() {
+		var period string
+		var hearts, holdSeconds int
+		if err := rows.Scan(&period, &hearts, &holdSeconds); err != nil {
+			return nil, err
+		}
+		if goal.Metric == models.GoalMetricHoldSeconds {
+			values[period] = holdSeconds
+		} else {
+			values[period] = hearts
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	periods := make([]models.GoalProgress, 0, len(starts))
+	for _, start := range starts {
+		value := values[start.Format("2006-01-02")]
+		periods = append(periods, models.GoalProgress{
+			GoalID:      goal.ID,
+			PeriodStart: start,
+			PeriodEnd:   nextBucket(start, goal.Period),
+			Value:       value,
+			Target:      goal.Target,
+			Completed:   value >= goal.Target,
+		})
+	}
+
+	return periods, nil
+}
+
+// attachCurrentProgress добавляет к цели прогресс текущего периода.
+func attachCurrentProgress(db queryer, goal *models.PairGoal) error {
+	periods, err := goalPeriods(db, *goal, time.Now(), 1)
+	if err != nil {
+		return err
+	}
+	if len(periods) > 0 {
+		goal.Progress = &periods[0]
+	}
+	return nil
+}
+
+// ListPairGoals возвращает активные цели пары с прогрессом текущего периода.
+func ListPairGoals(db queryer, pairID uuid.UUID) ([]models.PairGoal, error) {
+	rows, err := db.Query(
+		"SELECT "+goalColumns+" FROM pair_goals WHERE pair_id = $1 AND archived_at IS NULL ORDER BY created_at",
+		pairID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	goals := []models.PairGoal{}
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range goals {
+		if err := attachCurrentProgress(db, &goals[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return goals, nil
+}
+
+// CreatePairGoal добавляет цель текущей паре пользователя. Периоды считаются в его часовом поясе.
+func CreatePairGoal(db *sql.DB, userID uuid.UUID, req models.CreateGoalRequest) (models.PairGoal, error) {
+	if err := validateGoal(req.Metric, req.Period, req.Target); err != nil {
+		return models.PairGoal{}, err
+	}
+
+	pairID, _, err := FindPartner(db, userID)
+	if err != nil {
+		return models.PairGoal{}, err
+	}
+
+	loc, err := UserLocation(db, userID)
+	if err != nil {
+		return models.PairGoal{}, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.PairGoal{}, err
+	}
+	defer tx.Rollback()
+
+	// Блокировка пары сериализует одновременные создания, иначе оба пройдут проверку лимита
+	var locked uuid.UUID
+	err = tx.QueryRow("SELECT id FROM pairs WHERE id = $1 AND archived_at IS NULL FOR UPDATE", pairID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return models.PairGoal{}, ErrNoPair
+	}
+	if err != nil {
+		return models.PairGoal{}, err
+	}
+
+	var active int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM pair_goals WHERE pair_id = $1 AND archived_at IS NULL", pairID).Scan(&active); err != nil {
+		return models.PairGoal{}, err
+	}
+	if active >= maxActiveGoals {
+		return models.PairGoal{}, ErrTooManyGoals
+	}
+
+	goal, err := scanGoal(tx.QueryRow(
+		`INSERT INTO pair_goals (pair_id, metric, period, target, timezone, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+goalColumns,
+		pairID, req.Metric, req.Period, req.Target, loc.String(), userID,
+	))
+	if err != nil {
+		return goal, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return goal, err
+	}
+
+	return goal, attachCurrentProgress(db, &goal)
+}
+
+// UpdatePairGoal заменяет активную цель новой версией. Старая архивируется и ссылается на новую,
+// чтобы прошлые периоды сохранили свою цель.
+func UpdatePairGoal(db *sql.DB, userID, goalID uuid.UUID, req models.UpdateGoalRequest) (models.PairGoal, error) {
+	pairID, _, err := FindPartner(db, userID)
+	if err != nil {
+		return models.PairGoal{}, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return models.PairGoal{}, err
+	}
+	defer tx.Rollback()
+
+	current, err := scanGoal(tx.QueryRow(
+		"SELECT "+goalColumns+" FROM pair_goals WHERE id = $1 AND pair_id = $2 AND archived_at IS NULL FOR UPDATE",
+		goalID, pairID,
+	))
+	if err == sql.ErrNoRows {
+		return models.PairGoal{}, ErrGoalNotFound
+	}
+	if err != nil {
+		return models.PairGoal{}, err
+	}
+
+	metric, period, target := current.Metric, current.Period, current.Target
+	if req.Metric != nil {
+		metric = *req.Metric
+	}
+	if req.Period != nil {
+		period = *req.Period
+	}
+	if req.Target != nil {
+		target = *req.Target
+	}
+	if err := validateGoal(metric, period, target); err != nil {
+		return models.PairGoal{}, err
+	}
+
+	goal, err := scanGoal(tx.QueryRow(
+		`INSERT INTO pair_goals (pair_id, metric, period, target, timezone, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+goalColumns,
+		pairID, metric, period, target, current.Timezone, userID,
+	))
+	if err != nil {
+		return goal, err
+	}
+
+	_, err = tx.Exec("UPDATE pair_goals SET archived_at = NOW(), replaced_by = $2 WHERE id = $1", current.ID, goal.ID)
+	if err != nil {
+		return goal, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return goal, err
+	}
+
+	return goal, attachCurrentProgress(db, &goal)
+}
+
+// DeletePairGoal архивирует цель; ее история остается доступной.
+func DeletePairGoal(db *sql.DB, userID, goalID uuid.UUID) error {
+	pairID, _, err := FindPartner(db, userID)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.Exec(
+		"UPDATE pair_goals SET archived_at = NOW() WHERE id = $1 AND pair_id = $2 AND archived_at IS NULL",
+		goalID, pairID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrGoalNotFound
+	}
+
+	return nil
+}
+
+// GetGoalHistory возвращает прогресс цели по прошлым периодам, от последнего к первому.
+// Периоды берутся из всех версий цели по цепочке replaced_by, так что после изменения цели
+// прошлые периоды остаются со своей целью. Доступны и архивные цели, в том числе цели архивных пар пользователя.
+func GetGoalHistory(db *sql.DB, userID, goalID uuid.UUID, limit int) (models.GoalHistory, error) {
+	var history models.GoalHistory
+
+	if limit <= 0 {
+		limit = defaultGoalPeriods
+	}
+	if limit > maxGoalPeriods {
+		limit = maxGoalPeriods
+	}
+
+	versions, err := goalVersions(db, userID, goalID)
+	if err != nil {
+		return history, err
+	}
+	if len(versions) == 0 {
+		return history, ErrGoalNotFound
+	}
+
+	periods := []models.GoalProgress{}
+	// cutoff — начало самого раннего периода более новой версии. Период, в котором цель изменили,
+	// принадлежит новой версии, поэтому пересекающиеся периоды старых версий пропускаются.
+	var cutoff time.Time
+	for _, version := range versions {
+		if len(periods) >= limit {
+			break
+		}
+
+		last := time.Now()
+		if version.ArchivedAt != nil {
+			last = *version.ArchivedAt
+		}
+
+		versionPeriods, err := goalPeriods(db, version, last, limit)
+		if err != nil {
+			return history, err
+		}
+
+		for _, period := range versionPeriods {
+			if !cutoff.IsZero() && period.PeriodEnd.After(cutoff) {
+				continue
+			}
+			if len(periods) >= limit {
+				break
+			}
+			periods = append(periods, period)
+		}
+		if n := len(versionPeriods); n > 0 && (cutoff.IsZero() || versionPeriods[n-1].PeriodStart.Before(cutoff)) {
+			cutoff = versionPeriods[n-1].PeriodStart
+		}
+	}
+
+	for _, version := range versions {
+		if version.ID == goalID {
+			history.Goal = version
+		}
+	}
+	history.Versions = versions
+	history.Periods = periods
+	return history, nil
+}
+
+// goalVersions возвращает все версии цели goalID по цепочке replaced_by (в обе стороны), от новой к старой.
+// Пусто, если цели нет или пользователь не состоит в ее паре.
+func goalVersions(db queryer, userID, goalID uuid.UUID) ([]models.PairGoal, error) {
+	rows, err := db.Query(
+		`WITH RECURSIVE chain AS (
+			SELECT g.id, g.pair_id, g.metric, g.period, g.target, g.timezone, g.created_by, g.replaced_by, g.created_at, g.archived_at
+			FROM pair_goals g
+			JOIN pairs p ON p.id = g.pair_id
+			WHERE g.id = $1 AND (p.user1_id = $2 OR p.user2_id = $2)
+			UNION
+			SELECT g.id, g.pair_id, g.metric, g.period, g.target, g.timezone, g.created_by, g.replaced_by, g.created_at, g.archived_at
+			FROM pair_goals g
+			JOIN chain c ON g.replaced_by = c.id OR g.id = c.replaced_by
+		)
+		SELECT `+goalColumns+` FROM chain ORDER BY created_at DESC`,
+		goalID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []models.PairGoal
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, goal)
+	}
+	return versions, rows.Err()
+}
+
+// BroadcastGoalProgress отправляет обоим партнерам goal_progress с актуальным прогрессом целей пары.
+func BroadcastGoalProgress(db *sql.DB, broadcaster Broadcaster, pairID uuid.UUID, userIDs ...uuid.UUID) {
+	goals, err := ListPairGoals(db, pairID)
+	if err != nil {
+		fmt.Printf("Failed to compute goal progress for pair %s: %v\n", pairID, err)
+		return
+	}
+	if len(goals) == 0 {
+		return
+	}
+
+	event := models.GoalProgressEvent{PairID: pairID, Goals: goals}
+	for _, userID := range userIDs {
+		broadcaster.SendToUser(userID, "goal_progress", event)
+	}
+}
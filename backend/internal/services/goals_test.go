@@ -0,0 +1,166 @@
+package services
+
+import (
+	"errors"
+	"love-connection/backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateGoal(t *testing.T) {
+	tests := []struct {
+		metric, period string
+		target         int
+		valid          bool
+	}{
+		{models.GoalMetricHearts, models.StatsBucketDay, 1, true},
+		{models.GoalMetricHoldSeconds, models.StatsBucketWeek, maxGoalTarget, true},
+		{"kisses", models.StatsBucketDay, 5, false},
+		{models.GoalMetricHearts, models.StatsBucketMonth, 5, false},
+		{models.GoalMetricHearts, "", 5, false},
+		{models.GoalMetricHearts, models.StatsBucketDay, 0, false},
+		{models.GoalMetricHearts, models.StatsBucketDay, -3, false},
+		{models.GoalMetricHearts, models.StatsBucketDay, maxGoalTarget + 1, false},
+	}
+
+	for _, tt := range tests {
+		err := validateGoal(tt.metric, tt.period, tt.target)
+		if tt.valid != (err == nil) || (err != nil && !errors.Is(err, ErrInvalidGoal)) {
+			t.Errorf("validateGoal(%q, %q, %d) = %v, want valid %v", tt.metric, tt.period, tt.target, err, tt.valid)
+		}
+	}
+}
+
+func TestGoalLocation(t *testing.T) {
+	if loc := goalLocation(models.PairGoal{Timezone: "Europe/Moscow"}); loc.String() != "Europe/Moscow" {
+		t.Errorf("goalLocation(Europe/Moscow) = %s", loc)
+	}
+	for _, timezone := range []string{"", "Local", "Mars/Olympus"} {
+		if loc := goalLocation(models.PairGoal{Timezone: timezone}); loc != time.UTC {
+			t.Errorf("goalLocation(%q) = %s, want UTC", timezone, loc)
+		}
+	}
+}
+
+func TestGoalPeriods(t *testing.T) {
+	db := openTestDB(t)
+	pairID, user1ID, user2ID := createTestPair(t, db)
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	goal, err := scanGoal(db.QueryRow(
+		`INSERT INTO pair_goals (pair_id, metric, period, target, timezone, created_by, created_at)
+		VALUES ($1, $2, $3, 2, 'UTC', $4, $5)
+		RETURNING `+goalColumns,
+		pairID, models.GoalMetricHearts, models.StatsBucketDay, user1ID, today.AddDate(0, 0, -3).Add(15*time.Hour),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// До создания цели — не считается; позавчера два сердечка от обоих, сегодня одно
+	insertTestLoveEvent(t, db, pairID, user1ID, 3, today.AddDate(0, 0, -4).Add(12*time.Hour))
+	insertTestLoveEvent(t, db, pairID, user1ID, 3, today.AddDate(0, 0, -2).Add(9*time.Hour))
+	insertTestLoveEvent(t, db, pairID, user2ID, 7, today.AddDate(0, 0, -2).Add(21*time.Hour))
+	insertTestLoveEvent(t, db, pairID, user2ID, 4, today.Add(time.Minute))
+
+	periods, err := goalPeriods(db, goal, time.Now(), 12)
+	if err != nil {
+		t.Fatalf("goalPeriods: %v", err)
+	}
+	want := []struct {
+		value     int
+		completed bool
+	}{{1, false}, {0, false}, {2, true}, {0, false}}
+	if len(periods) != len(want) {
+		t.Fatalf("got %d periods, want %d (since the goal was created)", len(periods), len(want))
+	}
+	for i, w := range want {
+		p := periods[i]
+		start := today.AddDate(0, 0, -i)
+		if !p.PeriodStart.Equal(start) || !p.PeriodEnd.Equal(start.AddDate(0, 0, 1)) || p.Value != w.value || p.Completed != w.completed {
+			t.Errorf("period %d = %+v, want %s with value %d", i, p, start, w.value)
+		}
+	}
+
+	if limited, err := goalPeriods(db, goal, time.Now(), 2); err != nil || len(limited) != 2 {
+		t.Errorf("goalPeriods with limit 2 = %d periods, %v", len(limited), err)
+	}
+
+	goal.Metric = models.GoalMetricHoldSeconds
+	periods, err = goalPeriods(db, goal, time.Now(), 12)
+	if err != nil {
+		t.Fatalf("goalPeriods: %v", err)
+	}
+	if periods[2].Value != 10 {
+		t.Errorf("hold seconds two days ago = %d, want 10", periods[2].Value)
+	}
+}
+
+func TestPairGoalVersions(t *testing.T) {
+	db := openTestDB(t)
+	_, user1ID, user2ID := createTestPair(t, db)
+
+	goal, err := CreatePairGoal(db, user1ID, models.CreateGoalRequest{Metric: models.GoalMetricHearts, Period: models.StatsBucketDay, Target: 3})
+	if err != nil {
+		t.Fatalf("CreatePairGoal: %v", err)
+	}
+	if goal.Progress == nil || goal.Progress.Value != 0 || goal.Timezone != "UTC" {
+		t.Errorf("goal = %+v, want empty progress in UTC", goal)
+	}
+
+	// Партнер меняет цель: старая версия архивируется и ссылается на новую
+	target := 5
+	updated, err := UpdatePairGoal(db, user2ID, goal.ID, models.UpdateGoalRequest{Target: &target})
+	if err != nil {
+		t.Fatalf("UpdatePairGoal: %v", err)
+	}
+	if updated.ID == goal.ID || updated.Target != 5 || updated.Metric != goal.Metric {
+		t.Errorf("updated = %+v", updated)
+	}
+	if _, err := UpdatePairGoal(db, user1ID, goal.ID, models.UpdateGoalRequest{Target: &target}); err != ErrGoalNotFound {
+		t.Errorf("update of an archived version: err = %v, want ErrGoalNotFound", err)
+	}
+
+	for _, id := range []uuid.UUID{goal.ID, updated.ID} {
+		history, err := GetGoalHistory(db, user1ID, id, 0)
+		if err != nil {
+			t.Fatalf("GetGoalHistory: %v", err)
+		}
+		if len(history.Versions) != 2 || history.Versions[0].ID != updated.ID || history.Goal.ID != id {
+			t.Errorf("history of %s: goal %s, versions %+v", id, history.Goal.ID, history.Versions)
+		}
+		// Сегодняшний период принадлежит новой версии
+		if len(history.Periods) != 1 || history.Periods[0].GoalID != updated.ID || history.Periods[0].Target != 5 {
+			t.Errorf("history of %s: periods %+v", id, history.Periods)
+		}
+	}
+
+	outsiderID := createTestUser(t, db, "UTC")
+	if _, err := GetGoalHistory(db, outsiderID, goal.ID, 0); err != ErrGoalNotFound {
+		t.Errorf("history for another user: err = %v, want ErrGoalNotFound", err)
+	}
+
+	if err := DeletePairGoal(db, user1ID, updated.ID); err != nil {
+		t.Fatalf("DeletePairGoal: %v", err)
+	}
+	if err := DeletePairGoal(db, user1ID, updated.ID); err != ErrGoalNotFound {
+		t.Errorf("second delete: err = %v, want ErrGoalNotFound", err)
+	}
+}
+
+func TestPairGoalLimit(t *testing.T) {
+	db := openTestDB(t)
+	_, userID, _ := createTestPair(t, db)
+	req := models.CreateGoalRequest{Metric: models.GoalMetricHearts, Period: models.StatsBucketWeek, Target: 10}
+
+	for i := 0; i < maxActiveGoals; i++ {
+		if _, err := CreatePairGoal(db, userID, req); err != nil {
+			t.Fatalf("goal %d: %v", i+1, err)
+		}
+	}
+	if _, err := CreatePairGoal(db, userID, req); err != ErrTooManyGoals {
+		t.Errorf("goal %d: err = %v, want ErrTooManyGoals", maxActiveGoals+1, err)
+	}
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultHistoryLimit = 50
+	MaxHistoryLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// historyCursor — позиция в ленте истории по ключу (created_at, id).
+// Клиенту она передается как непрозрачная base64-строка.
+type historyCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+}
+
+func encodeHistoryCursor(event models.LoveEvent) string {
+	data, _ := json.Marshal(historyCursor{CreatedAt: event.CreatedAt, ID: event.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeHistoryCursor(cursor string) (historyCursor, error) {
+	var c historyCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// LoveHistory возвращает страницу истории пары пользователя (новые события первыми)
+// и курсор следующей страницы, пустой на последней странице.
+func LoveHistory(db *sql.DB, userID uuid.UUID, q models.HistoryQuery) ([]models.LoveEvent, string, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	if limit > MaxHistoryLimit {
+		limit = MaxHistoryLimit
+	}
+
+	conditions := []string{
+		"(p.user1_id = $1 OR p.user2_id = $1)",
+		"p.archived_at IS NULL",
+		"NOT EXISTS (SELECT 1 FROM love_event_hidden h WHERE h.love_event_id = e.id AND h.user_id = $1)",
+	}
+	args := []interface{}{userID}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q.Cursor != "" {
+		cursor, err := decodeHistoryCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		conditions = append(conditions, fmt.Sprintf("(e.created_at, e.id) < (%s, %s)", arg(cursor.CreatedAt), arg(cursor.ID)))
+	}
+
+	switch q.Direction {
+	case models.HistoryDirectionSent:
+		conditions = append(conditions, "e.sender_id = $1")
+	case models.HistoryDirectionReceived:
+		conditions = append(conditions, "e.sender_id != $1")
+	}
+
+	if q.From != nil {
+		conditions = append(conditions, "e.created_at >= "+arg(*q.From))
+	}
+	if q.To != nil {
+		conditions = append(conditions, "e.created_at < "+arg(*q.To))
+	}
+	if q.MinDuration != nil {
+		conditions = append(conditions, "e.duration_seconds >= "+arg(*q.MinDuration))
+	}
+	if q.MaxDuration != nil {
+		conditions = append(conditions, "e.duration_seconds <= "+arg(*q.MaxDuration))
+	}
+	if q.EventType != "" {
+		conditions = append(conditions, "e.event_type = "+arg(q.EventType))
+	}
+
+	// Запрашиваем на одну строку больше, чтобы понять, есть ли следующая страница
+	query := `SELECT e.id, e.pair_id, e.sender_id, e.duration_seconds, e.event_type, e.delivered_at, e.seen_at, e.created_at,
+			u.id, u.email, u.apple_id, u.username, u.created_at
+		FROM love_events e
+		JOIN users u ON e.sender_id = u.id
+		JOIN pairs p ON e.pair_id = p.id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT ` + arg(limit+1)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	events := make([]models.LoveEvent, 0, limit)
+	for rows.Next() {
+		var event models.LoveEvent
+		var sender models.User
+		var pairID sql.NullString
+		err := rows.Scan(
+			&event.ID, &pairID, &event.SenderID, &event.DurationSeconds, &event.EventType, &event.DeliveredAt, &event.SeenAt, &event.CreatedAt,
+			&sender.ID, &sender.Email, &sender.AppleID, &sender.Username, &sender.CreatedAt,
+		)
+		if err != nil {
+			return nil, "", err
+		}
+		if pairID.Valid {
+			parsedID, err := uuid.Parse(pairID.String)
+			if err == nil {
+				event.PairID = &parsedID
+			}
+		}
+		event.Sender = &sender
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(events) > limit {
+		events = events[:limit]
+		nextCursor = encodeHistoryCursor(events[len(events)-1])
+	}
+
+	return events, nextCursor, nil
+}
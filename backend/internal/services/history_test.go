@@ -0,0 +1,59 @@
+package services
+
+import (
+	"encoding/base64"
+	"love-connection/backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHistoryCursorRoundTrip(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, createdAt := range []time.Time{
+		time.Date(2026, 2, 14, 12, 30, 15, 123456000, time.UTC),
+		time.Date(2026, 2, 14, 23, 59, 59, 999999000, moscow),
+		time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC),
+	} {
+		event := models.LoveEvent{ID: uuid.New(), CreatedAt: createdAt}
+
+		encoded := encodeHistoryCursor(event)
+		if _, err := base64.RawURLEncoding.DecodeString(encoded); err != nil {
+			t.Errorf("cursor %q is not URL-safe base64: %v", encoded, err)
+		}
+
+		cursor, err := decodeHistoryCursor(encoded)
+		if err != nil {
+			t.Fatalf("decodeHistoryCursor(%q): %v", encoded, err)
+		}
+		// Курсор сравнивается с created_at в базе, поэтому момент времени должен сохраниться до микросекунд
+		if cursor.ID != event.ID || !cursor.CreatedAt.Equal(createdAt) {
+			t.Errorf("decoded %+v, want %s at %s", cursor, event.ID, createdAt)
+		}
+	}
+}
+
+func TestDecodeHistoryCursorGarbage(t *testing.T) {
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	for name, cursor := range map[string]string{
+		"not base64":       "!!!",
+		"standard base64":  base64.StdEncoding.EncodeToString([]byte{0xfb, 0xff}),
+		"not json":         encode("hello"),
+		"json array":       encode(`[1, 2]`),
+		"empty object":     encode(`{}`),
+		"nil id":           encode(`{"t": "2026-02-14T12:00:00Z", "id": "00000000-0000-0000-0000-000000000000"}`),
+		"bad id":           encode(`{"t": "2026-02-14T12:00:00Z", "id": "42"}`),
+		"bad time":         encode(`{"t": "yesterday", "id": "` + uuid.NewString() + `"}`),
+		"truncated cursor": encodeHistoryCursor(models.LoveEvent{ID: uuid.New(), CreatedAt: time.Now()})[:20],
+	} {
+		if _, err := decodeHistoryCursor(cursor); err != ErrInvalidCursor {
+			t.Errorf("%s: decodeHistoryCursor(%q) err = %v, want ErrInvalidCursor", name, cursor, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/i18n"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var ErrUnsupportedLocale = errors.New("unsupported locale")
+
+// notificationCatalog — каталог текстов уведомлений. Ошибки LOCALES_DIR проверяются при старте сервера,
+// здесь на этот случай остаются встроенные локали.
+func notificationCatalog() *i18n.Catalog {
+	catalog, err := i18n.Default()
+	if err != nil {
+		return i18n.Embedded()
+	}
+	return catalog
+}
+
+// SupportedLocales — языки, на которых можно получать уведомления.
+func SupportedLocales() []string {
+	return notificationCatalog().Locales()
+}
+
+// SetUserLocale сохраняет язык уведомлений пользователя. Пустая строка сбрасывает выбор:
+// тогда используется язык последнего активного устройства.
+func SetUserLocale(db *sql.DB, userID uuid.UUID, locale string) (string, error) {
+	var value interface{}
+	if locale = strings.TrimSpace(locale); locale != "" {
+		matched, ok := notificationCatalog().Supported(locale)
+		if !ok {
+			return "", fmt.Errorf("%w %q, expected one of: %s", ErrUnsupportedLocale, locale, strings.Join(SupportedLocales(), ", "))
+		}
+		locale, value = matched, matched
+	}
+
+	if _, err := db.Exec("UPDATE users SET locale = $1 WHERE id = $2", value, userID); err != nil {
+		return "", err
+	}
+	return locale, nil
+}
+
+// userLocale выбирает язык уведомлений: users.locale, затем локаль последнего активного устройства,
+// затем язык по умолчанию.
+func userLocale(db *sql.DB, userID uuid.UUID) string {
+	var userLocale, deviceLocale sql.NullString
+	err := db.QueryRow(
+		`SELECT u.locale,
+			(SELECT d.locale FROM devices d
+			 WHERE d.user_id = u.id AND d.active AND d.locale IS NOT NULL AND d.locale <> ''
+			 ORDER BY d.last_seen_at DESC LIMIT 1)
+		FROM users u WHERE u.id = $1`,
+		userID,
+	).Scan(&userLocale, &deviceLocale)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Printf("Failed to get locale for user %s: %v\n", userID, err)
+	}
+
+	return notificationCatalog().Match(userLocale.String, deviceLocale.String)
+}
+
+// renderNotification готовит заголовок и текст пуша на языке пользователя.
+func renderNotification(db *sql.DB, userID uuid.UUID, key string, params map[string]interface{}) (title, body string, err error) {
+	catalog := notificationCatalog()
+	locale := userLocale(db, userID)
+
+	if title, err = catalog.Render(locale, "push.title", nil); err != nil {
+		return "", "", err
+	}
+	if body, err = catalog.Render(locale, key, params); err != nil {
+		return "", "", fmt.Errorf("locale %s: %w", locale, err)
+	}
+	return title, body, nil
+}
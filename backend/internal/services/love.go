@@ -0,0 +1,161 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoPair             = errors.New("no pair found")
+	ErrInvalidPairMembers = errors.New("invalid pair configuration")
+)
+
+// queryer — общее подмножество *sql.DB и *sql.Tx, чтобы запросы можно было выполнять в транзакции.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// FindPartner возвращает пару пользователя и ID его партнера.
+func FindPartner(db queryer, userID uuid.UUID) (pairID uuid.UUID, partnerID uuid.UUID, err error) {
+	var user1ID, user2ID uuid.UUID
+	err = db.QueryRow(
+		"SELECT id, user1_id, user2_id FROM pairs WHERE (user1_id = $1 OR user2_id = $1) AND archived_at IS NULL",
+		userID,
+	).Scan(&pairID, &user1ID, &user2ID)
+
+	if err == sql.ErrNoRows {
+		return uuid.Nil, uuid.Nil, ErrNoPair
+	}
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+
+	switch userID {
+	case user1ID:
+		partnerID = user2ID
+	case user2ID:
+		partnerID = user1ID
+	default:
+		return uuid.Nil, uuid.Nil, ErrNoPair
+	}
+
+	if partnerID == userID {
+		return uuid.Nil, uuid.Nil, ErrInvalidPairMembers
+	}
+
+	return pairID, partnerID, nil
+}
+
+// CreateLoveEvent сохраняет сердечко от senderID его партнеру и возвращает
+// событие вместе с ID получателя. Уведомления и broadcast остаются на вызывающей стороне.
+func CreateLoveEvent(db *sql.DB, senderID uuid.UUID, durationSeconds int, eventType string) (models.LoveEvent, uuid.UUID, error) {
+	var event models.LoveEvent
+
+	// БЕЗОПАСНОСТЬ: pairID и partnerID берутся только из пары, в которой состоит отправитель
+	pairID, partnerID, err := FindPartner(db, senderID)
+	if err != nil {
+		return event, uuid.Nil, err
+	}
+
+	if eventType == "" {
+		eventType = models.LoveEventTypeStandard
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return event, uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	eventID, err := insertLoveEvent(tx, pairID, senderID, partnerID, durationSeconds, eventType)
+	if err != nil {
+		return event, uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return event, uuid.Nil, err
+	}
+
+	event, err = GetLoveEvent(db, eventID)
+	if err != nil {
+		return event, uuid.Nil, err
+	}
+
+	return event, partnerID, nil
+}
+
+// DeliverLoveEvent доставляет созданное сердечко партнеру: будит outbox с пушем, websocket-сообщение love_event
+// (с отметкой доставки, если партнер онлайн), поиск синхронных сердечек, проверку серий и достижений, прогресс целей пары.
+func DeliverLoveEvent(db *sql.DB, broadcaster Broadcaster, event models.LoveEvent, partnerID uuid.UUID) {
+	// Пуш уже записан в outbox вместе с событием
+	WakeOutbox()
+
+	if broadcaster.SendToUser(partnerID, "love_event", event) {
+		if err := MarkDelivered(db, event.ID); err != nil {
+			fmt.Printf("Failed to mark love event %s as delivered: %v\n", event.ID, err)
+		}
+	}
+
+	go HandleSyncedHearts(db, broadcaster, event, partnerID)
+	go CheckStreakMilestones(db, event.SenderID)
+	go HandleAchievements(db, broadcaster, event, partnerID)
+	if event.PairID != nil {
+		go BroadcastGoalProgress(db, broadcaster, *event.PairID, event.SenderID, partnerID)
+	}
+}
+
+// insertLoveEvent вставляет событие, учитывает его в дневных агрегатах и ставит пуш партнеру в outbox.
+// tx должна быть транзакцией, чтобы событие, агрегаты и уведомление менялись атомарно.
+func insertLoveEvent(tx queryer, pairID, senderID, partnerID uuid.UUID, durationSeconds int, eventType string) (uuid.UUID, error) {
+	var eventID uuid.UUID
+	err := tx.QueryRow(
+		"INSERT INTO love_events (pair_id, sender_id, duration_seconds, event_type) VALUES ($1, $2, $3, $4) RETURNING id",
+		pairID, senderID, durationSeconds, eventType,
+	).Scan(&eventID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := applyRollup(tx, eventID); err != nil {
+		return uuid.Nil, err
+	}
+
+	return eventID, EnqueueLoveEventNotification(tx, partnerID, eventID)
+}
+
+// GetLoveEvent загружает событие вместе с отправителем.
+func GetLoveEvent(db queryer, eventID uuid.UUID) (models.LoveEvent, error) {
+	var event models.LoveEvent
+	var sender models.User
+	var pairID sql.NullString
+	err := db.QueryRow(
+		`SELECT e.id, e.pair_id, e.sender_id, e.duration_seconds, e.event_type, e.delivered_at, e.seen_at, e.created_at,
+			u.id, u.email, u.apple_id, u.username, u.created_at
+		FROM love_events e
+		JOIN users u ON e.sender_id = u.id
+		WHERE e.id = $1`,
+		eventID,
+	).Scan(
+		&event.ID, &pairID, &event.SenderID, &event.DurationSeconds, &event.EventType, &event.DeliveredAt, &event.SeenAt, &event.CreatedAt,
+		&sender.ID, &sender.Email, &sender.AppleID, &sender.Username, &sender.CreatedAt,
+	)
+	if err != nil {
+		return event, err
+	}
+
+	if pairID.Valid {
+		parsedID, err := uuid.Parse(pairID.String)
+		if err == nil {
+			event.PairID = &parsedID
+		}
+	}
+	event.Sender = &sender
+
+	return event, nil
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	memoryPollInterval = time.Minute
+	// Сердечки не короче этого попадают в воспоминания как долгие.
+	notableHoldSeconds  = 60
+	maxLongHoldMemories = 5
+)
+
+// memoryDays возвращает даты MM-DD, которые вспоминаются в день today.
+// 29 февраля в невисокосный год вспоминается 28 февраля.
+func memoryDays(today time.Time) []string {
+	days := []string{today.Format("01-02")}
+	if today.Month() == time.February && today.Day() == 28 {
+		if time.Date(today.Year(), time.February, 29, 0, 0, 0, 0, time.UTC).Month() != time.February {
+			days = append(days, "02-29")
+		}
+	}
+	return days
+}
+
+// MemoriesForDay возвращает события прошлых лет, случившиеся в тот же локальный день, что и now:
+// дату создания пары, первое сердечко пары и самые долгие сердечки.
+func MemoriesForDay(db queryer, userID uuid.UUID, loc *time.Location, now time.Time, includeArchived bool) (models.MemoriesToday, error) {
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+
+	result := models.MemoriesToday{
+		Date:     today.Format("2006-01-02"),
+		Timezone: loc.String(),
+		Memories: []models.Memory{},
+	}
+
+	rows, err := db.Query(
+		`WITH user_pairs AS (
+			SELECT p.id, p.created_at,
+				CASE WHEN p.user1_id = $1 THEN p.user2_id ELSE p.user1_id END AS partner_id
+			FROM pairs p
+			WHERE (p.user1_id = $1 OR p.user2_id = $1) AND (p.archived_at IS NULL OR $3)
+		),
+		visible AS (
+			SELECT e.id, e.pair_id, e.sender_id, e.duration_seconds, e.created_at
+			FROM love_events e
+			JOIN user_pairs up ON up.id = e.pair_id
+			WHERE NOT EXISTS (SELECT 1 FROM love_event_hidden h WHERE h.love_event_id = e.id AND h.user_id = $1)
+		),
+		first_hearts AS (
+			SELECT DISTINCT ON (pair_id) * FROM visible ORDER BY pair_id, created_at, id
+		),
+		memories AS (
+			SELECT 'paired' AS kind, up.id AS pair_id, NULL::uuid AS event_id, NULL::uuid AS sender_id,
+				0 AS duration_seconds, up.created_at AS occurred_at
+			FROM user_pairs up
+			UNION ALL
+			SELECT 'first_heart', f.pair_id, f.id, f.sender_id, f.duration_seconds, f.created_at
+			FROM first_hearts f
+			UNION ALL
+			(
+				SELECT 'long_hold', v.pair_id, v.id, v.sender_id, v.duration_seconds, v.created_at
+				FROM visible v
+				WHERE v.duration_seconds >= $6
+					-- Первое сердечко может быть и долгим; показываем его один раз, как первое
+					AND v.id NOT IN (SELECT id FROM first_hearts)
+					AND to_char(v.created_at AT TIME ZONE $2, 'MM-DD') = ANY($4)
+					AND (v.created_at AT TIME ZONE $2)::date < $5::date
+				ORDER BY v.duration_seconds DESC, v.created_at
+				LIMIT $7
+			)
+		)
+		SELECT m.kind, m.pair_id, u.username, m.event_id, m.sender_id, m.duration_seconds, m.occurred_at,
+			to_char(m.occurred_at AT TIME ZONE $2, 'YYYY-MM-DD')
+		FROM memories m
+		JOIN user_pairs up ON up.id = m.pair_id
+		JOIN users u ON u.id = up.partner_id
+		WHERE to_char(m.occurred_at AT TIME ZONE $2, 'MM-DD') = ANY($4)
+			AND (m.occurred_at AT TIME ZONE $2)::date < $5::date
+		ORDER BY m.occurred_at, m.kind, m.event_id`,
+		userID, loc.String(), includeArchived, pq.Array(memoryDays(today)), result.Date, notableHoldSeconds, maxLongHoldMemories,
+	)
+	if err != nil {
+		return result, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var memory models.Memory
+		var eventID, senderID uuid.NullUUID
+		err := rows.Scan(
+			&memory.Kind, &memory.PairID, &memory.PartnerUsername, &eventID, &senderID,
+			&memory.DurationSeconds, &memory.OccurredAt, &memory.Date,
+		)
+		if err != nil {
+			return result, err
+		}
+
+		if eventID.Valid {
+			memory.LoveEventID = &eventID.UUID
+		}
+		if senderID.Valid {
+			memory.SenderID = &senderID.UUID
+		}
+		memory.YearsAgo = local.Year() - memory.OccurredAt.In(loc).Year()
+
+		result.Memories = append(result.Memories, memory)
+	}
+
+	return result, rows.Err()
+}
+
+// MemoriesToday — воспоминания пользователя на сегодня в его часовом поясе с учетом настроек.
+func MemoriesToday(db *sql.DB, userID uuid.UUID) (models.MemoriesToday, error) {
+	loc, err := UserLocation(db, userID)
+	if err != nil {
+		return models.MemoriesToday{}, err
+	}
+
+	settings, err := GetMemorySettings(db, userID)
+	if err != nil {
+		return models.MemoriesToday{}, err
+	}
+
+	return MemoriesForDay(db, userID, loc, time.Now(), settings.IncludeArchivedPairs)
+}
+
+func GetMemorySettings(db *sql.DB, userID uuid.UUID) (models.MemorySettings, error) {
+	settings := models.MemorySettings{PushAt: "09:00", IncludeArchivedPairs: true}
+
+	err := db.QueryRow(
+		`SELECT push_enabled, to_char(push_at, 'HH24:MI'), include_archived_pairs
+		FROM memory_settings
+		WHERE user_id = $1`,
+		userID,
+	).Scan(&settings.PushEnabled, &settings.PushAt, &settings.IncludeArchivedPairs)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+
+	return settings, err
+}
+
+// UpdateMemorySettings применяет переданные поля настроек воспоминаний.
+func UpdateMemorySettings(db *sql.DB, userID uuid.UUID, req models.UpdateMemorySettingsRequest) (models.MemorySettings, error) {
+	current, err := GetMemorySettings(db, userID)
+	if err != nil {
+		return current, err
+	}
+
+	if req.PushEnabled != nil {
+		current.PushEnabled = *req.PushEnabled
+	}
+	if req.IncludeArchivedPairs != nil {
+		current.IncludeArchivedPairs = *req.IncludeArchivedPairs
+	}
+	if req.PushAt != nil {
+		pushAt, err := time.Parse("15:04", *req.PushAt)
+		if err != nil {
+			return current, ErrInvalidTime
+		}
+		current.PushAt = pushAt.Format("15:04")
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO memory_settings (user_id, push_enabled, push_at, include_archived_pairs)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			push_enabled = EXCLUDED.push_enabled,
+			push_at = EXCLUDED.push_at,
+			include_archived_pairs = EXCLUDED.include_archived_pairs,
+			updated_at = NOW()`,
+		userID, current.PushEnabled, current.PushAt, current.IncludeArchivedPairs,
+	)
+
+	return current, err
+}
+
+// MemoryScheduler утром по локальному времени присылает пуш, если на сегодня есть воспоминания.
+// Как и у напоминаний, день сначала занимается в memory_settings.last_sent_on.
+type MemoryScheduler struct {
+	db *sql.DB
+}
+
+func NewMemoryScheduler(db *sql.DB) *MemoryScheduler {
+	return &MemoryScheduler{db: db}
+}
+
+func (s *MemoryScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(memoryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sendDue(ctx); err != nil {
+			fmt.Printf("Failed to send memories: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type dueMemoryPush struct {
+	userID          uuid.UUID
+	timezone        string
+	localDay        time.Time
+	includeArchived bool
+}
+
+func (s *MemoryScheduler) sendDue(ctx context.Context) error {
+	rows, err := s.db.Query(
+		`SELECT m.user_id, tz.name, (NOW() AT TIME ZONE tz.name)::date, m.include_archived_pairs
+		FROM memory_settings m
+		JOIN users u ON u.id = m.user_id
+		JOIN pg_timezone_names tz ON tz.name = u.timezone
+		WHERE m.push_enabled
+			AND (NOW() AT TIME ZONE tz.name)::time >= m.push_at
+			AND (m.last_sent_on IS NULL OR m.last_sent_on < (NOW() AT TIME ZONE tz.name)::date)`,
+	)
+	if err != nil {
+		return err
+	}
+
+	var due []dueMemoryPush
+	for rows.Next() {
+		var p dueMemoryPush
+		if err := rows.Scan(&p.userID, &p.timezone, &p.localDay, &p.includeArchived); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range due {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := s.push(p); err != nil {
+			fmt.Printf("Failed to send memories to user %s: %v\n", p.userID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *MemoryScheduler) push(p dueMemoryPush) error {
+	localDay := p.localDay.Format("2006-01-02")
+	result, err := s.db.Exec(
+		`UPDATE memory_settings SET last_sent_on = $2
+		WHERE user_id = $1 AND (last_sent_on IS NULL OR last_sent_on < $2)`,
+		p.userID, localDay,
+	)
+	if err != nil {
+		return err
+	}
+	if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+		return err
+	}
+
+	loc, err := LoadTimezone(p.timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	memories, err := MemoriesForDay(s.db, p.userID, loc, time.Now(), p.includeArchived)
+	if err != nil {
+		return err
+	}
+	if len(memories.Memories) == 0 {
+		return nil
+	}
+
+	SendMemoryNotification(s.db, p.userID, pickMemory(memories.Memories))
+	return nil
+}
+
+// pickMemory выбирает воспоминание для пуша: годовщина пары, затем первое сердечко, затем самое долгое.
+func pickMemory(memories []models.Memory) models.Memory {
+	best := memories[0]
+	rank := func(m models.Memory) int {
+		switch m.Kind {
+		case models.MemoryKindPaired:
+			return 0
+		case models.MemoryKindFirstHeart:
+			return 1
+		}
+		return 2
+	}
+
+	for _, m := range memories[1:] {
+		if rank(m) < rank(best) || (rank(m) == rank(best) && m.DurationSeconds > best.DurationSeconds) {
+			best = m
+		}
+	}
+	return best
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"love-connection/backend/internal/models"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryDays(t *testing.T) {
+	tests := []struct {
+		today time.Time
+		want  []string
+	}{
+		{time.Date(2026, time.June, 15, 0, 0, 0, 0, time.UTC), []string{"06-15"}},
+		// В невисокосный год 29 февраля вспоминается 28-го
+		{time.Date(2027, time.February, 28, 0, 0, 0, 0, time.UTC), []string{"02-28", "02-29"}},
+		{time.Date(2028, time.February, 28, 0, 0, 0, 0, time.UTC), []string{"02-28"}},
+		{time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC), []string{"02-29"}},
+		{time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC), []string{"03-01"}},
+	}
+
+	for _, tt := range tests {
+		if got := memoryDays(tt.today); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("memoryDays(%s) = %v, want %v", tt.today.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestPickMemory(t *testing.T) {
+	paired := models.Memory{Kind: models.MemoryKindPaired}
+	first := models.Memory{Kind: models.MemoryKindFirstHeart, DurationSeconds: 5}
+	short := models.Memory{Kind: models.MemoryKindLongHold, DurationSeconds: 60}
+	long := models.Memory{Kind: models.MemoryKindLongHold, DurationSeconds: 300}
+
+	tests := []struct {
+		memories []models.Memory
+		want     models.Memory
+	}{
+		{[]models.Memory{short}, short},
+		{[]models.Memory{short, long}, long},
+		{[]models.Memory{long, short}, long},
+		{[]models.Memory{long, first}, first},
+		{[]models.Memory{first, long, paired}, paired},
+	}
+
+	for i, tt := range tests {
+		if got := pickMemory(tt.memories); got != tt.want {
+			t.Errorf("case %d: pickMemory = %+v, want %+v", i, got, tt.want)
+		}
+	}
+}
+
+func TestMemoriesForDay(t *testing.T) {
+	db := openTestDB(t)
+	pairID, user1ID, user2ID := createTestPair(t, db)
+
+	if _, err := db.Exec("UPDATE pairs SET created_at = $2 WHERE id = $1", pairID, time.Date(2024, time.June, 15, 8, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	// Первое сердечко долгое, но вспоминается один раз — как первое
+	first := insertTestLoveEvent(t, db, pairID, user1ID, 90, time.Date(2024, time.June, 15, 8, 30, 0, 0, time.UTC))
+	longHold := insertTestLoveEvent(t, db, pairID, user2ID, 120, time.Date(2025, time.June, 15, 20, 0, 0, 0, time.UTC))
+	insertTestLoveEvent(t, db, pairID, user1ID, 10, time.Date(2025, time.June, 15, 21, 0, 0, 0, time.UTC))
+	insertTestLoveEvent(t, db, pairID, user1ID, 300, time.Date(2025, time.June, 16, 12, 0, 0, 0, time.UTC))
+	insertTestLoveEvent(t, db, pairID, user1ID, 200, time.Date(2026, time.June, 15, 7, 0, 0, 0, time.UTC))
+
+	now := time.Date(2026, time.June, 15, 9, 0, 0, 0, time.UTC)
+	memories, err := MemoriesForDay(db, user1ID, time.UTC, now, true)
+	if err != nil {
+		t.Fatalf("MemoriesForDay: %v", err)
+	}
+	if memories.Date != "2026-06-15" || len(memories.Memories) != 3 {
+		t.Fatalf("memories = %+v, want 3 for 2026-06-15", memories)
+	}
+	want := []struct {
+		kind     string
+		yearsAgo int
+		eventID  uuid.UUID
+	}{
+		{models.MemoryKindPaired, 2, uuid.Nil},
+		{models.MemoryKindFirstHeart, 2, first.ID},
+		{models.MemoryKindLongHold, 1, longHold.ID},
+	}
+	for i, w := range want {
+		m := memories.Memories[i]
+		if m.Kind != w.kind || m.YearsAgo != w.yearsAgo || m.PairID != pairID {
+			t.Errorf("memory %d = %+v, want %s %d years ago", i, m, w.kind, w.yearsAgo)
+		}
+		if (m.LoveEventID == nil) != (w.eventID == uuid.Nil) || (m.LoveEventID != nil && *m.LoveEventID != w.eventID) {
+			t.Errorf("memory %d event = %v, want %v", i, m.LoveEventID, w.eventID)
+		}
+	}
+
+	// Скрытое сердечко не вспоминается тому, кто его скрыл
+	if _, err := db.Exec("INSERT INTO love_event_hidden (love_event_id, user_id) VALUES ($1, $2)", longHold.ID, user1ID); err != nil {
+		t.Fatal(err)
+	}
+	if memories, err := MemoriesForDay(db, user1ID, time.UTC, now, true); err != nil || len(memories.Memories) != 2 {
+		t.Errorf("after hiding: %+v, %v", memories.Memories, err)
+	}
+	if memories, err := MemoriesForDay(db, user2ID, time.UTC, now, true); err != nil || len(memories.Memories) != 3 {
+		t.Errorf("partner after hiding: %+v, %v", memories.Memories, err)
+	}
+
+	if _, err := db.Exec("UPDATE pairs SET archived_at = NOW() WHERE id = $1", pairID); err != nil {
+		t.Fatal(err)
+	}
+	if memories, err := MemoriesForDay(db, user2ID, time.UTC, now, false); err != nil || len(memories.Memories) != 0 {
+		t.Errorf("archived pair excluded: %+v, %v", memories.Memories, err)
+	}
+	if memories, err := MemoriesForDay(db, user2ID, time.UTC, now, true); err != nil || len(memories.Memories) != 3 {
+		t.Errorf("archived pair included: %+v, %v", memories.Memories, err)
+	}
+}
+
+func TestUpdateMemorySettings(t *testing.T) {
+	db := openTestDB(t)
+	userID := createTestUser(t, db, "UTC")
+
+	settings, err := GetMemorySettings(db, userID)
+	if err != nil || settings.PushEnabled || settings.PushAt != "09:00" || !settings.IncludeArchivedPairs {
+		t.Fatalf("defaults = %+v, %v", settings, err)
+	}
+
+	enabled, pushAt := true, "25:00"
+	if _, err := UpdateMemorySettings(db, userID, models.UpdateMemorySettingsRequest{PushEnabled: &enabled, PushAt: &pushAt}); err != ErrInvalidTime {
+		t.Errorf("push_at %q: err = %v, want ErrInvalidTime", pushAt, err)
+	}
+
+	pushAt = "07:30"
+	if _, err := UpdateMemorySettings(db, userID, models.UpdateMemorySettingsRequest{PushEnabled: &enabled, PushAt: &pushAt}); err != nil {
+		t.Fatalf("UpdateMemorySettings: %v", err)
+	}
+	settings, err = GetMemorySettings(db, userID)
+	if err != nil || !settings.PushEnabled || settings.PushAt != "07:30" || !settings.IncludeArchivedPairs {
+		t.Errorf("saved = %+v, %v", settings, err)
+	}
+}
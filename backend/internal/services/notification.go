@@ -3,120 +3,183 @@ package services
 import (
 	"database/sql"
 	"fmt"
-	"love-connection/backend/pkg/apns"
-	"os"
-	"sync"
+	"love-connection/backend/internal/models"
 
 	"github.com/google/uuid"
 )
 
-var apnsClient *apns.Client
-var apnsClientOnce sync.Once
-
-func SendNotification(db *sql.DB, userID uuid.UUID, senderUsername string, durationSeconds int) {
-	var deviceToken sql.NullString
-	err := db.QueryRow("SELECT device_token FROM users WHERE id = $1", userID).Scan(&deviceToken)
-	if err != nil {
-		fmt.Printf("Failed to get device token for user %s: %v\n", userID, err)
-		return
+// SendNotification отправляет пуш о сердечке. Вызывается из OutboxWorker, ошибка означает, что пуш нужно повторить.
+func SendNotification(db *sql.DB, userID uuid.UUID, eventID uuid.UUID, senderUsername string, durationSeconds int) error {
+	params := map[string]interface{}{"Sender": senderUsername, "Duration": durationSeconds}
+	data := map[string]interface{}{"love_event_id": eventID.String()}
+	if err := deliverLocalizedPush(db, userID, "love_event", params, data); err != nil {
+		return err
 	}
-	if !deviceToken.Valid || deviceToken.String == "" {
-		fmt.Printf("No device token found for user %s\n", userID)
-		return
+
+	if err := MarkDelivered(db, eventID); err != nil {
+		fmt.Printf("Failed to mark love event %s as delivered: %v\n", eventID, err)
 	}
+	return nil
+}
 
-	formattedDuration := formatDuration(durationSeconds)
-	title := "Love Connection"
-	body := fmt.Sprintf("Пользователь %s отправил сердечко! <3\n%s", senderUsername, formattedDuration)
+func SendPairRequestNotification(db *sql.DB, userID uuid.UUID, requesterUsername string) error {
+	params := map[string]interface{}{"Requester": requesterUsername}
+	return deliverLocalizedPush(db, userID, "pair_request", params, nil)
+}
 
-	apnsKeyPath := os.Getenv("APNS_KEY_PATH")
-	apnsKeyID := os.Getenv("APNS_KEY_ID")
-	apnsTeamID := os.Getenv("APNS_TEAM_ID")
-	apnsBundleID := os.Getenv("APNS_BUNDLE_ID")
+func SendSyncedHeartNotification(db *sql.DB, userID uuid.UUID, partnerUsername string, overlapSeconds int) {
+	params := map[string]interface{}{"Partner": partnerUsername, "Duration": overlapSeconds}
+	sendPush(db, userID, "synced_heart", params, nil)
+}
+
+func SendReactionNotification(db *sql.DB, userID uuid.UUID, reactorUsername string, emoji string) {
+	params := map[string]interface{}{"Reactor": reactorUsername, "Emoji": emoji}
+	sendPush(db, userID, "reaction", params, nil)
+}
 
-	if apnsKeyPath == "" || apnsKeyID == "" || apnsTeamID == "" || apnsBundleID == "" {
-		fmt.Printf("⚠️ APNs not configured! Missing env vars:\n")
-		fmt.Printf("   APNS_KEY_PATH: %s\n", ifEmpty(apnsKeyPath, "NOT SET"))
-		fmt.Printf("   APNS_KEY_ID: %s\n", ifEmpty(apnsKeyID, "NOT SET"))
-		fmt.Printf("   APNS_TEAM_ID: %s\n", ifEmpty(apnsTeamID, "NOT SET"))
-		fmt.Printf("   APNS_BUNDLE_ID: %s\n", ifEmpty(apnsBundleID, "NOT SET"))
-		fmt.Printf("   Would send notification: %s\n", body)
+func SendReminderNotification(db *sql.DB, userID uuid.UUID, partnerUsername string) {
+	params := map[string]interface{}{"Partner": partnerUsername}
+	sendPush(db, userID, "reminder", params, nil)
+}
+
+// SendStreakMilestoneNotification поздравляет с круглой серией. partnerUsername пустой для личной серии.
+func SendStreakMilestoneNotification(db *sql.DB, userID uuid.UUID, days int, partnerUsername string) {
+	if partnerUsername != "" {
+		params := map[string]interface{}{"Partner": partnerUsername, "Days": days}
+		sendPush(db, userID, "streak_milestone_pair", params, nil)
 		return
 	}
 
-	apnsClientOnce.Do(func() {
-		client, err := apns.NewClient(apnsKeyPath, apnsKeyID, apnsTeamID, apnsBundleID)
-		if err != nil {
-			fmt.Printf("Failed to create APNs client: %v\n", err)
-			return
-		}
-		apnsClient = client
-	})
-
-	if apnsClient != nil {
-		fmt.Printf("📤 Sending notification to user %s (token: %s...)\n", userID, deviceToken.String[:min(20, len(deviceToken.String))])
-		if err := apnsClient.SendNotification(deviceToken.String, title, body); err != nil {
-			fmt.Printf("❌ Failed to send APNs notification: %v\n", err)
-		} else {
-			fmt.Printf("✅ Notification sent successfully!\n")
-		}
-	} else {
-		fmt.Printf("❌ APNs client is nil, cannot send notification\n")
+	sendPush(db, userID, "streak_milestone", map[string]interface{}{"Days": days}, nil)
+}
+
+func SendStreakAtRiskNotification(db *sql.DB, userID uuid.UUID, days int) {
+	sendPush(db, userID, "streak_at_risk", map[string]interface{}{"Days": days}, nil)
+}
+
+// SendAchievementNotification — пуш об открытом достижении; title уже на языке пользователя.
+func SendAchievementNotification(db *sql.DB, userID uuid.UUID, title string) {
+	sendPush(db, userID, "achievement", map[string]interface{}{"Achievement": title}, nil)
+}
+
+func SendRecapReadyNotification(db *sql.DB, userID uuid.UUID, recap models.LoveRecap) {
+	key := "recap.year"
+	switch recap.Period {
+	case models.StatsBucketWeek:
+		key = "recap.week"
+	case models.StatsBucketMonth:
+		key = "recap.month"
 	}
+
+	data := map[string]interface{}{"recap_id": recap.ID.String()}
+	sendPush(db, userID, key, nil, data)
 }
 
-func SendPairRequestNotification(db *sql.DB, userID uuid.UUID, requesterUsername string) {
-	var deviceToken sql.NullString
-	err := db.QueryRow("SELECT device_token FROM users WHERE id = $1", userID).Scan(&deviceToken)
-	if err != nil || !deviceToken.Valid {
-		return
+func SendMemoryNotification(db *sql.DB, userID uuid.UUID, memory models.Memory) {
+	params := map[string]interface{}{
+		"YearsAgo": memory.YearsAgo,
+		"Partner":  memory.PartnerUsername,
+		"Duration": memory.DurationSeconds,
 	}
 
-	title := "Love Connection"
-	body := fmt.Sprintf("%s wants to connect with you!", requesterUsername)
+	key := "memory.long_hold"
+	switch memory.Kind {
+	case models.MemoryKindPaired:
+		key = "memory.paired"
+	case models.MemoryKindFirstHeart:
+		key = "memory.first_heart"
+	}
 
-	apnsKeyPath := os.Getenv("APNS_KEY_PATH")
-	apnsKeyID := os.Getenv("APNS_KEY_ID")
-	apnsTeamID := os.Getenv("APNS_TEAM_ID")
-	apnsBundleID := os.Getenv("APNS_BUNDLE_ID")
+	sendPush(db, userID, key, params, nil)
+}
 
-	if apnsKeyPath == "" || apnsKeyID == "" || apnsTeamID == "" || apnsBundleID == "" {
-		fmt.Printf("⚠️ APNs not configured! Missing env vars:\n")
-		fmt.Printf("   APNS_KEY_PATH: %s\n", ifEmpty(apnsKeyPath, "NOT SET"))
-		fmt.Printf("   APNS_KEY_ID: %s\n", ifEmpty(apnsKeyID, "NOT SET"))
-		fmt.Printf("   APNS_TEAM_ID: %s\n", ifEmpty(apnsTeamID, "NOT SET"))
-		fmt.Printf("   APNS_BUNDLE_ID: %s\n", ifEmpty(apnsBundleID, "NOT SET"))
-		fmt.Printf("   Would send notification: %s\n", body)
-		return
+func SendDataExportReadyNotification(db *sql.DB, userID uuid.UUID, exportID uuid.UUID) {
+	data := map[string]interface{}{"data_export_id": exportID.String()}
+	sendPush(db, userID, "data_export_ready", nil, data)
+}
+
+// sendPush возвращает true, если пуш принят хотя бы для одного устройства.
+func sendPush(db *sql.DB, userID uuid.UUID, key string, params, data map[string]interface{}) bool {
+	return deliverLocalizedPush(db, userID, key, params, data) == nil
+}
+
+// deliverLocalizedPush отправляет пуш с текстом сообщения key из каталога на языке пользователя.
+func deliverLocalizedPush(db *sql.DB, userID uuid.UUID, key string, params, data map[string]interface{}) error {
+	title, body, err := renderNotification(db, userID, key, params)
+	if err != nil {
+		fmt.Printf("Failed to render notification %s for user %s: %v\n", key, userID, err)
+		return err
 	}
 
-	apnsClientOnce.Do(func() {
-		client, err := apns.NewClient(apnsKeyPath, apnsKeyID, apnsTeamID, apnsBundleID)
-		if err != nil {
-			fmt.Printf("Failed to create APNs client: %v\n", err)
-			return
-		}
-		apnsClient = client
-	})
+	return deliverPush(db, userID, title, body, data)
+}
 
-	if apnsClient != nil {
-		if err := apnsClient.SendNotification(deviceToken.String, title, body); err != nil {
-			fmt.Printf("Failed to send APNs notification: %v\n", err)
-		}
+// deliverPush отправляет alert-пуш на все активные устройства пользователя.
+func deliverPush(db *sql.DB, userID uuid.UUID, title, body string, data map[string]interface{}) error {
+	// Бейдж показывает реальное число непросмотренных сердечек
+	badge, err := UnreadCount(db, userID)
+	if err != nil {
+		fmt.Printf("Failed to count unread love events for user %s: %v\n", userID, err)
+		badge = 0
 	}
+
+	return pushToDevices(db, userID, PushMessage{Title: title, Body: body, Badge: badge, Data: data})
 }
 
-func formatDuration(seconds int) string {
-	minutes := seconds / 60
-	secs := seconds % 60
+// SendLoveDeletedNotification просит приложение партнера убрать уже показанный пуш удаленного сердечка.
+// APNs не умеет отзывать доставленные уведомления, поэтому отправляется тихий пуш с ID события.
+func SendLoveDeletedNotification(db *sql.DB, userID uuid.UUID, eventID uuid.UUID) {
+	pushToDevices(db, userID, loveDeletedMessage(eventID))
+}
 
-	if minutes > 0 {
-		if secs > 0 {
-			return fmt.Sprintf("%d мин %d сек", minutes, secs)
+// loveDeletedMessage — тихий пуш без текста: приложение только убирает событие из ленты.
+func loveDeletedMessage(eventID uuid.UUID) PushMessage {
+	return PushMessage{
+		Data:       map[string]interface{}{"deleted_love_event_id": eventID.String()},
+		Background: true,
+	}
+}
+
+// pushToDevices рассылает пуш на все активные устройства пользователя через транспорт их платформы.
+// Возвращает nil, если пуш принят хотя бы для одного устройства, и ErrPushSkipped, если отправлять некуда.
+func pushToDevices(db *sql.DB, userID uuid.UUID, msg PushMessage) error {
+	devices, err := activeDevices(db, userID)
+	if err != nil {
+		fmt.Printf("Failed to get devices for user %s: %v\n", userID, err)
+		return err
+	}
+	if len(devices) == 0 {
+		fmt.Printf("No devices found for user %s\n", userID)
+		return fmt.Errorf("%w: no active devices", ErrPushSkipped)
+	}
+
+	delivered := false
+	var lastErr error
+	for _, device := range devices {
+		notifier := notifierFor(device.Platform, msg)
+		if notifier == nil {
+			continue
 		}
-		return fmt.Sprintf("%d мин", minutes)
+
+		fmt.Printf("📤 Sending notification to user %s (%s device %s, token: %s...)\n", userID, device.Platform, device.ID, device.Token[:min(20, len(device.Token))])
+		err := notifier.Send(&device, msg)
+		recordDelivery(db, device, err)
+		if err != nil {
+			fmt.Printf("❌ Failed to send notification to device %s: %v\n", device.ID, err)
+			lastErr = err
+			continue
+		}
+		delivered = true
+	}
+
+	if delivered {
+		fmt.Printf("✅ Notification sent successfully!\n")
+		return nil
+	}
+	if lastErr == nil {
+		return fmt.Errorf("%w: no configured transport for user devices", ErrPushSkipped)
 	}
-	return fmt.Sprintf("%d сек", secs)
+	return lastErr
 }
 
 func ifEmpty(s, defaultValue string) string {
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// Пуш об удаленном сердечке — только данные: текста, который увидел бы пользователь, в нем нет.
+func TestLoveDeletedMessage(t *testing.T) {
+	eventID := uuid.New()
+	msg := loveDeletedMessage(eventID)
+
+	if msg.Title != "" || msg.Body != "" || msg.Badge != 0 {
+		t.Errorf("message has visible content: title %q, body %q, badge %d", msg.Title, msg.Body, msg.Badge)
+	}
+	if !msg.Background {
+		t.Error("message is not a background push")
+	}
+	if msg.Data["deleted_love_event_id"] != eventID.String() || len(msg.Data) != 1 {
+		t.Errorf("data = %v, want only deleted_love_event_id %s", msg.Data, eventID)
+	}
+}
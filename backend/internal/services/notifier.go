@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/pkg/apns"
+	"love-connection/backend/pkg/fcm"
+	"love-connection/backend/pkg/webpush"
+	"os"
+	"sync"
+	"time"
+)
+
+// PushMessage — пуш независимо от транспорта.
+type PushMessage struct {
+	Title string
+	Body  string
+	Badge int
+	Data  map[string]interface{}
+	// Background — тихий пуш только с данными, без alert.
+	Background bool
+}
+
+// Notifier доставляет пуши на устройства одной платформы.
+type Notifier interface {
+	// Send отправляет пуш на устройство. Если транспорт уточнил окружение токена
+	// (APNs fallback), оно записывается в device.Environment.
+	Send(device *models.Device, msg PushMessage) error
+}
+
+var (
+	apnsClient     *apns.Client
+	apnsClientOnce sync.Once
+	fcmClient      *fcm.Client
+	fcmClientOnce  sync.Once
+)
+
+// notifierFor возвращает транспорт для платформы устройства или nil, если он не настроен.
+func notifierFor(platform string, msg PushMessage) Notifier {
+	// Текст для лога, если транспорт не настроен; у тихих пушей текста нет
+	logText := msg.Body
+	if msg.Background {
+		logText = fmt.Sprintf("background %v", msg.Data)
+	}
+
+	switch platform {
+	case models.DevicePlatformIOS:
+		if client := getAPNsClient(logText); client != nil {
+			return apnsNotifier{client: client}
+		}
+	case models.DevicePlatformAndroid:
+		if client := getFCMClient(logText); client != nil {
+			return fcmNotifier{client: client}
+		}
+	case models.DevicePlatformWeb:
+		if client := getWebPushClient(); client != nil {
+			return webPushNotifier{client: client}
+		}
+		fmt.Printf("⚠️ Web Push not configured! VAPID_PRIVATE_KEY and VAPID_SUBJECT are required\n")
+	default:
+		fmt.Printf("⚠️ No notifier for platform %q\n", platform)
+	}
+	return nil
+}
+
+type apnsNotifier struct {
+	client *apns.Client
+}
+
+func (n apnsNotifier) Send(device *models.Device, msg PushMessage) error {
+	var env apns.Environment
+	var err error
+	if msg.Background {
+		env, err = n.client.SendBackgroundNotification(device.Token, apns.Environment(device.Environment), msg.Data)
+	} else {
+		env, err = n.client.SendNotification(device.Token, apns.Environment(device.Environment), msg.Title, msg.Body, msg.Badge, msg.Data)
+	}
+	device.Environment = string(env)
+	return err
+}
+
+type fcmNotifier struct {
+	client *fcm.Client
+}
+
+func (n fcmNotifier) Send(device *models.Device, msg PushMessage) error {
+	// В FCM значения data — только строки
+	data := make(map[string]string, len(msg.Data))
+	for key, value := range msg.Data {
+		data[key] = fmt.Sprint(value)
+	}
+
+	message := fcm.Message{Token: device.Token, Data: data}
+	if !msg.Background {
+		message.Title = msg.Title
+		message.Body = msg.Body
+		message.Badge = msg.Badge
+	}
+
+	return n.client.Send(message)
+}
+
+// deliveryFailure разбирает ошибку транспорта: причину для статистики устройства, признак
+// недействительного токена и время, с которого токен недействителен (если транспорт его сообщил).
+func deliveryFailure(err error) (reason string, invalid bool, since time.Time) {
+	var apnsErr *apns.Error
+	var fcmErr *fcm.Error
+	var webPushErr *webpush.Error
+	switch {
+	case errors.As(err, &apnsErr):
+		invalid = errors.Is(err, apns.ErrUnregistered) || errors.Is(err, apns.ErrBadDeviceToken)
+		return apnsErr.Reason, invalid, apnsErr.Timestamp
+	case errors.As(err, &fcmErr):
+		invalid = errors.Is(err, fcm.ErrUnregistered) || errors.Is(err, fcm.ErrSenderIDMismatch)
+		return fcmErr.Code, invalid, time.Time{}
+	case errors.As(err, &webPushErr):
+		return fmt.Sprintf("HTTP %d", webPushErr.StatusCode), errors.Is(err, webpush.ErrSubscriptionGone), time.Time{}
+	}
+	return "RequestFailed", false, time.Time{}
+}
+
+// getAPNsClient лениво создает APNs клиент из переменных окружения.
+// Если APNs не настроен, логирует уведомление, которое было бы отправлено, и возвращает nil.
+func getAPNsClient(body string) *apns.Client {
+	apnsKeyPath := os.Getenv("APNS_KEY_PATH")
+	apnsKeyID := os.Getenv("APNS_KEY_ID")
+	apnsTeamID := os.Getenv("APNS_TEAM_ID")
+	apnsBundleID := os.Getenv("APNS_BUNDLE_ID")
+
+	if apnsKeyPath == "" || apnsKeyID == "" || apnsTeamID == "" || apnsBundleID == "" {
+		fmt.Printf("⚠️ APNs not configured! Missing env vars:\n")
+		fmt.Printf("   APNS_KEY_PATH: %s\n", ifEmpty(apnsKeyPath, "NOT SET"))
+		fmt.Printf("   APNS_KEY_ID: %s\n", ifEmpty(apnsKeyID, "NOT SET"))
+		fmt.Printf("   APNS_TEAM_ID: %s\n", ifEmpty(apnsTeamID, "NOT SET"))
+		fmt.Printf("   APNS_BUNDLE_ID: %s\n", ifEmpty(apnsBundleID, "NOT SET"))
+		fmt.Printf("   Would send notification: %s\n", body)
+		return nil
+	}
+
+	apnsClientOnce.Do(func() {
+		var opts []apns.Option
+		if baseURL := os.Getenv("APNS_BASE_URL"); baseURL != "" {
+			opts = append(opts, apns.WithBaseURL(baseURL))
+		}
+		if baseURL := os.Getenv("APNS_DEVELOPMENT_BASE_URL"); baseURL != "" {
+			opts = append(opts, apns.WithEnvironmentURL(apns.Development, baseURL))
+		}
+
+		client, err := apns.NewClient(apnsKeyPath, apnsKeyID, apnsTeamID, apnsBundleID, opts...)
+		if err != nil {
+			fmt.Printf("Failed to create APNs client: %v\n", err)
+			return
+		}
+		apnsClient = client
+	})
+
+	if apnsClient == nil {
+		fmt.Printf("❌ APNs client is nil, cannot send notification\n")
+	}
+
+	return apnsClient
+}
+
+// getFCMClient лениво создает клиент FCM из FCM_CREDENTIALS_FILE (JSON-ключ сервисного аккаунта).
+func getFCMClient(body string) *fcm.Client {
+	credentialsPath := os.Getenv("FCM_CREDENTIALS_FILE")
+	if credentialsPath == "" {
+		fmt.Printf("⚠️ FCM not configured! FCM_CREDENTIALS_FILE is not set\n")
+		fmt.Printf("   Would send notification: %s\n", body)
+		return nil
+	}
+
+	fcmClientOnce.Do(func() {
+		var opts []fcm.Option
+		if endpoint := os.Getenv("FCM_ENDPOINT"); endpoint != "" {
+			opts = append(opts, fcm.WithEndpoint(endpoint))
+		}
+		if tokenURL := os.Getenv("FCM_TOKEN_URL"); tokenURL != "" {
+			opts = append(opts, fcm.WithTokenURL(tokenURL))
+		}
+
+		client, err := fcm.NewClient(credentialsPath, opts...)
+		if err != nil {
+			fmt.Printf("Failed to create FCM client: %v\n", err)
+			return
+		}
+		fcmClient = client
+	})
+
+	if fcmClient == nil {
+		fmt.Printf("❌ FCM client is nil, cannot send notification\n")
+	}
+
+	return fcmClient
+}
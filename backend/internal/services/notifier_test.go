@@ -0,0 +1,42 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"love-connection/backend/pkg/apns"
+	"love-connection/backend/pkg/fcm"
+	"love-connection/backend/pkg/webpush"
+	"testing"
+	"time"
+)
+
+func TestDeliveryFailure(t *testing.T) {
+	unregisteredAt := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		err         error
+		wantReason  string
+		wantInvalid bool
+		wantSince   time.Time
+	}{
+		{"apns unregistered", &apns.Error{StatusCode: 410, Reason: "Unregistered", Timestamp: unregisteredAt}, "Unregistered", true, unregisteredAt},
+		{"apns bad token", &apns.Error{StatusCode: 400, Reason: "BadDeviceToken"}, "BadDeviceToken", true, time.Time{}},
+		{"apns wrapped", fmt.Errorf("send: %w", &apns.Error{StatusCode: 400, Reason: "BadDeviceToken"}), "BadDeviceToken", true, time.Time{}},
+		{"apns throttled", &apns.Error{StatusCode: 429, Reason: "TooManyRequests"}, "TooManyRequests", false, time.Time{}},
+		{"fcm unregistered", &fcm.Error{StatusCode: 404, Code: "UNREGISTERED"}, "UNREGISTERED", true, time.Time{}},
+		{"fcm sender mismatch", &fcm.Error{StatusCode: 403, Code: "SENDER_ID_MISMATCH"}, "SENDER_ID_MISMATCH", true, time.Time{}},
+		{"fcm unavailable", &fcm.Error{StatusCode: 503, Code: "UNAVAILABLE"}, "UNAVAILABLE", false, time.Time{}},
+		{"web push gone", &webpush.Error{StatusCode: 410}, "HTTP 410", true, time.Time{}},
+		{"web push server error", &webpush.Error{StatusCode: 500}, "HTTP 500", false, time.Time{}},
+		{"network error", errors.New("connection reset"), "RequestFailed", false, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		reason, invalid, since := deliveryFailure(tt.err)
+		if reason != tt.wantReason || invalid != tt.wantInvalid || !since.Equal(tt.wantSince) {
+			t.Errorf("%s: deliveryFailure = %q, %v, %s; want %q, %v, %s",
+				tt.name, reason, invalid, since, tt.wantReason, tt.wantInvalid, tt.wantSince)
+		}
+	}
+}
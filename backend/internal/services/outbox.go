@@ -0,0 +1,421 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	outboxPollInterval = 2 * time.Second
+	// Сообщение, взятое воркером, который так и не отчитался (например, упал), снова берется после этого.
+	// Пока отправка идет, воркер продлевает аренду каждые outboxLeaseRenewal: отправка на несколько
+	// устройств с таймаутами и повторами APNs может длиться дольше самой аренды.
+	outboxLease          = 2 * time.Minute
+	outboxLeaseRenewal   = 30 * time.Second
+	outboxMaxAttempts    = 8
+	outboxBaseBackoff    = 10 * time.Second
+	outboxMaxBackoff     = time.Hour
+	defaultOutboxWorkers = 4
+	maxOutboxListLimit   = 500
+	// Отправленные и пропущенные сообщения хранятся OUTBOX_RETENTION, затем удаляются; dead остаются до разбора.
+	defaultOutboxRetention = 7 * 24 * time.Hour
+	outboxSweepInterval    = time.Hour
+)
+
+var (
+	// ErrPushSkipped — пуш отправлять некуда или незачем (нет устройств, событие удалено); повторять не нужно.
+	ErrPushSkipped           = errors.New("push skipped")
+	ErrOutboxMessageNotFound = errors.New("outbox message not found")
+	errUnknownOutboxKind     = errors.New("unknown outbox kind")
+	outboxWake               = make(chan struct{}, 1)
+	outboxHandlers           = map[string]func(db *sql.DB, userID uuid.UUID, payload json.RawMessage) error{
+		models.OutboxKindLoveEvent:   deliverLoveEventMessage,
+		models.OutboxKindPairRequest: deliverPairRequestMessage,
+	}
+)
+
+type loveEventPayload struct {
+	LoveEventID uuid.UUID `json:"love_event_id"`
+}
+
+type pairRequestPayload struct {
+	PairRequestID uuid.UUID `json:"pair_request_id"`
+}
+
+// EnqueueNotification записывает уведомление в outbox. db должна быть транзакцией, в которой
+// создается само событие: тогда уведомление не потеряется и не уйдет для откаченного события.
+// Повторная запись с тем же dedupeKey игнорируется.
+func EnqueueNotification(db queryer, userID uuid.UUID, kind, dedupeKey string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(
+		`INSERT INTO notification_outbox (user_id, kind, dedupe_key, payload)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (dedupe_key) DO NOTHING`,
+		userID, kind, dedupeKey, data,
+	)
+	return err
+}
+
+func EnqueueLoveEventNotification(db queryer, recipientID, eventID uuid.UUID) error {
+	return EnqueueNotification(db, recipientID, models.OutboxKindLoveEvent,
+		fmt.Sprintf("love_event:%s", eventID), loveEventPayload{LoveEventID: eventID})
+}
+
+func EnqueuePairRequestNotification(db queryer, requestedID, requestID uuid.UUID) error {
+	return EnqueueNotification(db, requestedID, models.OutboxKindPairRequest,
+		fmt.Sprintf("pair_request:%s", requestID), pairRequestPayload{PairRequestID: requestID})
+}
+
+// WakeOutbox будит воркер сразу после коммита, не дожидаясь очередного опроса.
+func WakeOutbox() {
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+func deliverLoveEventMessage(db *sql.DB, userID uuid.UUID, payload json.RawMessage) error {
+	var p loveEventPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	event, err := GetLoveEvent(db, p.LoveEventID)
+	if err == sql.ErrNoRows {
+		// Сердечко успели удалить до отправки пуша
+		return fmt.Errorf("%w: love event deleted", ErrPushSkipped)
+	}
+	if err != nil {
+		return err
+	}
+
+	senderUsername := ""
+	if event.Sender != nil {
+		senderUsername = event.Sender.Username
+	}
+
+	return SendNotification(db, userID, event.ID, senderUsername, event.DurationSeconds)
+}
+
+func deliverPairRequestMessage(db *sql.DB, userID uuid.UUID, payload json.RawMessage) error {
+	var p pairRequestPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+
+	var requesterUsername string
+	err := db.QueryRow(
+		`SELECT u.username
+		FROM pair_requests pr
+		JOIN users u ON u.id = pr.requester_id
+		WHERE pr.id = $1 AND pr.status = 'pending'`,
+		p.PairRequestID,
+	).Scan(&requesterUsername)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("%w: pair request is no longer pending", ErrPushSkipped)
+	}
+	if err != nil {
+		return err
+	}
+
+	return SendPairRequestNotification(db, userID, requesterUsername)
+}
+
+// outboxBackoff — задержка перед следующей попыткой: экспонента от числа попыток с разбросом ±20%.
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxMaxBackoff
+	if attempts < 20 && outboxBaseBackoff<<(attempts-1) < outboxMaxBackoff {
+		delay = outboxBaseBackoff << (attempts - 1)
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5*2+1)) - delay/5
+	return delay + jitter
+}
+
+// OutboxWorker доставляет уведомления из notification_outbox пулом воркеров.
+// Доставка как минимум однократная: сообщение, не отмеченное после отправки, будет отправлено снова.
+// Ошибки повторяются с экспоненциальной задержкой, после outboxMaxAttempts сообщение уходит в dead.
+type OutboxWorker struct {
+	db        *sql.DB
+	workers   int
+	retention time.Duration
+}
+
+// NewOutboxWorker создает пул размером OUTBOX_WORKERS (по умолчанию 4).
+func NewOutboxWorker(db *sql.DB) *OutboxWorker {
+	workers := defaultOutboxWorkers
+	if n, err := strconv.Atoi(os.Getenv("OUTBOX_WORKERS")); err == nil && n > 0 {
+		workers = n
+	}
+	return &OutboxWorker{db: db, workers: workers, retention: outboxRetention()}
+}
+
+// outboxRetention — сколько хранить sent и skipped сообщения. Настраивается через OUTBOX_RETENTION (например, "72h").
+func outboxRetention() time.Duration {
+	value := os.Getenv("OUTBOX_RETENTION")
+	if value == "" {
+		return defaultOutboxRetention
+	}
+
+	retention, err := time.ParseDuration(value)
+	if err != nil || retention <= 0 {
+		fmt.Printf("Invalid OUTBOX_RETENTION %q, using %s\n", value, defaultOutboxRetention)
+		return defaultOutboxRetention
+	}
+	return retention
+}
+
+// Run возвращается, когда ctx отменен и все начатые отправки завершены.
+func (w *OutboxWorker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.sweepLoop(ctx)
+	}()
+
+	wg.Wait()
+}
+
+// sweepLoop раз в outboxSweepInterval удаляет доставленные и пропущенные сообщения старше retention.
+func (w *OutboxWorker) sweepLoop(ctx context.Context) {
+	ticker := time.NewTicker(outboxSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		removed, err := w.removeCompleted()
+		if err != nil {
+			fmt.Printf("Failed to remove completed notification outbox messages: %v\n", err)
+		} else if removed > 0 {
+			fmt.Printf("🧹 Removed %d completed notification outbox messages\n", removed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *OutboxWorker) removeCompleted() (int64, error) {
+	result, err := w.db.Exec(
+		"DELETE FROM notification_outbox WHERE status IN ('sent', 'skipped') AND completed_at < $1",
+		time.Now().Add(-w.retention),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (w *OutboxWorker) loop(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			processed, err := w.processNext()
+			if err != nil {
+				fmt.Printf("Failed to process notification outbox: %v\n", err)
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-outboxWake:
+		}
+	}
+}
+
+func (w *OutboxWorker) processNext() (bool, error) {
+	var msg models.OutboxMessage
+	err := w.db.QueryRow(
+		`UPDATE notification_outbox SET status = 'processing', attempts = attempts + 1, locked_until = $1
+		WHERE id = (
+			SELECT id FROM notification_outbox
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+				OR (status = 'processing' AND locked_until < NOW())
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, kind, payload, attempts`,
+		time.Now().Add(outboxLease),
+	).Scan(&msg.ID, &msg.UserID, &msg.Kind, &msg.Payload, &msg.Attempts)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	handler, ok := outboxHandlers[msg.Kind]
+	if !ok {
+		return true, w.complete(msg, models.OutboxStatusDead, fmt.Errorf("%w %q", errUnknownOutboxKind, msg.Kind))
+	}
+
+	stopRenewal := w.renewLease(msg.ID)
+	sendErr := handler(w.db, msg.UserID, msg.Payload)
+	stopRenewal()
+
+	return true, w.finish(msg, sendErr)
+}
+
+// finish записывает результат попытки: отправлено, пропущено, dead после outboxMaxAttempts
+// или повтор через outboxBackoff.
+func (w *OutboxWorker) finish(msg models.OutboxMessage, sendErr error) error {
+	switch {
+	case sendErr == nil:
+		return w.complete(msg, models.OutboxStatusSent, nil)
+	case errors.Is(sendErr, ErrPushSkipped):
+		return w.complete(msg, models.OutboxStatusSkipped, sendErr)
+	case msg.Attempts >= outboxMaxAttempts:
+		fmt.Printf("☠️ Notification %s failed %d times, moving to dead letters: %v\n", msg.ID, msg.Attempts, sendErr)
+		return w.complete(msg, models.OutboxStatusDead, sendErr)
+	}
+
+	_, err := w.db.Exec(
+		`UPDATE notification_outbox SET status = 'pending', locked_until = NULL, last_error = $2, next_attempt_at = $3
+		WHERE id = $1`,
+		msg.ID, sendErr.Error(), time.Now().Add(outboxBackoff(msg.Attempts)),
+	)
+	return err
+}
+
+// renewLease продлевает аренду сообщения, пока не будет вызвана возвращенная функция.
+// Если воркер упадет, продления прекратятся и сообщение возьмет другой воркер.
+func (w *OutboxWorker) renewLease(messageID uuid.UUID) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(outboxLeaseRenewal)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			_, err := w.db.Exec(
+				"UPDATE notification_outbox SET locked_until = $2 WHERE id = $1 AND status = 'processing'",
+				messageID, time.Now().Add(outboxLease),
+			)
+			if err != nil {
+				fmt.Printf("Failed to renew lease of notification %s: %v\n", messageID, err)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func (w *OutboxWorker) complete(msg models.OutboxMessage, status string, cause error) error {
+	var lastError *string
+	if cause != nil {
+		s := cause.Error()
+		lastError = &s
+	}
+
+	_, err := w.db.Exec(
+		`UPDATE notification_outbox SET status = $2, locked_until = NULL, last_error = COALESCE($3, last_error), completed_at = NOW()
+		WHERE id = $1`,
+		msg.ID, status, lastError,
+	)
+	return err
+}
+
+// ListOutboxMessages возвращает сообщения outbox со статусом status, новые сначала.
+func ListOutboxMessages(db *sql.DB, status string, limit int) ([]models.OutboxMessage, error) {
+	if limit <= 0 || limit > maxOutboxListLimit {
+		limit = maxOutboxListLimit
+	}
+
+	rows, err := db.Query(
+		`SELECT id, user_id, kind, dedupe_key, payload, status, attempts, next_attempt_at, last_error, created_at, completed_at
+		FROM notification_outbox
+		WHERE status = $1
+		ORDER BY created_at DESC
+		LIMIT $2`,
+		status, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.OutboxMessage{}
+	for rows.Next() {
+		var msg models.OutboxMessage
+		err := rows.Scan(
+			&msg.ID, &msg.UserID, &msg.Kind, &msg.DedupeKey, &msg.Payload, &msg.Status, &msg.Attempts,
+			&msg.NextAttemptAt, &msg.LastError, &msg.CreatedAt, &msg.CompletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, rows.Err()
+}
+
+// RetryOutboxMessage возвращает сообщение из dead в очередь с обнуленным счетчиком попыток
+// и без ошибки прошлых попыток.
+func RetryOutboxMessage(db *sql.DB, messageID uuid.UUID) error {
+	result, err := db.Exec(
+		`UPDATE notification_outbox SET status = 'pending', attempts = 0, next_attempt_at = NOW(),
+			locked_until = NULL, last_error = NULL, completed_at = NULL
+		WHERE id = $1 AND status = 'dead'`,
+		messageID,
+	)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrOutboxMessageNotFound
+	}
+
+	WakeOutbox()
+	return nil
+}
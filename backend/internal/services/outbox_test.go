@@ -0,0 +1,143 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	for attempts := 1; attempts <= 40; attempts++ {
+		base := outboxMaxBackoff
+		if attempts <= 10 {
+			base = min64(outboxBaseBackoff<<(attempts-1), outboxMaxBackoff)
+		}
+		low, high := base-base/5, base+base/5
+
+		seen := make(map[time.Duration]bool)
+		for i := 0; i < 200; i++ {
+			delay := outboxBackoff(attempts)
+			if delay < low || delay > high {
+				t.Fatalf("attempt %d: backoff %s outside [%s, %s]", attempts, delay, low, high)
+			}
+			seen[delay] = true
+		}
+		// Разброс нужен, чтобы сообщения, упавшие вместе, не повторялись одновременно
+		if len(seen) < 2 {
+			t.Errorf("attempt %d: backoff has no jitter", attempts)
+		}
+	}
+}
+
+func min64(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func TestOutboxFinish(t *testing.T) {
+	sendErr := errors.New("apns: service unavailable")
+
+	tests := []struct {
+		name       string
+		attempts   int
+		err        error
+		wantStatus string
+	}{
+		{"sent", 1, nil, models.OutboxStatusSent},
+		{"skipped", 1, fmt.Errorf("%w: no devices", ErrPushSkipped), models.OutboxStatusSkipped},
+		{"first failure", 1, sendErr, models.OutboxStatusPending},
+		{"last retry", outboxMaxAttempts - 1, sendErr, models.OutboxStatusPending},
+		{"dead after max attempts", outboxMaxAttempts, sendErr, models.OutboxStatusDead},
+		{"sent on the last attempt", outboxMaxAttempts, nil, models.OutboxStatusSent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := newRecordingDB(t)
+			w := &OutboxWorker{db: db}
+			msg := models.OutboxMessage{ID: uuid.New(), Attempts: tt.attempts}
+
+			before := time.Now()
+			if err := w.finish(msg, tt.err); err != nil {
+				t.Fatalf("finish: %v", err)
+			}
+
+			execs := recorder.recorded()
+			if len(execs) != 1 {
+				t.Fatalf("got %d statements, want 1", len(execs))
+			}
+			exec := execs[0]
+			if exec.Args[0] != msg.ID.String() {
+				t.Errorf("updated %v, want message %s", exec.Args[0], msg.ID)
+			}
+
+			if tt.wantStatus != models.OutboxStatusPending {
+				if !strings.Contains(exec.Query, "completed_at = NOW()") || exec.Args[1] != tt.wantStatus {
+					t.Errorf("statement %q with %v, want status %s", exec.Query, exec.Args, tt.wantStatus)
+				}
+				return
+			}
+
+			if !strings.Contains(exec.Query, "status = 'pending'") {
+				t.Fatalf("statement %q, want a retry", exec.Query)
+			}
+			if exec.Args[1] != sendErr.Error() {
+				t.Errorf("last_error = %v", exec.Args[1])
+			}
+			next, _ := exec.Args[2].(time.Time)
+			delay := next.Sub(before)
+			if base := outboxBaseBackoff << (tt.attempts - 1); delay < base-base/5 || delay > base+base/5+time.Second {
+				t.Errorf("next attempt in %s, want about %s", delay, base)
+			}
+		})
+	}
+}
+
+// Сообщение, возвращенное из dead, начинает попытки заново и без прошлой ошибки.
+func TestRetryOutboxMessage(t *testing.T) {
+	db := openTestDB(t)
+	userID := createTestUser(t, db, "UTC")
+
+	var messageID uuid.UUID
+	err := db.QueryRow(
+		`INSERT INTO notification_outbox (user_id, kind, dedupe_key, payload, status, attempts, last_error, locked_until, completed_at)
+		VALUES ($1, $2, $3, '{}', 'dead', $4, 'apns: service unavailable', NOW() + INTERVAL '1 minute', NOW())
+		RETURNING id`,
+		userID, models.OutboxKindLoveEvent, "test:"+uuid.NewString(), outboxMaxAttempts,
+	).Scan(&messageID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RetryOutboxMessage(db, messageID); err != nil {
+		t.Fatalf("RetryOutboxMessage: %v", err)
+	}
+
+	var status string
+	var attempts int
+	var lastError *string
+	var lockedUntil, completedAt *time.Time
+	err = db.QueryRow(
+		"SELECT status, attempts, last_error, locked_until, completed_at FROM notification_outbox WHERE id = $1",
+		messageID,
+	).Scan(&status, &attempts, &lastError, &lockedUntil, &completedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != models.OutboxStatusPending || attempts != 0 || lastError != nil || lockedUntil != nil || completedAt != nil {
+		t.Errorf("retried message: status %s, attempts %d, last_error %v, locked_until %v, completed_at %v",
+			status, attempts, lastError, lockedUntil, completedAt)
+	}
+
+	// Повторить можно только dead-сообщение
+	if err := RetryOutboxMessage(db, messageID); err != ErrOutboxMessageNotFound {
+		t.Errorf("retry of a pending message: err = %v, want ErrOutboxMessageNotFound", err)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"love-connection/backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var ErrInvalidReaction = errors.New("invalid reaction")
+
+// findReceivedEvent проверяет, что событие отправлено партнером пользователя, и возвращает отправителя.
+func findReceivedEvent(db *sql.DB, userID, eventID uuid.UUID) (uuid.UUID, error) {
+	var senderID uuid.UUID
+	err := db.QueryRow(
+		`SELECT e.sender_id
+		FROM love_events e
+		JOIN pairs p ON e.pair_id = p.id
+		WHERE e.id = $1 AND (p.user1_id = $2 OR p.user2_id = $2) AND p.archived_at IS NULL AND e.sender_id != $2`,
+		eventID, userID,
+	).Scan(&senderID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, ErrLoveEventNotFound
+	}
+	return senderID, err
+}
+
+// SetReaction ставит или меняет реакцию получателя на сердечко.
+// Возвращает реакцию и ID отправителя сердечка.
+func SetReaction(db *sql.DB, userID, eventID uuid.UUID, emoji string) (models.Reaction, uuid.UUID, error) {
+	var reaction models.Reaction
+	if !models.IsAllowedReaction(emoji) {
+		return reaction, uuid.Nil, ErrInvalidReaction
+	}
+
+	senderID, err := findReceivedEvent(db, userID, eventID)
+	if err != nil {
+		return reaction, uuid.Nil, err
+	}
+
+	err = db.QueryRow(
+		`INSERT INTO love_reactions (love_event_id, user_id, emoji)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (love_event_id, user_id) DO UPDATE SET emoji = EXCLUDED.emoji, updated_at = NOW()
+		RETURNING id, love_event_id, user_id, emoji, created_at, updated_at`,
+		eventID, userID, emoji,
+	).Scan(&reaction.ID, &reaction.LoveEventID, &reaction.UserID, &reaction.Emoji, &reaction.CreatedAt, &reaction.UpdatedAt)
+	if err != nil {
+		return reaction, uuid.Nil, err
+	}
+
+	return reaction, senderID, nil
+}
+
+// RemoveReaction удаляет реакцию получателя. Возвращает ID отправителя сердечка.
+func RemoveReaction(db *sql.DB, userID, eventID uuid.UUID) (uuid.UUID, error) {
+	senderID, err := findReceivedEvent(db, userID, eventID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err = db.Exec("DELETE FROM love_reactions WHERE love_event_id = $1 AND user_id = $2", eventID, userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return senderID, nil
+}
+
+// ReactionsForEvents загружает реакции для набора событий, сгруппированные по ID события.
+func ReactionsForEvents(db *sql.DB, eventIDs []uuid.UUID) (map[uuid.UUID][]models.Reaction, error) {
+	reactions := make(map[uuid.UUID][]models.Reaction)
+	if len(eventIDs) == 0 {
+		return reactions, nil
+	}
+
+	ids := make([]string, len(eventIDs))
+	for i, id := range eventIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := db.Query(
+		`SELECT id, love_event_id, user_id, emoji, created_at, updated_at
+		FROM love_reactions
+		WHERE love_event_id = ANY($1::uuid[])
+		ORDER BY created_at`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.Reaction
+		if err := rows.Scan(&r.ID, &r.LoveEventID, &r.UserID, &r.Emoji, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		reactions[r.LoveEventID] = append(reactions[r.LoveEventID], r)
+	}
+
+	return reactions, rows.Err()
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"love-connection/backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Реакция не из набора отклоняется до обращения к базе.
+func TestSetReactionRejectsUnknownEmoji(t *testing.T) {
+	for _, emoji := range []string{"", "👍", "❤", "❤️❤️", "<3", "heart"} {
+		if _, _, err := SetReaction(nil, uuid.New(), uuid.New(), emoji); err != ErrInvalidReaction {
+			t.Errorf("SetReaction(%q): err = %v, want ErrInvalidReaction", emoji, err)
+		}
+	}
+}
+
+func TestReactions(t *testing.T) {
+	db := openTestDB(t)
+	pairID, user1ID, user2ID := createTestPair(t, db)
+	outsiderID := createTestUser(t, db, "UTC")
+	event := insertTestLoveEvent(t, db, pairID, user1ID, 3, time.Now())
+	other := insertTestLoveEvent(t, db, pairID, user1ID, 3, time.Now())
+
+	reaction, senderID, err := SetReaction(db, user2ID, event.ID, models.AllowedReactions[0])
+	if err != nil {
+		t.Fatalf("SetReaction: %v", err)
+	}
+	if senderID != user1ID || reaction.UserID != user2ID || reaction.LoveEventID != event.ID {
+		t.Errorf("SetReaction = %+v, sender %s", reaction, senderID)
+	}
+
+	// Повторная реакция заменяет прежнюю
+	changed, _, err := SetReaction(db, user2ID, event.ID, models.AllowedReactions[1])
+	if err != nil {
+		t.Fatalf("SetReaction: %v", err)
+	}
+	if changed.ID != reaction.ID || changed.Emoji != models.AllowedReactions[1] {
+		t.Errorf("changed reaction = %+v, want %s on reaction %s", changed, models.AllowedReactions[1], reaction.ID)
+	}
+
+	// Реагировать можно только на полученное сердечко своей пары
+	own := insertTestLoveEvent(t, db, pairID, user2ID, 3, time.Now())
+	for name, tt := range map[string]struct{ userID, eventID uuid.UUID }{
+		"own event":  {user2ID, own.ID},
+		"other pair": {outsiderID, event.ID},
+	} {
+		if _, _, err := SetReaction(db, tt.userID, tt.eventID, models.AllowedReactions[0]); err != ErrLoveEventNotFound {
+			t.Errorf("%s: SetReaction err = %v, want ErrLoveEventNotFound", name, err)
+		}
+		if _, err := RemoveReaction(db, tt.userID, tt.eventID); err != ErrLoveEventNotFound {
+			t.Errorf("%s: RemoveReaction err = %v, want ErrLoveEventNotFound", name, err)
+		}
+	}
+
+	reactions, err := ReactionsForEvents(db, []uuid.UUID{event.ID, other.ID})
+	if err != nil {
+		t.Fatalf("ReactionsForEvents: %v", err)
+	}
+	if len(reactions[event.ID]) != 1 || reactions[event.ID][0].Emoji != models.AllowedReactions[1] || len(reactions[other.ID]) != 0 {
+		t.Errorf("reactions = %+v", reactions)
+	}
+
+	if _, err := RemoveReaction(db, user2ID, event.ID); err != nil {
+		t.Fatalf("RemoveReaction: %v", err)
+	}
+	if reactions, err := ReactionsForEvents(db, []uuid.UUID{event.ID}); err != nil || len(reactions[event.ID]) != 0 {
+		t.Errorf("reactions after removal = %+v, %v", reactions, err)
+	}
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	recapPollInterval = time.Hour
+	defaultRecapLimit = 20
+	maxRecapLimit     = 100
+)
+
+var (
+	ErrRecapNotFound      = errors.New("recap not found")
+	ErrInvalidRecapPeriod = errors.New("period must be 'week', 'month' or 'year'")
+	ErrRecapPeriodNotOver = errors.New("recap period is not over yet")
+)
+
+var recapPeriods = []string{models.StatsBucketWeek, models.StatsBucketMonth, models.StatsBucketYear}
+
+func IsRecapPeriod(period string) bool {
+	for _, p := range recapPeriods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
+
+// PairLocation — часовой пояс, в котором считаются периоды отчетов пары (пояс первого партнера).
+func PairLocation(db queryer, pair models.Pair) (*time.Location, error) {
+	return UserLocation(db, pair.User1ID)
+}
+
+const recapColumns = "id, pair_id, period, to_char(period_start, 'YYYY-MM-DD'), to_char(period_end, 'YYYY-MM-DD'), timezone, report, created_at"
+
+func scanRecap(row interface{ Scan(...interface{}) error }) (models.LoveRecap, error) {
+	var recap models.LoveRecap
+	var report []byte
+	err := row.Scan(&recap.ID, &recap.PairID, &recap.Period, &recap.PeriodStart, &recap.PeriodEnd, &recap.Timezone, &report, &recap.CreatedAt)
+	if err != nil {
+		return recap, err
+	}
+	return recap, json.Unmarshal(report, &recap.Report)
+}
+
+// buildRecapReport считает отчет пары за [start, end) в часовом поясе loc.
+func buildRecapReport(db queryer, pair models.Pair, period string, start, end time.Time, loc *time.Location) (models.RecapReport, error) {
+	report := models.RecapReport{Partners: []models.RecapPartner{}}
+
+	rows, err := db.Query(
+		`SELECT u.id, u.username, COUNT(e.id), COALESCE(SUM(e.duration_seconds), 0)
+		FROM users u
+		LEFT JOIN love_events e ON e.sender_id = u.id AND e.pair_id = $1 AND e.created_at >= $2 AND e.created_at < $3
+		WHERE u.id IN ($4, $5)
+		GROUP BY u.id, u.username
+		ORDER BY u.username`,
+		pair.ID, start, end, pair.User1ID, pair.User2ID,
+	)
+	if err != nil {
+		return report, err
+	}
+	for rows.Next() {
+		var partner models.RecapPartner
+		if err := rows.Scan(&partner.UserID, &partner.Username, &partner.Hearts, &partner.TotalDurationSeconds); err != nil {
+			rows.Close()
+			return report, err
+		}
+		report.Partners = append(report.Partners, partner)
+		report.TotalHearts += partner.Hearts
+		report.TotalDurationSeconds += partner.TotalDurationSeconds
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	var hold models.RecapLongestHold
+	err = db.QueryRow(
+		`SELECT sender_id, duration_seconds, created_at FROM love_events
+		WHERE pair_id = $1 AND created_at >= $2 AND created_at < $3
+		ORDER BY duration_seconds DESC, created_at
+		LIMIT 1`,
+		pair.ID, start, end,
+	).Scan(&hold.SenderID, &hold.DurationSeconds, &hold.CreatedAt)
+	if err == nil {
+		report.LongestHold = &hold
+	} else if err != sql.ErrNoRows {
+		return report, err
+	}
+
+	var busiestHour int
+	err = db.QueryRow(
+		`SELECT EXTRACT(HOUR FROM created_at AT TIME ZONE $4)::int FROM love_events
+		WHERE pair_id = $1 AND created_at >= $2 AND created_at < $3
+		GROUP BY 1
+		ORDER BY COUNT(*) DESC, 1
+		LIMIT 1`,
+		pair.ID, start, end, loc.String(),
+	).Scan(&busiestHour)
+	if err == nil {
+		report.BusiestHour = &busiestHour
+	} else if err != sql.ErrNoRows {
+		return report, err
+	}
+
+	err = db.QueryRow(
+		`SELECT
+			(SELECT COUNT(DISTINCT (created_at AT TIME ZONE $4)::date) FROM love_events
+				WHERE pair_id = $1 AND created_at >= $2 AND created_at < $3),
+			(SELECT COUNT(*) FROM synced_hearts
+				WHERE pair_id = $1 AND overlap_started_at >= $2 AND overlap_started_at < $3)`,
+		pair.ID, start, end, loc.String(),
+	).Scan(&report.ActiveDays, &report.SyncedHearts)
+	if err != nil {
+		return report, err
+	}
+
+	// Серия пары внутри периода: дни серий уже в локальных датах партнеров
+	days, err := pairStreakDays(db, pair, StreakGrace())
+	if err != nil {
+		return report, err
+	}
+	firstDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	lastDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	for day := range days {
+		if day.Before(firstDay) || !day.Before(lastDay) {
+			delete(days, day)
+		}
+	}
+	report.LongestStreak = longestRun(days)
+
+	previousStart := TruncateToBucket(start.AddDate(0, 0, -1), period)
+	report.Previous.PeriodStart = previousStart.Format("2006-01-02")
+	err = db.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(duration_seconds), 0) FROM love_events
+		WHERE pair_id = $1 AND created_at >= $2 AND created_at < $3`,
+		pair.ID, previousStart, start,
+	).Scan(&report.Previous.TotalHearts, &report.Previous.TotalDurationSeconds)
+	if err != nil {
+		return report, err
+	}
+	if report.Previous.TotalHearts > 0 {
+		change := float64(report.TotalHearts-report.Previous.TotalHearts) / float64(report.Previous.TotalHearts) * 100
+		report.Previous.HeartsChangePercent = &change
+	}
+
+	return report, nil
+}
+
+// GenerateRecap возвращает отчет пары за период, в который попадает day (в часовом поясе пары).
+// Готовый отчет не пересчитывается; created — отчет создан этим вызовом.
+func GenerateRecap(db *sql.DB, pair models.Pair, period string, day time.Time) (models.LoveRecap, bool, error) {
+	if !IsRecapPeriod(period) {
+		return models.LoveRecap{}, false, ErrInvalidRecapPeriod
+	}
+
+	loc, err := PairLocation(db, pair)
+	if err != nil {
+		return models.LoveRecap{}, false, err
+	}
+
+	start := TruncateToBucket(time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, loc), period)
+	end := nextBucket(start, period)
+	if end.After(time.Now()) {
+		return models.LoveRecap{}, false, ErrRecapPeriodNotOver
+	}
+
+	recap, err := scanRecap(db.QueryRow(
+		"SELECT "+recapColumns+" FROM love_recaps WHERE pair_id = $1 AND period = $2 AND period_start = $3",
+		pair.ID, period, start.Format("2006-01-02"),
+	))
+	if err == nil {
+		return recap, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return recap, false, err
+	}
+
+	report, err := buildRecapReport(db, pair, period, start, end, loc)
+	if err != nil {
+		return recap, false, err
+	}
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return recap, false, err
+	}
+
+	recap, err = scanRecap(db.QueryRow(
+		`INSERT INTO love_recaps (pair_id, period, period_start, period_end, timezone, report)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (pair_id, period, period_start) DO NOTHING
+		RETURNING `+recapColumns,
+		pair.ID, period, start.Format("2006-01-02"), end.Format("2006-01-02"), loc.String(), reportJSON,
+	))
+	if err == sql.ErrNoRows {
+		// Отчет параллельно создал другой инстанс
+		recap, err = scanRecap(db.QueryRow(
+			"SELECT "+recapColumns+" FROM love_recaps WHERE pair_id = $1 AND period = $2 AND period_start = $3",
+			pair.ID, period, start.Format("2006-01-02"),
+		))
+		return recap, false, err
+	}
+
+	return recap, err == nil, err
+}
+
+// ListRecaps возвращает отчеты пары от новых к старым; period может быть пустым.
+func ListRecaps(db *sql.DB, pairID uuid.UUID, period string, limit int) ([]models.LoveRecap, error) {
+	if limit <= 0 {
+		limit = defaultRecapLimit
+	}
+	if limit > maxRecapLimit {
+		limit = maxRecapLimit
+	}
+
+	rows, err := db.Query(
+		`SELECT `+recapColumns+` FROM love_recaps
+		WHERE pair_id = $1 AND ($2 = '' OR period = $2)
+		ORDER BY period_start DESC, period
+		LIMIT $3`,
+		pairID, period, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recaps := []models.LoveRecap{}
+	for rows.Next() {
+		recap, err := scanRecap(rows)
+		if err != nil {
+			return nil, err
+		}
+		recaps = append(recaps, recap)
+	}
+
+	return recaps, rows.Err()
+}
+
+// GetRecap возвращает отчет любой пары пользователя, включая архивные.
+func GetRecap(db *sql.DB, userID, recapID uuid.UUID) (models.LoveRecap, error) {
+	recap, err := scanRecap(db.QueryRow(
+		`SELECT r.id, r.pair_id, r.period, to_char(r.period_start, 'YYYY-MM-DD'), to_char(r.period_end, 'YYYY-MM-DD'),
+			r.timezone, r.report, r.created_at
+		FROM love_recaps r
+		JOIN pairs p ON p.id = r.pair_id
+		WHERE r.id = $1 AND (p.user1_id = $2 OR p.user2_id = $2)`,
+		recapID, userID,
+	))
+	if err == sql.ErrNoRows {
+		return recap, ErrRecapNotFound
+	}
+	return recap, err
+}
+
+// RecapWorker раз в час создает отчеты за только что закончившиеся неделю, месяц и год
+// для активных пар с сердечками за период и присылает обоим партнерам пуш.
+type RecapWorker struct {
+	db *sql.DB
+}
+
+func NewRecapWorker(db *sql.DB) *RecapWorker {
+	return &RecapWorker{db: db}
+}
+
+func (w *RecapWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(recapPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.generateDue(ctx); err != nil {
+			fmt.Printf("Failed to generate recaps: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *RecapWorker) generateDue(ctx context.Context) error {
+	rows, err := w.db.Query("SELECT id, user1_id, user2_id, created_at FROM pairs WHERE archived_at IS NULL")
+	if err != nil {
+		return err
+	}
+
+	var pairs []models.Pair
+	for rows.Next() {
+		var pair models.Pair
+		if err := rows.Scan(&pair.ID, &pair.User1ID, &pair.User2ID, &pair.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		pairs = append(pairs, pair)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		loc, err := PairLocation(w.db, pair)
+		if err != nil {
+			fmt.Printf("Failed to get time zone for pair %s: %v\n", pair.ID, err)
+			continue
+		}
+
+		for _, period := range recapPeriods {
+			previous := TruncateToBucket(time.Now().In(loc), period).AddDate(0, 0, -1)
+			if err := w.generate(pair, period, previous, loc); err != nil {
+				fmt.Printf("Failed to generate %s recap for pair %s: %v\n", period, pair.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *RecapWorker) generate(pair models.Pair, period string, day time.Time, loc *time.Location) error {
+	start := TruncateToBucket(day, period)
+	end := nextBucket(start, period)
+
+	// Пустые периоды отчетом не отмечаются; их можно запросить вручную
+	var hasEvents bool
+	err := w.db.QueryRow(
+		`SELECT EXISTS(SELECT 1 FROM love_events WHERE pair_id = $1 AND created_at >= $2 AND created_at < $3)
+			AND NOT EXISTS(SELECT 1 FROM love_recaps WHERE pair_id = $1 AND period = $4 AND period_start = $5)`,
+		pair.ID, start, end, period, start.Format("2006-01-02"),
+	).Scan(&hasEvents)
+	if err != nil || !hasEvents {
+		return err
+	}
+
+	recap, created, err := GenerateRecap(w.db, pair, period, day)
+	if err != nil || !created {
+		return err
+	}
+
+	SendRecapReadyNotification(w.db, pair.User1ID, recap)
+	SendRecapReadyNotification(w.db, pair.User2ID, recap)
+	return nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"love-connection/backend/internal/models"
+	"testing"
+	"time"
+)
+
+func TestIsRecapPeriod(t *testing.T) {
+	for _, period := range []string{models.StatsBucketWeek, models.StatsBucketMonth, models.StatsBucketYear} {
+		if !IsRecapPeriod(period) {
+			t.Errorf("IsRecapPeriod(%q) = false", period)
+		}
+	}
+	for _, period := range []string{"", models.StatsBucketDay, "hour", "Week"} {
+		if IsRecapPeriod(period) {
+			t.Errorf("IsRecapPeriod(%q) = true", period)
+		}
+	}
+
+	if _, _, err := GenerateRecap(nil, models.Pair{}, models.StatsBucketDay, time.Now()); err != ErrInvalidRecapPeriod {
+		t.Errorf("GenerateRecap(day): err = %v, want ErrInvalidRecapPeriod", err)
+	}
+}
+
+func TestGenerateRecap(t *testing.T) {
+	db := openTestDB(t)
+	pairID, user1ID, user2ID := createTestPair(t, db)
+	pair := models.Pair{ID: pairID, User1ID: user1ID, User2ID: user2ID}
+
+	start := TruncateToBucket(time.Now().UTC().AddDate(0, 0, -7), models.StatsBucketWeek)
+	insertTestLoveEvent(t, db, pairID, user1ID, 2, start.AddDate(0, 0, -1).Add(12*time.Hour))
+	insertTestLoveEvent(t, db, pairID, user1ID, 5, start.Add(10*time.Hour))
+	insertTestLoveEvent(t, db, pairID, user2ID, 3, start.Add(10*time.Hour+time.Minute))
+	insertTestLoveEvent(t, db, pairID, user1ID, 4, start.AddDate(0, 0, 2).Add(15*time.Hour))
+
+	if _, _, err := GenerateRecap(db, pair, models.StatsBucketWeek, time.Now()); err != ErrRecapPeriodNotOver {
+		t.Errorf("recap for the current week: err = %v, want ErrRecapPeriodNotOver", err)
+	}
+
+	recap, created, err := GenerateRecap(db, pair, models.StatsBucketWeek, start.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatalf("GenerateRecap: %v", err)
+	}
+	if !created || recap.PeriodStart != start.Format("2006-01-02") || recap.PeriodEnd != start.AddDate(0, 0, 7).Format("2006-01-02") || recap.Timezone != "UTC" {
+		t.Errorf("recap = %+v, created %v", recap, created)
+	}
+
+	report := recap.Report
+	if report.TotalHearts != 3 || report.TotalDurationSeconds != 12 || report.ActiveDays != 2 || len(report.Partners) != 2 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.LongestHold == nil || report.LongestHold.SenderID != user1ID || report.LongestHold.DurationSeconds != 5 {
+		t.Errorf("longest hold = %+v", report.LongestHold)
+	}
+	if report.BusiestHour == nil || *report.BusiestHour != 10 {
+		t.Errorf("busiest hour = %v, want 10", report.BusiestHour)
+	}
+	// Неделей раньше — одно сердечко, значит рост на 200%
+	if report.Previous.TotalHearts != 1 || report.Previous.HeartsChangePercent == nil || *report.Previous.HeartsChangePercent != 200 {
+		t.Errorf("previous = %+v", report.Previous)
+	}
+
+	again, created, err := GenerateRecap(db, pair, models.StatsBucketWeek, start)
+	if err != nil || created || again.ID != recap.ID {
+		t.Errorf("second GenerateRecap = %s, created %v, %v; want the existing recap", again.ID, created, err)
+	}
+
+	recaps, err := ListRecaps(db, pairID, models.StatsBucketWeek, 0)
+	if err != nil || len(recaps) != 1 || recaps[0].ID != recap.ID {
+		t.Errorf("ListRecaps = %v, %v", recaps, err)
+	}
+	if recaps, err := ListRecaps(db, pairID, models.StatsBucketMonth, 0); err != nil || len(recaps) != 0 {
+		t.Errorf("ListRecaps(month) = %v, %v", recaps, err)
+	}
+
+	if got, err := GetRecap(db, user2ID, recap.ID); err != nil || got.Report.TotalHearts != 3 {
+		t.Errorf("GetRecap by partner = %+v, %v", got, err)
+	}
+	if _, err := GetRecap(db, createTestUser(t, db, "UTC"), recap.ID); err != ErrRecapNotFound {
+		t.Errorf("GetRecap by outsider: err = %v, want ErrRecapNotFound", err)
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"love-connection/backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var ErrLoveEventNotFound = errors.New("love event not found")
+
+// UnreadCount считает полученные пользователем сердечки, которые он еще не просмотрел.
+func UnreadCount(db *sql.DB, userID uuid.UUID) (int, error) {
+	var count int
+	err := db.QueryRow(
+		`SELECT COUNT(*)
+		FROM love_events e
+		JOIN pairs p ON e.pair_id = p.id
+		WHERE (p.user1_id = $1 OR p.user2_id = $1) AND p.archived_at IS NULL
+			AND e.sender_id != $1
+			AND e.seen_at IS NULL`,
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+// MarkDelivered отмечает доставку события получателю (через websocket или пуш).
+func MarkDelivered(db *sql.DB, eventID uuid.UUID) error {
+	_, err := db.Exec(
+		"UPDATE love_events SET delivered_at = NOW() WHERE id = $1 AND delivered_at IS NULL",
+		eventID,
+	)
+	return err
+}
+
+// MarkSeen отмечает просмотренными все сердечки партнера до eventID включительно.
+// Возвращает событие love_seen для отправителя и ID отправителя.
+func MarkSeen(db *sql.DB, userID, eventID uuid.UUID) (models.LoveSeenEvent, uuid.UUID, error) {
+	seen := models.LoveSeenEvent{UpToEventID: eventID}
+
+	// БЕЗОПАСНОСТЬ: событие должно быть отправлено партнером в паре текущего пользователя
+	var pairID, senderID uuid.UUID
+	err := db.QueryRow(
+		`SELECT e.pair_id, e.sender_id
+		FROM love_events e
+		JOIN pairs p ON e.pair_id = p.id
+		WHERE e.id = $1 AND (p.user1_id = $2 OR p.user2_id = $2) AND p.archived_at IS NULL AND e.sender_id != $2`,
+		eventID, userID,
+	).Scan(&pairID, &senderID)
+	if err == sql.ErrNoRows {
+		return seen, uuid.Nil, ErrLoveEventNotFound
+	}
+	if err != nil {
+		return seen, uuid.Nil, err
+	}
+
+	err = db.QueryRow(
+		`WITH updated AS (
+			UPDATE love_events e
+			SET seen_at = NOW(), delivered_at = COALESCE(e.delivered_at, NOW())
+			FROM love_events target
+			WHERE target.id = $1
+				AND e.pair_id = $2
+				AND e.sender_id = $3
+				AND e.seen_at IS NULL
+				AND (e.created_at, e.id) <= (target.created_at, target.id)
+			RETURNING e.seen_at
+		)
+		SELECT COUNT(*), COALESCE(MAX(seen_at), NOW()) FROM updated`,
+		eventID, pairID, senderID,
+	).Scan(&seen.Count, &seen.SeenAt)
+	if err != nil {
+		return seen, uuid.Nil, err
+	}
+
+	return seen, senderID, nil
+}
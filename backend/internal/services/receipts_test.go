@@ -0,0 +1,103 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestReadReceipts(t *testing.T) {
+	db := openTestDB(t)
+	pairID, user1ID, user2ID := createTestPair(t, db)
+	outsiderID := createTestUser(t, db, "UTC")
+	now := time.Now().Truncate(time.Second)
+
+	var received []uuid.UUID
+	for i := 3; i >= 1; i-- {
+		received = append(received, insertTestLoveEvent(t, db, pairID, user1ID, 3, now.Add(-time.Duration(i)*time.Minute)).ID)
+	}
+	own := insertTestLoveEvent(t, db, pairID, user2ID, 3, now)
+
+	unread := func(userID uuid.UUID) int {
+		t.Helper()
+		count, err := UnreadCount(db, userID)
+		if err != nil {
+			t.Fatalf("UnreadCount: %v", err)
+		}
+		return count
+	}
+	if got := unread(user2ID); got != 3 {
+		t.Errorf("user2 unread = %d, want 3", got)
+	}
+	if got := unread(user1ID); got != 1 {
+		t.Errorf("user1 unread = %d, want 1", got)
+	}
+
+	// Просмотр отмечает все сердечки партнера до указанного включительно
+	seen, senderID, err := MarkSeen(db, user2ID, received[1])
+	if err != nil {
+		t.Fatalf("MarkSeen: %v", err)
+	}
+	if seen.Count != 2 || seen.UpToEventID != received[1] || senderID != user1ID {
+		t.Errorf("MarkSeen = %+v, sender %s; want 2 events up to %s from %s", seen, senderID, received[1], user1ID)
+	}
+	if got := unread(user2ID); got != 1 {
+		t.Errorf("user2 unread after MarkSeen = %d, want 1", got)
+	}
+
+	var delivered int
+	if err := db.QueryRow("SELECT COUNT(*) FROM love_events WHERE pair_id = $1 AND delivered_at IS NOT NULL", pairID).Scan(&delivered); err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 2 {
+		t.Errorf("%d events delivered, want the 2 seen ones", delivered)
+	}
+
+	// Повторный просмотр ничего не меняет
+	if seen, _, err := MarkSeen(db, user2ID, received[1]); err != nil || seen.Count != 0 {
+		t.Errorf("second MarkSeen = %+v, %v; want 0 events", seen, err)
+	}
+
+	// Свое сердечко и сердечко чужой пары отметить нельзя
+	for _, tt := range []struct {
+		name    string
+		userID  uuid.UUID
+		eventID uuid.UUID
+	}{
+		{"own event", user2ID, own.ID},
+		{"other pair", outsiderID, received[2]},
+		{"unknown event", user2ID, uuid.New()},
+	} {
+		if _, _, err := MarkSeen(db, tt.userID, tt.eventID); err != ErrLoveEventNotFound {
+			t.Errorf("%s: err = %v, want ErrLoveEventNotFound", tt.name, err)
+		}
+	}
+}
+
+func TestMarkDeliveredKeepsFirstDelivery(t *testing.T) {
+	db := openTestDB(t)
+	pairID, user1ID, _ := createTestPair(t, db)
+	event := insertTestLoveEvent(t, db, pairID, user1ID, 3, time.Now())
+
+	deliveredAt := func() time.Time {
+		t.Helper()
+		var at time.Time
+		if err := db.QueryRow("SELECT delivered_at FROM love_events WHERE id = $1", event.ID).Scan(&at); err != nil {
+			t.Fatal(err)
+		}
+		return at
+	}
+
+	if err := MarkDelivered(db, event.ID); err != nil {
+		t.Fatalf("MarkDelivered: %v", err)
+	}
+	first := deliveredAt()
+	time.Sleep(10 * time.Millisecond)
+	if err := MarkDelivered(db, event.ID); err != nil {
+		t.Fatalf("MarkDelivered: %v", err)
+	}
+	if again := deliveredAt(); !again.Equal(first) {
+		t.Errorf("delivered_at changed from %s to %s", first, again)
+	}
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const reminderPollInterval = time.Minute
+
+var (
+	ErrInvalidTimezone = errors.New("invalid time zone")
+	ErrInvalidTime     = errors.New("invalid time, expected HH:MM")
+)
+
+// LoadTimezone проверяет имя часового пояса IANA. "Local" (часовой пояс сервера) не принимается:
+// Postgres не знает такого имени, а оно попадает в AT TIME ZONE.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// ValidateTimezone — LoadTimezone плюс проверка, что имя есть в pg_timezone_names.
+// Часовые пояса пользователей используются в AT TIME ZONE, и имя, которое Postgres не знает,
+// ломало бы запросы. Вызывается перед сохранением часового пояса.
+func ValidateTimezone(db queryer, name string) (*time.Location, error) {
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var known bool
+	if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM pg_timezone_names WHERE name = $1)", name).Scan(&known); err != nil {
+		return nil, err
+	}
+	if !known {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// UserLocation возвращает часовой пояс пользователя (UTC, если он не задан или некорректен).
+func UserLocation(db queryer, userID uuid.UUID) (*time.Location, error) {
+	var name string
+	if err := db.QueryRow("SELECT timezone FROM users WHERE id = $1", userID).Scan(&name); err != nil {
+		return nil, err
+	}
+
+	loc, err := LoadTimezone(name)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+// localDayBounds возвращает начало и конец локального дня, в который попадает t.
+func localDayBounds(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := t.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+func GetReminderSettings(db *sql.DB, userID uuid.UUID) (models.ReminderSettings, error) {
+	settings := models.ReminderSettings{RemindAt: "20:00"}
+
+	err := db.QueryRow(
+		`SELECT u.timezone, COALESCE(r.enabled, FALSE), COALESCE(to_char(r.remind_at, 'HH24:MI'), '20:00'),
+			COALESCE(r.skip_if_partner_sent, FALSE)
+		FROM users u
+		LEFT JOIN reminder_settings r ON r.user_id = u.id
+		WHERE u.id = $1`,
+		userID,
+	).Scan(&settings.Timezone, &settings.Enabled, &settings.RemindAt, &settings.SkipIfPartnerSent)
+
+	return settings, err
+}
+
+// UpdateReminderSettings применяет переданные поля настроек. Часовой пояс хранится в users.timezone.
+func UpdateReminderSettings(db *sql.DB, userID uuid.UUID, req models.UpdateReminderSettingsRequest) (models.ReminderSettings, error) {
+	current, err := GetReminderSettings(db, userID)
+	if err != nil {
+		return current, err
+	}
+
+	if req.Enabled != nil {
+		current.Enabled = *req.Enabled
+	}
+	if req.SkipIfPartnerSent != nil {
+		current.SkipIfPartnerSent = *req.SkipIfPartnerSent
+	}
+	if req.RemindAt != nil {
+		remindAt, err := time.Parse("15:04", *req.RemindAt)
+		if err != nil {
+			return current, ErrInvalidTime
+		}
+		current.RemindAt = remindAt.Format("15:04")
+	}
+	if req.Timezone != nil {
+		if _, err := ValidateTimezone(db, *req.Timezone); err != nil {
+			return current, err
+		}
+		current.Timezone = *req.Timezone
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return current, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE users SET timezone = $1 WHERE id = $2", current.Timezone, userID); err != nil {
+		return current, err
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO reminder_settings (user_id, enabled, remind_at, skip_if_partner_sent)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE SET
+			enabled = EXCLUDED.enabled,
+			remind_at = EXCLUDED.remind_at,
+			skip_if_partner_sent = EXCLUDED.skip_if_partner_sent,
+			updated_at = NOW()`,
+		userID, current.Enabled, current.RemindAt, current.SkipIfPartnerSent,
+	)
+	if err != nil {
+		return current, err
+	}
+
+	return current, tx.Commit()
+}
+
+// ReminderScheduler раз в минуту напоминает отправить сердечко тем, кто сегодня (по своему
+// локальному времени) еще не отправлял. День напоминания сначала занимается в reminder_settings.last_sent_on,
+// поэтому рестарт или несколько инстансов не приводят к повторной отправке.
+type ReminderScheduler struct {
+	db *sql.DB
+}
+
+func NewReminderScheduler(db *sql.DB) *ReminderScheduler {
+	return &ReminderScheduler{db: db}
+}
+
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(reminderPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sendDue(ctx); err != nil {
+			fmt.Printf("Failed to send reminders: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type dueReminder struct {
+	userID            uuid.UUID
+	timezone          string
+	localDay          time.Time
+	skipIfPartnerSent bool
+}
+
+func (s *ReminderScheduler) sendDue(ctx context.Context) error {
+	// JOIN с pg_timezone_names пропускает пользователей с часовым поясом, которого не знает Postgres,
+	// чтобы одна такая строка не ломала запрос для всех
+	rows, err := s.db.Query(
+		`SELECT r.user_id, tz.name, (NOW() AT TIME ZONE tz.name)::date, r.skip_if_partner_sent
+		FROM reminder_settings r
+		JOIN users u ON u.id = r.user_id
+		JOIN pg_timezone_names tz ON tz.name = u.timezone
+		WHERE r.enabled
+			AND (NOW() AT TIME ZONE tz.name)::time >= r.remind_at
+			AND (r.last_sent_on IS NULL OR r.last_sent_on < (NOW() AT TIME ZONE tz.name)::date)`,
+	)
+	if err != nil {
+		return err
+	}
+
+	var due []dueReminder
+	for rows.Next() {
+		var r dueReminder
+		if err := rows.Scan(&r.userID, &r.timezone, &r.localDay, &r.skipIfPartnerSent); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, r := range due {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err := s.remind(r); err != nil {
+			fmt.Printf("Failed to send reminder to user %s: %v\n", r.userID, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *ReminderScheduler) remind(r dueReminder) error {
+	// Занимаем день до отправки: если строка уже обновлена другим инстансом, ничего не делаем
+	localDay := r.localDay.Format("2006-01-02")
+	result, err := s.db.Exec(
+		`UPDATE reminder_settings SET last_sent_on = $2
+		WHERE user_id = $1 AND (last_sent_on IS NULL OR last_sent_on < $2)`,
+		r.userID, localDay,
+	)
+	if err != nil {
+		return err
+	}
+	if claimed, err := result.RowsAffected(); err != nil || claimed == 0 {
+		return err
+	}
+
+	pairID, partnerID, err := FindPartner(s.db, r.userID)
+	if err == ErrNoPair {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	loc, err := LoadTimezone(r.timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	dayStart, dayEnd := localDayBounds(time.Now(), loc)
+
+	var sentToday, partnerSentToday bool
+	err = s.db.QueryRow(
+		`SELECT
+			EXISTS(SELECT 1 FROM love_events WHERE pair_id = $1 AND sender_id = $2 AND created_at >= $4 AND created_at < $5),
+			EXISTS(SELECT 1 FROM love_events WHERE pair_id = $1 AND sender_id = $3 AND created_at >= $4 AND created_at < $5)`,
+		pairID, r.userID, partnerID, dayStart, dayEnd,
+	).Scan(&sentToday, &partnerSentToday)
+	if err != nil {
+		return err
+	}
+
+	if sentToday || (r.skipIfPartnerSent && partnerSentToday) {
+		return nil
+	}
+
+	var partnerUsername string
+	if err := s.db.QueryRow("SELECT username FROM users WHERE id = $1", partnerID).Scan(&partnerUsername); err != nil {
+		return err
+	}
+
+	SendReminderNotification(s.db, r.userID, partnerUsername)
+	return nil
+}
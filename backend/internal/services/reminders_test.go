@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLoadTimezone(t *testing.T) {
+	for name, wantErr := range map[string]bool{
+		"Europe/Moscow":    false,
+		"America/New_York": false,
+		"UTC":              false,
+		"":                 true,
+		"Local":            true,
+		"Mars/Olympus":     true,
+	} {
+		loc, err := LoadTimezone(name)
+		if wantErr {
+			if err != ErrInvalidTimezone {
+				t.Errorf("LoadTimezone(%q) = %v, %v; want ErrInvalidTimezone", name, loc, err)
+			}
+			continue
+		}
+		if err != nil || loc.String() != name {
+			t.Errorf("LoadTimezone(%q) = %v, %v", name, loc, err)
+		}
+	}
+}
+
+func TestLocalDayBounds(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		t      time.Time
+		length time.Duration
+	}{
+		{"regular day", time.Date(2024, 6, 1, 15, 0, 0, 0, newYork), 24 * time.Hour},
+		{"spring forward", time.Date(2024, 3, 10, 12, 0, 0, 0, newYork), 23 * time.Hour},
+		{"fall back", time.Date(2024, 11, 3, 12, 0, 0, 0, newYork), 25 * time.Hour},
+		// 03:30 UTC — еще предыдущий день в Нью-Йорке
+		{"utc after local midnight", time.Date(2024, 6, 2, 3, 30, 0, 0, time.UTC), 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		start, end := localDayBounds(tt.t, newYork)
+		local := tt.t.In(newYork)
+		if start.In(newYork).Hour() != 0 || start.In(newYork).Day() != local.Day() {
+			t.Errorf("%s: start = %v, want local midnight of %v", tt.name, start, local)
+		}
+		if got := end.Sub(start); got != tt.length {
+			t.Errorf("%s: day is %v long, want %v", tt.name, got, tt.length)
+		}
+		if tt.t.Before(start) || !tt.t.Before(end) {
+			t.Errorf("%s: %v is outside [%v, %v)", tt.name, tt.t, start, end)
+		}
+	}
+}
+
+func TestValidateTimezone(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := ValidateTimezone(db, "Europe/Moscow"); err != nil {
+		t.Errorf("Europe/Moscow: %v", err)
+	}
+	for _, name := range []string{"Local", "", "Mars/Olympus"} {
+		if _, err := ValidateTimezone(db, name); err != ErrInvalidTimezone {
+			t.Errorf("%q: err = %v, want ErrInvalidTimezone", name, err)
+		}
+	}
+}
+
+// Часовой пояс, которого не знает Postgres, не должен ломать запросы планировщиков для остальных.
+func TestSchedulersSkipUnknownTimezones(t *testing.T) {
+	db := openTestDB(t)
+
+	// Так мог сохраниться часовой пояс до проверки по pg_timezone_names
+	userID := createTestUser(t, db, "Local")
+	for _, query := range []string{
+		"INSERT INTO reminder_settings (user_id, enabled, remind_at) VALUES ($1, TRUE, '00:00')",
+		"INSERT INTO memory_settings (user_id, push_enabled, push_at) VALUES ($1, TRUE, '00:00')",
+		"INSERT INTO love_events (sender_id, duration_seconds) VALUES ($1, 5)",
+	} {
+		if _, err := db.Exec(query, userID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// С отмененным контекстом планировщики только выбирают кандидатов, ничего не отправляя
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := NewReminderScheduler(db).sendDue(ctx); err != nil {
+		t.Errorf("reminders: %v", err)
+	}
+	if err := NewMemoryScheduler(db).sendDue(ctx); err != nil {
+		t.Errorf("memories: %v", err)
+	}
+	if err := NewStreakWatcher(db).warnAtRisk(ctx); err != nil {
+		t.Errorf("streaks: %v", err)
+	}
+}
@@ -0,0 +1,413 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	rollupCatchUpInterval = 30 * time.Second
+	rollupCatchUpBatch    = 500
+)
+
+// applyRollup учитывает событие в дневных и часовых агрегатах, если оно еще не учтено.
+// Вызывается в той же транзакции, что и вставка события.
+func applyRollup(tx queryer, eventID uuid.UUID) error {
+	var senderID uuid.UUID
+	var pairID uuid.NullUUID
+	var durationSeconds int
+	var createdAt time.Time
+	err := tx.QueryRow(
+		`UPDATE love_events SET rolled_up = TRUE
+		WHERE id = $1 AND NOT rolled_up
+		RETURNING sender_id, pair_id, duration_seconds, created_at`,
+		eventID,
+	).Scan(&senderID, &pairID, &durationSeconds, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	day := createdAt.UTC().Format("2006-01-02")
+	hour := createdAt.UTC().Truncate(time.Hour)
+
+	_, err = tx.Exec(
+		`INSERT INTO love_user_hourly_rollups AS r (user_id, hour, event_count, total_duration_seconds)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (user_id, hour) DO UPDATE SET
+			event_count = r.event_count + 1,
+			total_duration_seconds = r.total_duration_seconds + EXCLUDED.total_duration_seconds`,
+		senderID, hour, durationSeconds,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO love_user_daily_rollups AS r (user_id, day, event_count, total_duration_seconds, max_duration_seconds)
+		VALUES ($1, $2, 1, $3, $3)
+		ON CONFLICT (user_id, day) DO UPDATE SET
+			event_count = r.event_count + 1,
+			total_duration_seconds = r.total_duration_seconds + EXCLUDED.total_duration_seconds,
+			max_duration_seconds = GREATEST(r.max_duration_seconds, EXCLUDED.max_duration_seconds)`,
+		senderID, day, durationSeconds,
+	)
+	if err != nil {
+		return err
+	}
+
+	if !pairID.Valid {
+		return nil
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO love_pair_hourly_rollups AS r (pair_id, hour, event_count, total_duration_seconds)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (pair_id, hour) DO UPDATE SET
+			event_count = r.event_count + 1,
+			total_duration_seconds = r.total_duration_seconds + EXCLUDED.total_duration_seconds`,
+		pairID.UUID, hour, durationSeconds,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`INSERT INTO love_pair_daily_rollups AS r
+			(pair_id, sender_id, day, event_count, total_duration_seconds, max_duration_seconds, first_event_at, last_event_at)
+		VALUES ($1, $2, $3, 1, $4, $4, $5, $5)
+		ON CONFLICT (pair_id, sender_id, day) DO UPDATE SET
+			event_count = r.event_count + 1,
+			total_duration_seconds = r.total_duration_seconds + EXCLUDED.total_duration_seconds,
+			max_duration_seconds = GREATEST(r.max_duration_seconds, EXCLUDED.max_duration_seconds),
+			first_event_at = LEAST(r.first_event_at, EXCLUDED.first_event_at),
+			last_event_at = GREATEST(r.last_event_at, EXCLUDED.last_event_at)`,
+		pairID.UUID, senderID, day, durationSeconds, createdAt,
+	)
+	return err
+}
+
+// removeRollup вычитает удаленное событие из дневных и часовых агрегатов. Вызывается после DELETE
+// в той же транзакции; максимум и границы дня пересчитываются по оставшимся событиям.
+func removeRollup(tx queryer, senderID uuid.UUID, pairID uuid.NullUUID, durationSeconds int, createdAt time.Time) error {
+	dayStart := createdAt.UTC().Truncate(24 * time.Hour)
+	dayEnd := dayStart.Add(24 * time.Hour)
+	day := dayStart.Format("2006-01-02")
+	hour := createdAt.UTC().Truncate(time.Hour)
+
+	_, err := tx.Exec(
+		`UPDATE love_user_hourly_rollups SET
+			event_count = event_count - 1,
+			total_duration_seconds = total_duration_seconds - $3
+		WHERE user_id = $1 AND hour = $2`,
+		senderID, hour, durationSeconds,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM love_user_hourly_rollups WHERE user_id = $1 AND hour = $2 AND event_count <= 0", senderID, hour)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE love_user_daily_rollups SET
+			event_count = event_count - 1,
+			total_duration_seconds = total_duration_seconds - $3,
+			max_duration_seconds = (
+				SELECT COALESCE(MAX(duration_seconds), 0) FROM love_events
+				WHERE sender_id = $1 AND rolled_up AND created_at >= $4 AND created_at < $5
+			)
+		WHERE user_id = $1 AND day = $2`,
+		senderID, day, durationSeconds, dayStart, dayEnd,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM love_user_daily_rollups WHERE user_id = $1 AND day = $2 AND event_count <= 0", senderID, day)
+	if err != nil {
+		return err
+	}
+
+	if !pairID.Valid {
+		return nil
+	}
+
+	_, err = tx.Exec(
+		`UPDATE love_pair_hourly_rollups SET
+			event_count = event_count - 1,
+			total_duration_seconds = total_duration_seconds - $3
+		WHERE pair_id = $1 AND hour = $2`,
+		pairID.UUID, hour, durationSeconds,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM love_pair_hourly_rollups WHERE pair_id = $1 AND hour = $2 AND event_count <= 0", pairID.UUID, hour)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		`UPDATE love_pair_daily_rollups r SET
+			event_count = r.event_count - 1,
+			total_duration_seconds = r.total_duration_seconds - $4,
+			max_duration_seconds = COALESCE(remaining.max_duration, 0),
+			first_event_at = remaining.first_at,
+			last_event_at = remaining.last_at
+		FROM (
+			SELECT MAX(duration_seconds) AS max_duration, MIN(created_at) AS first_at, MAX(created_at) AS last_at
+			FROM love_events
+			WHERE pair_id = $1 AND sender_id = $2 AND rolled_up AND created_at >= $5 AND created_at < $6
+		) remaining
+		WHERE r.pair_id = $1 AND r.sender_id = $2 AND r.day = $3`,
+		pairID.UUID, senderID, day, durationSeconds, dayStart, dayEnd,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"DELETE FROM love_pair_daily_rollups WHERE pair_id = $1 AND sender_id = $2 AND day = $3 AND event_count <= 0",
+		pairID.UUID, senderID, day,
+	)
+	return err
+}
+
+// RollupWorker учитывает в агрегатах события, вставленные в обход CreateLoveEvent
+// (например, SQL-скриптами), и события, оставшиеся после сбоя.
+type RollupWorker struct {
+	db *sql.DB
+}
+
+func NewRollupWorker(db *sql.DB) *RollupWorker {
+	return &RollupWorker{db: db}
+}
+
+func (w *RollupWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(rollupCatchUpInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			applied, err := w.catchUp()
+			if err != nil {
+				fmt.Printf("Failed to catch up stats rollups: %v\n", err)
+				break
+			}
+			if applied < rollupCatchUpBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *RollupWorker) catchUp() (int, error) {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		`SELECT id FROM love_events
+		WHERE NOT rolled_up
+		ORDER BY created_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`,
+		rollupCatchUpBatch,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range ids {
+		if err := applyRollup(tx, id); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(ids), tx.Commit()
+}
+
+// RebuildRollups пересчитывает все агрегаты с нуля по love_events.
+// Запись в love_events блокируется на время пересчета.
+func RebuildRollups(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"LOCK TABLE love_events IN SHARE ROW EXCLUSIVE MODE",
+		"DELETE FROM love_user_daily_rollups",
+		"DELETE FROM love_pair_daily_rollups",
+		"DELETE FROM love_user_hourly_rollups",
+		"DELETE FROM love_pair_hourly_rollups",
+		`INSERT INTO love_user_daily_rollups (user_id, day, event_count, total_duration_seconds, max_duration_seconds)
+		SELECT sender_id, (created_at AT TIME ZONE 'UTC')::date, COUNT(*), SUM(duration_seconds), MAX(duration_seconds)
+		FROM love_events
+		GROUP BY 1, 2`,
+		`INSERT INTO love_pair_daily_rollups
+			(pair_id, sender_id, day, event_count, total_duration_seconds, max_duration_seconds, first_event_at, last_event_at)
+		SELECT pair_id, sender_id, (created_at AT TIME ZONE 'UTC')::date, COUNT(*), SUM(duration_seconds),
+			MAX(duration_seconds), MIN(created_at), MAX(created_at)
+		FROM love_events
+		WHERE pair_id IS NOT NULL
+		GROUP BY 1, 2, 3`,
+		`INSERT INTO love_user_hourly_rollups (user_id, hour, event_count, total_duration_seconds)
+		SELECT sender_id, date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC', COUNT(*), SUM(duration_seconds)
+		FROM love_events
+		GROUP BY 1, 2`,
+		`INSERT INTO love_pair_hourly_rollups (pair_id, hour, event_count, total_duration_seconds)
+		SELECT pair_id, date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC', COUNT(*), SUM(duration_seconds)
+		FROM love_events
+		WHERE pair_id IS NOT NULL
+		GROUP BY 1, 2`,
+		"UPDATE love_events SET rolled_up = TRUE WHERE NOT rolled_up",
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// VerifyRollups сравнивает агрегаты с сырыми данными love_events и возвращает описания расхождений.
+// События, еще не учтенные catch-up воркером, тоже считаются расхождением.
+func VerifyRollups(db *sql.DB) ([]string, error) {
+	checks := []struct {
+		name  string
+		query string
+	}{
+		{
+			name: "user",
+			query: `WITH raw AS (
+				SELECT sender_id AS user_id, (created_at AT TIME ZONE 'UTC')::date AS day,
+					COUNT(*) AS event_count, SUM(duration_seconds) AS total, MAX(duration_seconds) AS max_duration
+				FROM love_events
+				GROUP BY 1, 2
+			)
+			SELECT COALESCE(raw.user_id, r.user_id)::text || ' ' || to_char(COALESCE(raw.day, r.day), 'YYYY-MM-DD')
+				|| ': raw ' || COALESCE(raw.event_count, 0) || '/' || COALESCE(raw.total, 0) || '/' || COALESCE(raw.max_duration, 0)
+				|| ', rollup ' || COALESCE(r.event_count, 0) || '/' || COALESCE(r.total_duration_seconds, 0) || '/' || COALESCE(r.max_duration_seconds, 0)
+			FROM raw
+			FULL OUTER JOIN love_user_daily_rollups r ON r.user_id = raw.user_id AND r.day = raw.day
+			WHERE raw.event_count IS DISTINCT FROM r.event_count
+				OR raw.total IS DISTINCT FROM r.total_duration_seconds
+				OR raw.max_duration IS DISTINCT FROM r.max_duration_seconds`,
+		},
+		{
+			name: "pair",
+			query: `WITH raw AS (
+				SELECT pair_id, sender_id, (created_at AT TIME ZONE 'UTC')::date AS day,
+					COUNT(*) AS event_count, SUM(duration_seconds) AS total, MAX(duration_seconds) AS max_duration,
+					MIN(created_at) AS first_at, MAX(created_at) AS last_at
+				FROM love_events
+				WHERE pair_id IS NOT NULL
+				GROUP BY 1, 2, 3
+			)
+			SELECT COALESCE(raw.pair_id, r.pair_id)::text || ' ' || COALESCE(raw.sender_id, r.sender_id)::text
+				|| ' ' || to_char(COALESCE(raw.day, r.day), 'YYYY-MM-DD')
+				|| ': raw ' || COALESCE(raw.event_count, 0) || '/' || COALESCE(raw.total, 0) || '/' || COALESCE(raw.max_duration, 0)
+				|| ', rollup ' || COALESCE(r.event_count, 0) || '/' || COALESCE(r.total_duration_seconds, 0) || '/' || COALESCE(r.max_duration_seconds, 0)
+			FROM raw
+			FULL OUTER JOIN love_pair_daily_rollups r
+				ON r.pair_id = raw.pair_id AND r.sender_id = raw.sender_id AND r.day = raw.day
+			WHERE raw.event_count IS DISTINCT FROM r.event_count
+				OR raw.total IS DISTINCT FROM r.total_duration_seconds
+				OR raw.max_duration IS DISTINCT FROM r.max_duration_seconds
+				OR raw.first_at IS DISTINCT FROM r.first_event_at
+				OR raw.last_at IS DISTINCT FROM r.last_event_at`,
+		},
+		{
+			name: "user hourly",
+			query: `WITH raw AS (
+				SELECT sender_id AS user_id, date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS hour,
+					COUNT(*) AS event_count, SUM(duration_seconds) AS total
+				FROM love_events
+				GROUP BY 1, 2
+			)
+			SELECT COALESCE(raw.user_id, r.user_id)::text || ' ' || to_char(COALESCE(raw.hour, r.hour) AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:00')
+				|| ': raw ' || COALESCE(raw.event_count, 0) || '/' || COALESCE(raw.total, 0)
+				|| ', rollup ' || COALESCE(r.event_count, 0) || '/' || COALESCE(r.total_duration_seconds, 0)
+			FROM raw
+			FULL OUTER JOIN love_user_hourly_rollups r ON r.user_id = raw.user_id AND r.hour = raw.hour
+			WHERE raw.event_count IS DISTINCT FROM r.event_count
+				OR raw.total IS DISTINCT FROM r.total_duration_seconds`,
+		},
+		{
+			name: "pair hourly",
+			query: `WITH raw AS (
+				SELECT pair_id, date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS hour,
+					COUNT(*) AS event_count, SUM(duration_seconds) AS total
+				FROM love_events
+				WHERE pair_id IS NOT NULL
+				GROUP BY 1, 2
+			)
+			SELECT COALESCE(raw.pair_id, r.pair_id)::text || ' ' || to_char(COALESCE(raw.hour, r.hour) AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:00')
+				|| ': raw ' || COALESCE(raw.event_count, 0) || '/' || COALESCE(raw.total, 0)
+				|| ', rollup ' || COALESCE(r.event_count, 0) || '/' || COALESCE(r.total_duration_seconds, 0)
+			FROM raw
+			FULL OUTER JOIN love_pair_hourly_rollups r ON r.pair_id = raw.pair_id AND r.hour = raw.hour
+			WHERE raw.event_count IS DISTINCT FROM r.event_count
+				OR raw.total IS DISTINCT FROM r.total_duration_seconds`,
+		},
+	}
+
+	var mismatches []string
+	for _, check := range checks {
+		rows, err := db.Query(check.query)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var mismatch string
+			if err := rows.Scan(&mismatch); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			mismatches = append(mismatches, check.name+" "+mismatch)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return mismatches, nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const scheduledLovePollInterval = 10 * time.Second
+
+var (
+	ErrScheduledLoveNotFound = errors.New("scheduled love not found")
+	ErrDeliverAtInPast       = errors.New("deliver_at must be in the future")
+)
+
+// ScheduleLove сохраняет сердечко для доставки партнеру в момент deliverAt.
+func ScheduleLove(db *sql.DB, senderID uuid.UUID, durationSeconds int, deliverAt time.Time) (models.ScheduledLove, error) {
+	var scheduled models.ScheduledLove
+
+	if !deliverAt.After(time.Now()) {
+		return scheduled, ErrDeliverAtInPast
+	}
+
+	pairID, _, err := FindPartner(db, senderID)
+	if err != nil {
+		return scheduled, err
+	}
+
+	err = db.QueryRow(
+		`INSERT INTO scheduled_loves (sender_id, pair_id, duration_seconds, deliver_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, sender_id, pair_id, duration_seconds, deliver_at, status, love_event_id, created_at, updated_at`,
+		senderID, pairID, durationSeconds, deliverAt,
+	).Scan(
+		&scheduled.ID, &scheduled.SenderID, &scheduled.PairID, &scheduled.DurationSeconds, &scheduled.DeliverAt,
+		&scheduled.Status, &scheduled.LoveEventID, &scheduled.CreatedAt, &scheduled.UpdatedAt,
+	)
+	return scheduled, err
+}
+
+// ListScheduledLoves возвращает ожидающие доставки сердечки отправителя в порядке доставки.
+func ListScheduledLoves(db *sql.DB, senderID uuid.UUID) ([]models.ScheduledLove, error) {
+	rows, err := db.Query(
+		`SELECT id, sender_id, pair_id, duration_seconds, deliver_at, status, love_event_id, created_at, updated_at
+		FROM scheduled_loves
+		WHERE sender_id = $1 AND status = 'pending'
+		ORDER BY deliver_at`,
+		senderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scheduled := make([]models.ScheduledLove, 0)
+	for rows.Next() {
+		var s models.ScheduledLove
+		err := rows.Scan(
+			&s.ID, &s.SenderID, &s.PairID, &s.DurationSeconds, &s.DeliverAt,
+			&s.Status, &s.LoveEventID, &s.CreatedAt, &s.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, s)
+	}
+
+	return scheduled, rows.Err()
+}
+
+// CancelScheduledLove отменяет еще не доставленное сердечко отправителя.
+func CancelScheduledLove(db *sql.DB, senderID, scheduledID uuid.UUID) error {
+	result, err := db.Exec(
+		`UPDATE scheduled_loves SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND sender_id = $2 AND status = 'pending'`,
+		scheduledID, senderID,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrScheduledLoveNotFound
+	}
+
+	return nil
+}
+
+// LoveScheduler доставляет запланированные сердечки. Состояние хранится в scheduled_loves,
+// поэтому доставка переживает рестарт, а FOR UPDATE SKIP LOCKED не дает нескольким
+// инстансам доставить одно сердечко дважды.
+type LoveScheduler struct {
+	db          *sql.DB
+	broadcaster Broadcaster
+}
+
+func NewLoveScheduler(db *sql.DB, broadcaster Broadcaster) *LoveScheduler {
+	return &LoveScheduler{db: db, broadcaster: broadcaster}
+}
+
+func (s *LoveScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(scheduledLovePollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *LoveScheduler) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivered, err := s.deliverNext()
+		if err != nil {
+			fmt.Printf("Failed to deliver scheduled love: %v\n", err)
+			return
+		}
+		if !delivered {
+			return
+		}
+	}
+}
+
+// deliverNext доставляет одно созревшее сердечко. Возвращает false, если доставлять нечего.
+func (s *LoveScheduler) deliverNext() (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var scheduled models.ScheduledLove
+	err = tx.QueryRow(
+		`SELECT id, sender_id, pair_id, duration_seconds
+		FROM scheduled_loves
+		WHERE status = 'pending' AND deliver_at <= NOW()
+		ORDER BY deliver_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`,
+	).Scan(&scheduled.ID, &scheduled.SenderID, &scheduled.PairID, &scheduled.DurationSeconds)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// Пара могла распасться после планирования — тогда сердечко не доставляется
+	pairID, partnerID, err := FindPartner(tx, scheduled.SenderID)
+	if err != nil && err != ErrNoPair {
+		return false, err
+	}
+	if err == ErrNoPair || pairID != scheduled.PairID {
+		_, err = tx.Exec(
+			"UPDATE scheduled_loves SET status = 'failed', updated_at = NOW() WHERE id = $1",
+			scheduled.ID,
+		)
+		if err != nil {
+			return false, err
+		}
+		return true, tx.Commit()
+	}
+
+	eventID, err := insertLoveEvent(tx, pairID, scheduled.SenderID, partnerID, scheduled.DurationSeconds, models.LoveEventTypeScheduled)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE scheduled_loves SET status = 'delivered', love_event_id = $1, updated_at = NOW() WHERE id = $2",
+		eventID, scheduled.ID,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	event, err := GetLoveEvent(s.db, eventID)
+	if err != nil {
+		return true, err
+	}
+
+	DeliverLoveEvent(s.db, s.broadcaster, event, partnerID)
+	return true, nil
+}
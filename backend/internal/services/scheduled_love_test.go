@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"love-connection/backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// offlineBroadcaster — хаб, в котором никто не подключен.
+type offlineBroadcaster struct{}
+
+func (offlineBroadcaster) SendToUser(uuid.UUID, string, interface{}) bool { return false }
+
+func TestScheduleLoveRejectsPast(t *testing.T) {
+	for _, deliverAt := range []time.Time{time.Now(), time.Now().Add(-time.Minute)} {
+		if _, err := ScheduleLove(nil, uuid.New(), 3, deliverAt); err != ErrDeliverAtInPast {
+			t.Errorf("deliver at %s: err = %v, want ErrDeliverAtInPast", deliverAt, err)
+		}
+	}
+}
+
+func TestScheduledLoveLifecycle(t *testing.T) {
+	db := openTestDB(t)
+	pairID, user1ID, user2ID := createTestPair(t, db)
+
+	later, err := ScheduleLove(db, user1ID, 5, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ScheduleLove: %v", err)
+	}
+	cancelled, err := ScheduleLove(db, user1ID, 4, time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("ScheduleLove: %v", err)
+	}
+	if later.PairID != pairID || later.Status != models.ScheduledLoveStatusPending {
+		t.Errorf("scheduled = %+v, want pending in pair %s", later, pairID)
+	}
+
+	// Отменить можно только свое и только один раз
+	if err := CancelScheduledLove(db, user2ID, cancelled.ID); err != ErrScheduledLoveNotFound {
+		t.Errorf("partner cancel: err = %v, want ErrScheduledLoveNotFound", err)
+	}
+	if err := CancelScheduledLove(db, user1ID, cancelled.ID); err != nil {
+		t.Fatalf("CancelScheduledLove: %v", err)
+	}
+	if err := CancelScheduledLove(db, user1ID, cancelled.ID); err != ErrScheduledLoveNotFound {
+		t.Errorf("second cancel: err = %v, want ErrScheduledLoveNotFound", err)
+	}
+
+	pending, err := ListScheduledLoves(db, user1ID)
+	if err != nil {
+		t.Fatalf("ListScheduledLoves: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != later.ID {
+		t.Errorf("pending = %+v, want only %s", pending, later.ID)
+	}
+
+	// Время пришло: сердечко доставляется как событие типа scheduled
+	if _, err := db.Exec("UPDATE scheduled_loves SET deliver_at = NOW() - INTERVAL '1 second' WHERE id = $1", later.ID); err != nil {
+		t.Fatal(err)
+	}
+	NewLoveScheduler(db, offlineBroadcaster{}).deliverDue(context.Background())
+
+	var status string
+	var eventID *uuid.UUID
+	if err := db.QueryRow("SELECT status, love_event_id FROM scheduled_loves WHERE id = $1", later.ID).Scan(&status, &eventID); err != nil {
+		t.Fatal(err)
+	}
+	if status != models.ScheduledLoveStatusDelivered || eventID == nil {
+		t.Fatalf("status %s, event %v; want delivered with an event", status, eventID)
+	}
+	event, err := GetLoveEvent(db, *eventID)
+	if err != nil {
+		t.Fatalf("GetLoveEvent: %v", err)
+	}
+	if event.EventType != models.LoveEventTypeScheduled || event.SenderID != user1ID || event.DurationSeconds != 5 {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+// Если пара распалась до срока доставки, сердечко не доставляется.
+func TestScheduledLoveFailsWithoutPair(t *testing.T) {
+	db := openTestDB(t)
+	pairID, user1ID, _ := createTestPair(t, db)
+
+	scheduled, err := ScheduleLove(db, user1ID, 5, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ScheduleLove: %v", err)
+	}
+	if _, err := db.Exec("UPDATE pairs SET archived_at = NOW() WHERE id = $1", pairID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("UPDATE scheduled_loves SET deliver_at = NOW() - INTERVAL '1 second' WHERE id = $1", scheduled.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	NewLoveScheduler(db, offlineBroadcaster{}).deliverDue(context.Background())
+
+	var status string
+	if err := db.QueryRow("SELECT status FROM scheduled_loves WHERE id = $1", scheduled.ID).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status != models.ScheduledLoveStatusFailed {
+		t.Errorf("status = %s, want failed", status)
+	}
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"database/sql"
+	"love-connection/backend/internal/models"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResolvePair возвращает пару пользователя: указанную (в том числе архивную) или текущую.
+// Возвращает ErrNoPair, если пользователь не состоит в паре.
+func ResolvePair(db *sql.DB, userID uuid.UUID, pairID *uuid.UUID) (models.Pair, error) {
+	var pair models.Pair
+
+	query := `SELECT id, user1_id, user2_id, created_at, archived_at FROM pairs
+		WHERE (user1_id = $1 OR user2_id = $1) AND archived_at IS NULL`
+	args := []interface{}{userID}
+	if pairID != nil {
+		query = `SELECT id, user1_id, user2_id, created_at, archived_at FROM pairs
+		WHERE (user1_id = $1 OR user2_id = $1) AND id = $2`
+		args = append(args, *pairID)
+	}
+
+	err := db.QueryRow(query, args...).Scan(&pair.ID, &pair.User1ID, &pair.User2ID, &pair.CreatedAt, &pair.ArchivedAt)
+	if err == sql.ErrNoRows {
+		return pair, ErrNoPair
+	}
+	return pair, err
+}
+
+// GetPairStats считает отправленные и полученные сердечки в паре для userID.
+func GetPairStats(db *sql.DB, pair models.Pair, userID uuid.UUID) (models.PairStats, error) {
+	stats := models.PairStats{
+		PairID:        pair.ID,
+		PairCreatedAt: pair.CreatedAt,
+		ArchivedAt:    pair.ArchivedAt,
+	}
+
+	partnerID := pair.User1ID
+	if partnerID == userID {
+		partnerID = pair.User2ID
+	}
+
+	rows, err := db.Query(
+		`SELECT u.id, u.username,
+			COALESCE(SUM(r.event_count), 0),
+			COALESCE(SUM(r.total_duration_seconds), 0),
+			COALESCE(SUM(r.total_duration_seconds)::float8 / NULLIF(SUM(r.event_count), 0), 0),
+			COALESCE(MAX(r.max_duration_seconds), 0),
+			MIN(r.first_event_at),
+			MAX(r.last_event_at)
+		FROM users u
+		LEFT JOIN (
+			SELECT sender_id, event_count, total_duration_seconds, max_duration_seconds, first_event_at, last_event_at
+			FROM love_pair_daily_rollups
+			WHERE pair_id = $1
+			UNION ALL
+			-- События, которые catch-up воркер еще не учел в агрегатах
+			SELECT sender_id, 1, duration_seconds, duration_seconds, created_at, created_at
+			FROM love_events
+			WHERE pair_id = $1 AND NOT rolled_up
+		) r ON r.sender_id = u.id
+		WHERE u.id IN ($2, $3)
+		GROUP BY u.id, u.username`,
+		pair.ID, userID, partnerID,
+	)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ps models.PartnerStats
+		err := rows.Scan(
+			&ps.UserID, &ps.Username, &ps.TotalEvents, &ps.TotalDurationSeconds,
+			&ps.AverageDurationSeconds, &ps.LongestHoldSeconds, &ps.FirstEventAt, &ps.LastEventAt,
+		)
+		if err != nil {
+			return stats, err
+		}
+
+		if ps.UserID == userID {
+			stats.Me = ps
+		} else {
+			stats.Partner = ps
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	stats.TotalEvents = stats.Me.TotalEvents + stats.Partner.TotalEvents
+	stats.TotalDurationSeconds = stats.Me.TotalDurationSeconds + stats.Partner.TotalDurationSeconds
+	stats.FirstEventAt = earliest(stats.Me.FirstEventAt, stats.Partner.FirstEventAt)
+	stats.LastEventAt = latest(stats.Me.LastEventAt, stats.Partner.LastEventAt)
+
+	if stats.Partner.TotalEvents > 0 {
+		ratio := float64(stats.Me.TotalEvents) / float64(stats.Partner.TotalEvents)
+		stats.SentReceivedRatio = &ratio
+	}
+
+	streak, err := PairStreak(db, pair, userID)
+	if err != nil {
+		return stats, err
+	}
+	stats.Streak = streak
+
+	return stats, nil
+}
+
+// GetUserStats считает все сердечки, отправленные userID, синхронные сердечки и серию.
+func GetUserStats(db *sql.DB, userID uuid.UUID) (models.Stats, error) {
+	var stats models.Stats
+	err := db.QueryRow(
+		`SELECT
+			COALESCE(SUM(event_count), 0) as total_events,
+			COALESCE(SUM(total_duration_seconds), 0) as total_duration_seconds,
+			COALESCE(SUM(total_duration_seconds)::float8 / NULLIF(SUM(event_count), 0), 0) as average_duration_seconds
+		FROM (
+			SELECT event_count, total_duration_seconds FROM love_user_daily_rollups WHERE user_id = $1
+			UNION ALL
+			-- События, которые catch-up воркер еще не учел в агрегатах
+			SELECT 1, duration_seconds FROM love_events WHERE sender_id = $1 AND NOT rolled_up
+		) r`,
+		userID,
+	).Scan(&stats.TotalEvents, &stats.TotalDurationSeconds, &stats.AverageDurationSeconds)
+	if err != nil {
+		return stats, err
+	}
+
+	// Синхронные сердечки считаются для пары: в каждом участвуют оба партнера
+	err = db.QueryRow(
+		`SELECT COUNT(*), COALESCE(SUM(s.overlap_seconds), 0)
+		FROM synced_hearts s
+		JOIN love_events e ON s.second_event_id = e.id OR s.first_event_id = e.id
+		WHERE e.sender_id = $1`,
+		userID,
+	).Scan(&stats.SyncedHearts, &stats.SyncedDurationSeconds)
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Streak, err = UserStreak(db, userID)
+	return stats, err
+}
+
+func earliest(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.Before(*a)) {
+		return b
+	}
+	return a
+}
+
+func latest(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
+
+// StatsFilter задает выборку сердечек для агрегатов: все отправленные пользователем
+// (PairID == nil) или все сердечки пары.
+type StatsFilter struct {
+	UserID uuid.UUID
+	PairID *uuid.UUID
+	From   time.Time
+	To     time.Time
+}
+
+func (f StatsFilter) where() (string, []interface{}) {
+	if f.PairID != nil {
+		return "e.pair_id = $1 AND e.created_at >= $2 AND e.created_at < $3", []interface{}{*f.PairID, f.From, f.To}
+	}
+	return "e.sender_id = $1 AND e.created_at >= $2 AND e.created_at < $3", []interface{}{f.UserID, f.From, f.To}
+}
+
+// hourAligned сообщает, можно ли собрать периоды часового пояса loc из часовых агрегатов:
+// границы запроса должны приходиться на начало часа, а смещение пояса — быть целым числом часов
+// на всем интервале. Иначе (например, Asia/Kolkata, UTC+5:30) считаем по love_events.
+func (f StatsFilter) hourAligned(loc *time.Location) bool {
+	if !f.From.Equal(f.From.Truncate(time.Hour)) || !f.To.Equal(f.To.Truncate(time.Hour)) {
+		return false
+	}
+
+	wholeHours := func(t time.Time) bool {
+		_, offset := t.In(loc).Zone()
+		return offset%3600 == 0
+	}
+	// Смена смещения длится дольше суток, поэтому достаточно проверять раз в день
+	for t := f.From; t.Before(f.To); t = t.Add(24 * time.Hour) {
+		if !wholeHours(t) {
+			return false
+		}
+	}
+	return wholeHours(f.To)
+}
+
+// statsSource — откуда читать сердечки для рядов и тепловой карты: выражения для времени,
+// количества и суммарной длительности и подзапрос с алиасом r.
+type statsSource struct {
+	from     string
+	at       string
+	count    string
+	duration string
+	args     []interface{}
+}
+
+// source выбирает часовые агрегаты, если по ним можно посчитать периоды в loc, иначе love_events.
+// К агрегатам добавляются события, которые catch-up воркер еще не учел (rolled_up = false).
+func (f StatsFilter) source(loc *time.Location) statsSource {
+	where, args := f.where()
+
+	if !f.hourAligned(loc) {
+		return statsSource{
+			from:     "(SELECT e.created_at, e.duration_seconds FROM love_events e WHERE " + where + ") r",
+			at:       "r.created_at",
+			count:    "COUNT(*)",
+			duration: "COALESCE(SUM(r.duration_seconds), 0)",
+			args:     args,
+		}
+	}
+
+	table, key := "love_user_hourly_rollups", "user_id"
+	if f.PairID != nil {
+		table, key = "love_pair_hourly_rollups", "pair_id"
+	}
+
+	return statsSource{
+		from: `(SELECT hour, event_count, total_duration_seconds FROM ` + table + `
+			WHERE ` + key + ` = $1 AND hour >= $2 AND hour < $3
+			UNION ALL
+			SELECT e.created_at, 1, e.duration_seconds FROM love_events e
+			WHERE NOT e.rolled_up AND ` + where + `) r`,
+		at:       "r.hour",
+		count:    "COALESCE(SUM(r.event_count), 0)",
+		duration: "COALESCE(SUM(r.total_duration_seconds), 0)",
+		args:     args,
+	}
+}
+
+// TruncateToBucket возвращает начало периода bucket, в который попадает t (в часовом поясе t).
+// Недели начинаются с понедельника, как date_trunc('week') в PostgreSQL.
+func TruncateToBucket(t time.Time, bucket string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch bucket {
+	case models.StatsBucketWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case models.StatsBucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	case models.StatsBucketYear:
+		return time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, t.Location())
+	}
+	return day
+}
+
+func nextBucket(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case models.StatsBucketWeek:
+		return t.AddDate(0, 0, 7)
+	case models.StatsBucketMonth:
+		return t.AddDate(0, 1, 0)
+	case models.StatsBucketYear:
+		return t.AddDate(1, 0, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// GetTimeSeries считает сердечки по периодам и тепловую карту день недели × час в часовом поясе loc.
+// Пустые периоды возвращаются с нулями.
+func GetTimeSeries(db *sql.DB, filter StatsFilter, bucket string, loc *time.Location) (models.TimeSeriesStats, error) {
+	// bucket подставляется в date_trunc, поэтому допускаются только известные значения
+	if bucket != models.StatsBucketWeek && bucket != models.StatsBucketMonth {
+		bucket = models.StatsBucketDay
+	}
+
+	stats := models.TimeSeriesStats{
+		Bucket:   bucket,
+		Timezone: loc.String(),
+		From:     filter.From,
+		To:       filter.To,
+		Points:   make([]models.TimeSeriesPoint, 0),
+	}
+
+	src := filter.source(loc)
+	tz := "$" + strconv.Itoa(len(src.args)+1)
+	args := append(src.args, loc.String())
+
+	rows, err := db.Query(
+		`SELECT to_char(date_trunc('`+bucket+`', `+src.at+` AT TIME ZONE `+tz+`), 'YYYY-MM-DD'),
+			`+src.count+`, `+src.duration+`
+		FROM `+src.from+`
+		GROUP BY 1`,
+		args...,
+	)
+	if err != nil {
+		return stats, err
+	}
+
+	buckets := make(map[string]models.TimeSeriesPoint)
+	for rows.Next() {
+		var point models.TimeSeriesPoint
+		if err := rows.Scan(&point.Bucket, &point.Count, &point.TotalDurationSeconds); err != nil {
+			rows.Close()
+			return stats, err
+		}
+		buckets[point.Bucket] = point
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	for b := TruncateToBucket(filter.From.In(loc), bucket); b.Before(filter.To); b = nextBucket(b, bucket) {
+		key := b.Format("2006-01-02")
+		point, ok := buckets[key]
+		if !ok {
+			point = models.TimeSeriesPoint{Bucket: key}
+		}
+		stats.Points = append(stats.Points, point)
+	}
+
+	rows, err = db.Query(
+		`SELECT EXTRACT(ISODOW FROM `+src.at+` AT TIME ZONE `+tz+`)::int - 1,
+			EXTRACT(HOUR FROM `+src.at+` AT TIME ZONE `+tz+`)::int,
+			`+src.count+`
+		FROM `+src.from+`
+		GROUP BY 1, 2`,
+		args...,
+	)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var weekday, hour, count int
+		if err := rows.Scan(&weekday, &hour, &count); err != nil {
+			return stats, err
+		}
+		if weekday >= 0 && weekday < 7 && hour >= 0 && hour < 24 {
+			stats.Heatmap[weekday][hour] = count
+		}
+	}
+
+	return stats, rows.Err()
+}
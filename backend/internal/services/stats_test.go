@@ -0,0 +1,316 @@
+package services
+
+import (
+	"love-connection/backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Итоги /stats не должны меняться, когда catch-up воркер переносит события в агрегаты.
+func TestUserStatsUnchangedByRollup(t *testing.T) {
+	db := openTestDB(t)
+	pairID, userID, _ := createTestPair(t, db)
+
+	now := time.Now()
+	for _, event := range []struct {
+		duration int
+		at       time.Time
+	}{
+		{3, now.Add(-50 * time.Hour)},
+		{5, now.Add(-26 * time.Hour)},
+		{10, now.Add(-time.Hour)},
+	} {
+		_, err := db.Exec(
+			"INSERT INTO love_events (pair_id, sender_id, duration_seconds, created_at) VALUES ($1, $2, $3, $4)",
+			pairID, userID, event.duration, event.at,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before, err := GetUserStats(db, userID)
+	if err != nil {
+		t.Fatalf("GetUserStats: %v", err)
+	}
+	if before.TotalEvents != 3 || before.TotalDurationSeconds != 18 || before.AverageDurationSeconds != 6 {
+		t.Fatalf("before rollup: %+v, want 3 events, 18 seconds, average 6", before)
+	}
+
+	worker := NewRollupWorker(db)
+	for {
+		applied, err := worker.catchUp()
+		if err != nil {
+			t.Fatalf("catchUp: %v", err)
+		}
+		if applied < rollupCatchUpBatch {
+			break
+		}
+	}
+
+	after, err := GetUserStats(db, userID)
+	if err != nil {
+		t.Fatalf("GetUserStats: %v", err)
+	}
+	if after != before {
+		t.Errorf("after rollup: %+v, want %+v", after, before)
+	}
+}
+
+func TestEarliestLatest(t *testing.T) {
+	a := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := a.Add(time.Hour)
+
+	for _, tt := range []struct {
+		x, y                   *time.Time
+		wantEarliest, wantLast *time.Time
+	}{
+		{nil, nil, nil, nil},
+		{&a, nil, &a, &a},
+		{nil, &b, &b, &b},
+		{&a, &b, &a, &b},
+		{&b, &a, &a, &b},
+	} {
+		if got := earliest(tt.x, tt.y); got != tt.wantEarliest {
+			t.Errorf("earliest(%v, %v) = %v, want %v", tt.x, tt.y, got, tt.wantEarliest)
+		}
+		if got := latest(tt.x, tt.y); got != tt.wantLast {
+			t.Errorf("latest(%v, %v) = %v, want %v", tt.x, tt.y, got, tt.wantLast)
+		}
+	}
+}
+
+func TestGetPairStats(t *testing.T) {
+	db := openTestDB(t)
+	pairID, user1ID, user2ID := createTestPair(t, db)
+	t0 := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+
+	// Часть событий уже в агрегатах, часть еще нет
+	insertTestLoveEvent(t, db, pairID, user1ID, 4, t0)
+	insertTestLoveEvent(t, db, pairID, user1ID, 10, t0.Add(time.Hour))
+	worker := NewRollupWorker(db)
+	for {
+		applied, err := worker.catchUp()
+		if err != nil {
+			t.Fatalf("catchUp: %v", err)
+		}
+		if applied < rollupCatchUpBatch {
+			break
+		}
+	}
+	insertTestLoveEvent(t, db, pairID, user1ID, 1, t0.Add(2*time.Hour))
+	last := insertTestLoveEvent(t, db, pairID, user2ID, 6, t0.Add(3*time.Hour))
+
+	pair, err := ResolvePair(db, user1ID, nil)
+	if err != nil {
+		t.Fatalf("ResolvePair: %v", err)
+	}
+	if pair.ID != pairID {
+		t.Fatalf("ResolvePair = %s, want %s", pair.ID, pairID)
+	}
+
+	stats, err := GetPairStats(db, pair, user1ID)
+	if err != nil {
+		t.Fatalf("GetPairStats: %v", err)
+	}
+	if stats.Me.UserID != user1ID || stats.Me.TotalEvents != 3 || stats.Me.TotalDurationSeconds != 15 ||
+		stats.Me.AverageDurationSeconds != 5 || stats.Me.LongestHoldSeconds != 10 {
+		t.Errorf("me = %+v, want 3 events, 15 seconds, average 5, longest 10", stats.Me)
+	}
+	if stats.Partner.UserID != user2ID || stats.Partner.TotalEvents != 1 || stats.Partner.LongestHoldSeconds != 6 {
+		t.Errorf("partner = %+v, want 1 event of 6 seconds", stats.Partner)
+	}
+	if stats.TotalEvents != 4 || stats.TotalDurationSeconds != 21 {
+		t.Errorf("totals = %d events, %d seconds; want 4, 21", stats.TotalEvents, stats.TotalDurationSeconds)
+	}
+	if stats.SentReceivedRatio == nil || *stats.SentReceivedRatio != 3 {
+		t.Errorf("ratio = %v, want 3", stats.SentReceivedRatio)
+	}
+	if stats.FirstEventAt == nil || !stats.FirstEventAt.Equal(t0) || stats.LastEventAt == nil || !stats.LastEventAt.Equal(last.CreatedAt) {
+		t.Errorf("first %v, last %v; want %s, %s", stats.FirstEventAt, stats.LastEventAt, t0, last.CreatedAt)
+	}
+
+	// Архивная пара доступна только по ID
+	if _, err := db.Exec("UPDATE pairs SET archived_at = NOW() WHERE id = $1", pairID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResolvePair(db, user1ID, nil); err != ErrNoPair {
+		t.Errorf("ResolvePair without a current pair: err = %v, want ErrNoPair", err)
+	}
+	if archived, err := ResolvePair(db, user1ID, &pairID); err != nil || archived.ArchivedAt == nil {
+		t.Errorf("ResolvePair(archived) = %+v, %v", archived, err)
+	}
+	outsiderID := createTestUser(t, db, "UTC")
+	if _, err := ResolvePair(db, outsiderID, &pairID); err != ErrNoPair {
+		t.Errorf("ResolvePair of another pair: err = %v, want ErrNoPair", err)
+	}
+}
+
+func TestTruncateToBucket(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		t      time.Time
+		bucket string
+		want   time.Time
+	}{
+		{time.Date(2026, 3, 11, 15, 4, 5, 0, time.UTC), models.StatsBucketDay, time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 11, 15, 4, 5, 0, time.UTC), "", time.Date(2026, 3, 11, 0, 0, 0, 0, time.UTC)},
+		// Среда, воскресенье и понедельник одной недели
+		{time.Date(2026, 3, 11, 15, 0, 0, 0, time.UTC), models.StatsBucketWeek, time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 15, 23, 59, 0, 0, time.UTC), models.StatsBucketWeek, time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), models.StatsBucketWeek, time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)},
+		// Неделя через границу года
+		{time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC), models.StatsBucketWeek, time.Date(2025, 12, 29, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC), models.StatsBucketMonth, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC), models.StatsBucketYear, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// День перехода на летнее время длится 23 часа, но начинается в полночь
+		{time.Date(2026, 3, 8, 22, 0, 0, 0, newYork), models.StatsBucketDay, time.Date(2026, 3, 8, 0, 0, 0, 0, newYork)},
+		// Неделя с переходом: понедельник до перехода по зимнему времени
+		{time.Date(2026, 3, 12, 9, 0, 0, 0, newYork), models.StatsBucketWeek, time.Date(2026, 3, 9, 0, 0, 0, 0, newYork)},
+		{time.Date(2026, 3, 8, 9, 0, 0, 0, newYork), models.StatsBucketWeek, time.Date(2026, 3, 2, 0, 0, 0, 0, newYork)},
+	}
+
+	for _, tt := range tests {
+		if got := TruncateToBucket(tt.t, tt.bucket); !got.Equal(tt.want) || got.Location() != tt.want.Location() {
+			t.Errorf("TruncateToBucket(%s, %q) = %s, want %s", tt.t, tt.bucket, got, tt.want)
+		}
+	}
+}
+
+func TestNextBucket(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		t      time.Time
+		bucket string
+		want   time.Time
+	}{
+		{time.Date(2026, 3, 7, 0, 0, 0, 0, newYork), models.StatsBucketDay, time.Date(2026, 3, 8, 0, 0, 0, 0, newYork)},
+		// После 23-часового дня — снова полночь, а не 01:00
+		{time.Date(2026, 3, 8, 0, 0, 0, 0, newYork), models.StatsBucketDay, time.Date(2026, 3, 9, 0, 0, 0, 0, newYork)},
+		{time.Date(2026, 3, 2, 0, 0, 0, 0, newYork), models.StatsBucketWeek, time.Date(2026, 3, 9, 0, 0, 0, 0, newYork)},
+		{time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), models.StatsBucketMonth, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), models.StatsBucketMonth, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), models.StatsBucketYear, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		if got := nextBucket(tt.t, tt.bucket); !got.Equal(tt.want) {
+			t.Errorf("nextBucket(%s, %q) = %s, want %s", tt.t, tt.bucket, got, tt.want)
+		}
+	}
+}
+
+func TestHourAligned(t *testing.T) {
+	zones := make(map[string]*time.Location)
+	for _, name := range []string{"UTC", "America/New_York", "Asia/Kolkata", "Australia/Lord_Howe"} {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zones[name] = loc
+	}
+	hour := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		zone     string
+		want     bool
+	}{
+		{"whole hours", hour, hour.AddDate(0, 1, 0), "UTC", true},
+		{"from mid-hour", hour.Add(30 * time.Minute), hour.AddDate(0, 1, 0), "UTC", false},
+		{"to mid-hour", hour, hour.Add(90 * time.Minute), "UTC", false},
+		{"across DST", hour, hour.AddDate(1, 0, 0), "America/New_York", true},
+		{"half-hour offset", hour, hour.AddDate(0, 0, 1), "Asia/Kolkata", false},
+		// На острове Лорд-Хау летом UTC+11, зимой UTC+10:30
+		{"half-hour DST, summer", hour, hour.AddDate(0, 1, 0), "Australia/Lord_Howe", true},
+		{"half-hour DST, winter", time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 6, 2, 0, 0, 0, 0, time.UTC), "Australia/Lord_Howe", false},
+		{"half-hour DST, whole year", hour, hour.AddDate(1, 0, 0), "Australia/Lord_Howe", false},
+	}
+
+	for _, tt := range tests {
+		filter := StatsFilter{UserID: uuid.New(), From: tt.from, To: tt.to}
+		if got := filter.hourAligned(zones[tt.zone]); got != tt.want {
+			t.Errorf("%s: hourAligned = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// Ряды из часовых агрегатов и из love_events совпадают.
+func TestGetTimeSeriesSources(t *testing.T) {
+	db := openTestDB(t)
+	pairID, userID, _ := createTestPair(t, db)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, at := range []time.Time{
+		time.Date(2026, 3, 7, 23, 30, 0, 0, newYork),
+		time.Date(2026, 3, 8, 1, 15, 0, 0, newYork),
+		time.Date(2026, 3, 8, 3, 15, 0, 0, newYork), // после перехода на летнее время
+		time.Date(2026, 3, 9, 12, 0, 0, 0, newYork),
+	} {
+		insertTestLoveEvent(t, db, pairID, userID, 3, at)
+	}
+	worker := NewRollupWorker(db)
+	for {
+		applied, err := worker.catchUp()
+		if err != nil {
+			t.Fatalf("catchUp: %v", err)
+		}
+		if applied < rollupCatchUpBatch {
+			break
+		}
+	}
+	// Одно событие еще не попало в агрегаты
+	insertTestLoveEvent(t, db, pairID, userID, 5, time.Date(2026, 3, 9, 13, 0, 0, 0, newYork))
+
+	aligned := StatsFilter{
+		UserID: userID,
+		From:   time.Date(2026, 3, 7, 0, 0, 0, 0, newYork),
+		To:     time.Date(2026, 3, 10, 0, 0, 0, 0, newYork),
+	}
+	unaligned := aligned
+	unaligned.From = aligned.From.Add(-time.Second)
+	if !aligned.hourAligned(newYork) || unaligned.hourAligned(newYork) {
+		t.Fatal("filters do not pick different sources")
+	}
+
+	want := map[string][2]int{"2026-03-07": {1, 3}, "2026-03-08": {2, 6}, "2026-03-09": {2, 8}}
+	for name, filter := range map[string]StatsFilter{"rollups": aligned, "events": unaligned} {
+		stats, err := GetTimeSeries(db, filter, models.StatsBucketDay, newYork)
+		if err != nil {
+			t.Fatalf("%s: GetTimeSeries: %v", name, err)
+		}
+		got := make(map[string][2]int)
+		for _, point := range stats.Points {
+			if point.Count > 0 {
+				got[point.Bucket] = [2]int{point.Count, point.TotalDurationSeconds}
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: points %v, want %v", name, got, want)
+			continue
+		}
+		for bucket, w := range want {
+			if got[bucket] != w {
+				t.Errorf("%s: %s = %v, want %v", name, bucket, got[bucket], w)
+			}
+		}
+		// 8 марта в 01:15 — воскресенье (ISO 6), 01 час
+		if stats.Heatmap[6][1] != 1 || stats.Heatmap[6][3] != 1 {
+			t.Errorf("%s: heatmap for Sunday = %v", name, stats.Heatmap[6])
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultStreakGrace = 2 * time.Hour
+	maxStreakGrace     = 12 * time.Hour
+
+	streakWatchInterval = 10 * time.Minute
+	// Час локального времени, после которого приходит пуш о том, что серия вот-вот прервется.
+	streakRiskHour = 21
+	// Минимальная длина серии, ради которой стоит напоминать.
+	streakRiskMinDays = 2
+)
+
+var streakMilestones = []int{3, 7, 14, 30, 50, 100, 200, 365, 500, 1000}
+
+// StreakGrace — сколько времени после полуночи сердечко еще может закрыть вчерашний день,
+// если вчера ничего не было отправлено. Настраивается через LOVE_STREAK_GRACE (например, "3h").
+func StreakGrace() time.Duration {
+	value := os.Getenv("LOVE_STREAK_GRACE")
+	if value == "" {
+		return defaultStreakGrace
+	}
+
+	grace, err := time.ParseDuration(value)
+	if err != nil || grace < 0 || grace > maxStreakGrace {
+		fmt.Printf("Invalid LOVE_STREAK_GRACE %q, using %s\n", value, defaultStreakGrace)
+		return defaultStreakGrace
+	}
+	return grace
+}
+
+// streakDays возвращает множество локальных дней (полночь UTC с датой дня), закрытых сердечками.
+// Серии не хранятся, а каждый раз считаются по love_events, поэтому удаленные
+// и задним числом добавленные события учитываются автоматически.
+func streakDays(db queryer, loc *time.Location, grace time.Duration, senderID uuid.UUID, pairID *uuid.UUID) (map[time.Time]bool, error) {
+	graceTime := fmt.Sprintf("%02d:%02d:00", int(grace.Hours()), int(grace.Minutes())%60)
+
+	query := `SELECT (created_at AT TIME ZONE $1)::date AS day,
+			COUNT(*) FILTER (WHERE (created_at AT TIME ZONE $1)::time >= $2::time),
+			COUNT(*) FILTER (WHERE (created_at AT TIME ZONE $1)::time < $2::time)
+		FROM love_events
+		WHERE sender_id = $3`
+	args := []interface{}{loc.String(), graceTime, senderID}
+	if pairID != nil {
+		query += " AND pair_id = $4"
+		args = append(args, *pairID)
+	}
+	query += " GROUP BY 1 ORDER BY 1"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := make(map[time.Time]bool)
+	for rows.Next() {
+		var day time.Time
+		var regular, early int
+		if err := rows.Scan(&day, &regular, &early); err != nil {
+			return nil, err
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+		// Сердечко вскоре после полуночи закрывает пропущенный вчерашний день.
+		// Дни идут по возрастанию, так что вчерашний день уже обработан.
+		if early > 0 && grace > 0 {
+			previous := day.AddDate(0, 0, -1)
+			if !days[previous] {
+				days[previous] = true
+				early--
+			}
+		}
+		if regular > 0 || early > 0 {
+			days[day] = true
+		}
+	}
+
+	return days, rows.Err()
+}
+
+// streakFromDays считает текущую и самую длинную серию на момент now.
+// Серия жива, если закрыт сегодняшний или вчерашний день (а в пределах grace — и позавчерашний).
+func streakFromDays(days map[time.Time]bool, now time.Time, loc *time.Location, grace time.Duration) models.Streak {
+	var streak models.Streak
+
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	sinceMidnight := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+
+	start := today
+	if !days[start] {
+		start = start.AddDate(0, 0, -1)
+		if !days[start] && sinceMidnight < grace {
+			start = start.AddDate(0, 0, -1)
+		}
+	}
+
+	for day := start; days[day]; day = day.AddDate(0, 0, -1) {
+		streak.Current++
+		streak.StartedOn = day.Format("2006-01-02")
+	}
+	streak.AtRisk = streak.Current > 0 && !days[today]
+	streak.Longest = longestRun(days)
+
+	return streak
+}
+
+// longestRun возвращает длину самой длинной цепочки дней подряд.
+func longestRun(days map[time.Time]bool) int {
+	sorted := make([]time.Time, 0, len(days))
+	for day := range days {
+		sorted = append(sorted, day)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	longest, run := 0, 0
+	for i, day := range sorted {
+		if i > 0 && sorted[i-1].AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+	}
+
+	return longest
+}
+
+// firstRunReaching возвращает день, на котором первая по времени серия достигла n дней.
+func firstRunReaching(days map[time.Time]bool, n int) (time.Time, bool) {
+	sorted := make([]time.Time, 0, len(days))
+	for day := range days {
+		sorted = append(sorted, day)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	run := 0
+	for i, day := range sorted {
+		if i > 0 && sorted[i-1].AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		if run >= n {
+			return day, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// UserStreak — серия пользователя по всем его парам, в его часовом поясе.
+func UserStreak(db queryer, userID uuid.UUID) (models.Streak, error) {
+	loc, err := UserLocation(db, userID)
+	if err != nil {
+		return models.Streak{}, err
+	}
+
+	grace := StreakGrace()
+	days, err := streakDays(db, loc, grace, userID, nil)
+	if err != nil {
+		return models.Streak{}, err
+	}
+
+	return streakFromDays(days, time.Now(), loc, grace), nil
+}
+
+// pairStreakDays возвращает дни, закрытые обоими партнерами пары, каждым в своем часовом поясе.
+func pairStreakDays(db queryer, pair models.Pair, grace time.Duration) (map[time.Time]bool, error) {
+	var common map[time.Time]bool
+	for _, memberID := range []uuid.UUID{pair.User1ID, pair.User2ID} {
+		loc, err := UserLocation(db, memberID)
+		if err != nil {
+			return nil, err
+		}
+
+		days, err := streakDays(db, loc, grace, memberID, &pair.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if common == nil {
+			common = days
+			continue
+		}
+		for day := range common {
+			if !days[day] {
+				delete(common, day)
+			}
+		}
+	}
+
+	return common, nil
+}
+
+// PairStreak — серия пары: день засчитывается, если оба партнера закрыли его в своих часовых поясах.
+// Текущая серия считается относительно «сегодня» пользователя viewerID.
+func PairStreak(db queryer, pair models.Pair, viewerID uuid.UUID) (models.Streak, error) {
+	grace := StreakGrace()
+
+	days, err := pairStreakDays(db, pair, grace)
+	if err != nil {
+		return models.Streak{}, err
+	}
+
+	viewerLoc, err := UserLocation(db, viewerID)
+	if err != nil {
+		return models.Streak{}, err
+	}
+
+	return streakFromDays(days, time.Now(), viewerLoc, grace), nil
+}
+
+func isStreakMilestone(days int) bool {
+	for _, milestone := range streakMilestones {
+		if days == milestone {
+			return true
+		}
+	}
+	return false
+}
+
+// claimStreakNotification отмечает пуш как отправленный; false, если он уже был отправлен.
+func claimStreakNotification(db *sql.DB, userID uuid.UUID, kind, period string) (bool, error) {
+	result, err := db.Exec(
+		"INSERT INTO streak_notifications (user_id, kind, period) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+		userID, kind, period,
+	)
+	if err != nil {
+		return false, err
+	}
+	claimed, err := result.RowsAffected()
+	return claimed > 0, err
+}
+
+// CheckStreakMilestones вызывается после отправки сердечка и присылает пуш,
+// если личная серия отправителя или серия пары достигла круглого числа дней.
+func CheckStreakMilestones(db *sql.DB, senderID uuid.UUID) {
+	streak, err := UserStreak(db, senderID)
+	if err != nil {
+		fmt.Printf("Failed to compute streak for user %s: %v\n", senderID, err)
+		return
+	}
+
+	if isStreakMilestone(streak.Current) {
+		kind := fmt.Sprintf("user_milestone_%d", streak.Current)
+		claimed, err := claimStreakNotification(db, senderID, kind, streak.StartedOn)
+		if err != nil {
+			fmt.Printf("Failed to claim streak notification for user %s: %v\n", senderID, err)
+		} else if claimed {
+			SendStreakMilestoneNotification(db, senderID, streak.Current, "")
+		}
+	}
+
+	pairID, partnerID, err := FindPartner(db, senderID)
+	if err != nil {
+		return
+	}
+
+	pairStreak, err := PairStreak(db, models.Pair{ID: pairID, User1ID: senderID, User2ID: partnerID}, senderID)
+	if err != nil {
+		fmt.Printf("Failed to compute pair streak for pair %s: %v\n", pairID, err)
+		return
+	}
+	if !isStreakMilestone(pairStreak.Current) {
+		return
+	}
+
+	usernames := make(map[uuid.UUID]string)
+	rows, err := db.Query("SELECT id, username FROM users WHERE id IN ($1, $2)", senderID, partnerID)
+	if err != nil {
+		fmt.Printf("Failed to load usernames for pair %s: %v\n", pairID, err)
+		return
+	}
+	for rows.Next() {
+		var id uuid.UUID
+		var username string
+		if err := rows.Scan(&id, &username); err == nil {
+			usernames[id] = username
+		}
+	}
+	rows.Close()
+
+	kind := fmt.Sprintf("pair_milestone_%d", pairStreak.Current)
+	for userID, otherID := range map[uuid.UUID]uuid.UUID{senderID: partnerID, partnerID: senderID} {
+		claimed, err := claimStreakNotification(db, userID, kind, pairStreak.StartedOn)
+		if err != nil {
+			fmt.Printf("Failed to claim streak notification for user %s: %v\n", userID, err)
+			continue
+		}
+		if claimed {
+			SendStreakMilestoneNotification(db, userID, pairStreak.Current, usernames[otherID])
+		}
+	}
+}
+
+// StreakWatcher вечером по локальному времени пользователя присылает пуш,
+// если его серия жива, но сегодня он еще не отправлял сердечко.
+type StreakWatcher struct {
+	db *sql.DB
+}
+
+func NewStreakWatcher(db *sql.DB) *StreakWatcher {
+	return &StreakWatcher{db: db}
+}
+
+func (w *StreakWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(streakWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.warnAtRisk(ctx); err != nil {
+			fmt.Printf("Failed to check streaks at risk: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// streakRiskDay возвращает локальный день пользователя в поясе loc и true,
+// если там уже наступил вечер (streakRiskHour) и пора предупреждать о серии.
+func streakRiskDay(now time.Time, loc *time.Location) (string, bool) {
+	local := now.In(loc)
+	return local.Format("2006-01-02"), local.Hour() >= streakRiskHour
+}
+
+func (w *StreakWatcher) warnAtRisk(ctx context.Context) error {
+	// Серия может быть жива только у тех, кто отправлял сердечки в последние дни
+	rows, err := w.db.Query(
+		`SELECT u.id, tz.name
+		FROM users u
+		JOIN pg_timezone_names tz ON tz.name = u.timezone
+		WHERE EXISTS (
+			SELECT 1 FROM love_events e
+			WHERE e.sender_id = u.id AND e.created_at > NOW() - INTERVAL '3 days'
+		)`,
+	)
+	if err != nil {
+		return err
+	}
+
+	type candidate struct {
+		userID   uuid.UUID
+		localDay string
+	}
+	now := time.Now()
+	var candidates []candidate
+	for rows.Next() {
+		var userID uuid.UUID
+		var timezone string
+		if err := rows.Scan(&userID, &timezone); err != nil {
+			rows.Close()
+			return err
+		}
+		loc, err := LoadTimezone(timezone)
+		if err != nil {
+			continue
+		}
+		if localDay, due := streakRiskDay(now, loc); due {
+			candidates = append(candidates, candidate{userID: userID, localDay: localDay})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range candidates {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		streak, err := UserStreak(w.db, c.userID)
+		if err != nil {
+			fmt.Printf("Failed to compute streak for user %s: %v\n", c.userID, err)
+			continue
+		}
+		if !streak.AtRisk || streak.Current < streakRiskMinDays {
+			continue
+		}
+
+		claimed, err := claimStreakNotification(w.db, c.userID, "at_risk", c.localDay)
+		if err != nil {
+			fmt.Printf("Failed to claim streak notification for user %s: %v\n", c.userID, err)
+			continue
+		}
+		if claimed {
+			SendStreakAtRiskNotification(w.db, c.userID, streak.Current)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+// day — локальный день в представлении streakDays (полночь UTC с датой дня).
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func daySet(days ...time.Time) map[time.Time]bool {
+	set := make(map[time.Time]bool)
+	for _, d := range days {
+		set[d] = true
+	}
+	return set
+}
+
+func TestStreakGrace(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"":     defaultStreakGrace,
+		"3h":   3 * time.Hour,
+		"90m":  90 * time.Minute,
+		"0":    0,
+		"12h":  maxStreakGrace,
+		"13h":  defaultStreakGrace,
+		"-1h":  defaultStreakGrace,
+		"soon": defaultStreakGrace,
+	} {
+		t.Setenv("LOVE_STREAK_GRACE", value)
+		if got := StreakGrace(); got != want {
+			t.Errorf("LOVE_STREAK_GRACE=%q: StreakGrace() = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestStreakFromDays(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		days        map[time.Time]bool
+		now         time.Time
+		loc         *time.Location
+		grace       time.Duration
+		wantCurrent int
+		wantStarted string
+		wantAtRisk  bool
+		wantLongest int
+	}{
+		{
+			name:  "no hearts",
+			days:  daySet(),
+			now:   time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+			loc:   time.UTC,
+			grace: 2 * time.Hour,
+		},
+		{
+			name:        "today closed",
+			days:        daySet(day(2026, 3, 8), day(2026, 3, 9), day(2026, 3, 10)),
+			now:         time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+			loc:         time.UTC,
+			grace:       2 * time.Hour,
+			wantCurrent: 3,
+			wantStarted: "2026-03-08",
+			wantLongest: 3,
+		},
+		{
+			name:        "today still open",
+			days:        daySet(day(2026, 3, 8), day(2026, 3, 9)),
+			now:         time.Date(2026, 3, 10, 22, 0, 0, 0, time.UTC),
+			loc:         time.UTC,
+			grace:       2 * time.Hour,
+			wantCurrent: 2,
+			wantStarted: "2026-03-08",
+			wantAtRisk:  true,
+			wantLongest: 2,
+		},
+		{
+			name:        "yesterday missed, within grace",
+			days:        daySet(day(2026, 3, 7), day(2026, 3, 8)),
+			now:         time.Date(2026, 3, 10, 1, 30, 0, 0, time.UTC),
+			loc:         time.UTC,
+			grace:       2 * time.Hour,
+			wantCurrent: 2,
+			wantStarted: "2026-03-07",
+			wantAtRisk:  true,
+			wantLongest: 2,
+		},
+		{
+			name:        "yesterday missed, grace over",
+			days:        daySet(day(2026, 3, 7), day(2026, 3, 8)),
+			now:         time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC),
+			loc:         time.UTC,
+			grace:       2 * time.Hour,
+			wantLongest: 2,
+		},
+		{
+			name:        "yesterday missed, no grace",
+			days:        daySet(day(2026, 3, 7), day(2026, 3, 8)),
+			now:         time.Date(2026, 3, 10, 0, 10, 0, 0, time.UTC),
+			loc:         time.UTC,
+			grace:       0,
+			wantLongest: 2,
+		},
+		{
+			name:        "longest is an older run",
+			days:        daySet(day(2026, 2, 1), day(2026, 2, 2), day(2026, 2, 3), day(2026, 2, 4), day(2026, 3, 10)),
+			now:         time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC),
+			loc:         time.UTC,
+			grace:       2 * time.Hour,
+			wantCurrent: 1,
+			wantStarted: "2026-03-10",
+			wantLongest: 4,
+		},
+		{
+			// 23:30 UTC — уже следующий день в Москве
+			name:        "local day differs from UTC",
+			days:        daySet(day(2026, 3, 10), day(2026, 3, 11)),
+			now:         time.Date(2026, 3, 10, 23, 30, 0, 0, time.UTC),
+			loc:         moscow,
+			grace:       2 * time.Hour,
+			wantCurrent: 2,
+			wantStarted: "2026-03-10",
+			wantLongest: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streak := streakFromDays(tt.days, tt.now, tt.loc, tt.grace)
+			if streak.Current != tt.wantCurrent || streak.StartedOn != tt.wantStarted ||
+				streak.AtRisk != tt.wantAtRisk || streak.Longest != tt.wantLongest {
+				t.Errorf("streak = %+v, want current %d from %q, at risk %v, longest %d",
+					streak, tt.wantCurrent, tt.wantStarted, tt.wantAtRisk, tt.wantLongest)
+			}
+		})
+	}
+}
+
+func TestLongestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		days map[time.Time]bool
+		want int
+	}{
+		{"empty", daySet(), 0},
+		{"single day", daySet(day(2026, 5, 1)), 1},
+		{"gap", daySet(day(2026, 5, 1), day(2026, 5, 2), day(2026, 5, 4)), 2},
+		{"across months", daySet(day(2026, 2, 27), day(2026, 2, 28), day(2026, 3, 1)), 3},
+		{"across years", daySet(day(2025, 12, 30), day(2025, 12, 31), day(2026, 1, 1), day(2026, 1, 3)), 3},
+	}
+
+	for _, tt := range tests {
+		if got := longestRun(tt.days); got != tt.want {
+			t.Errorf("%s: longestRun = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsStreakMilestone(t *testing.T) {
+	for days, want := range map[int]bool{0: false, 2: false, 3: true, 6: false, 7: true, 8: false, 30: true, 365: true, 366: false} {
+		if got := isStreakMilestone(days); got != want {
+			t.Errorf("isStreakMilestone(%d) = %v, want %v", days, got, want)
+		}
+	}
+}
+
+func TestStreakRiskDay(t *testing.T) {
+	zones := make(map[string]*time.Location)
+	for _, name := range []string{"Europe/Moscow", "America/New_York", "Asia/Tokyo", "Asia/Kolkata"} {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zones[name] = loc
+	}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		zone    string
+		wantDay string
+		wantDue bool
+	}{
+		{"before 21:00", time.Date(2026, 6, 1, 17, 59, 0, 0, time.UTC), "Europe/Moscow", "2026-06-01", false},
+		{"at 21:00", time.Date(2026, 6, 1, 18, 0, 0, 0, time.UTC), "Europe/Moscow", "2026-06-01", true},
+		// 21:00 EST = 02:00 UTC следующего дня по UTC
+		{"before DST", time.Date(2026, 3, 8, 2, 0, 0, 0, time.UTC), "America/New_York", "2026-03-07", true},
+		{"before DST, 20:59", time.Date(2026, 3, 8, 1, 59, 0, 0, time.UTC), "America/New_York", "2026-03-07", false},
+		// 8 марта переход на летнее время: 21:00 EDT = 01:00 UTC
+		{"DST start day", time.Date(2026, 3, 9, 1, 0, 0, 0, time.UTC), "America/New_York", "2026-03-08", true},
+		{"DST start day, 20:59", time.Date(2026, 3, 9, 0, 59, 0, 0, time.UTC), "America/New_York", "2026-03-08", false},
+		// 1 ноября возврат на зимнее время: 21:00 EST = 02:00 UTC
+		{"DST end day", time.Date(2026, 11, 2, 2, 0, 0, 0, time.UTC), "America/New_York", "2026-11-01", true},
+		{"DST end day, 20:00", time.Date(2026, 11, 2, 1, 0, 0, 0, time.UTC), "America/New_York", "2026-11-01", false},
+		{"last minute of the day", time.Date(2026, 1, 1, 14, 59, 0, 0, time.UTC), "Asia/Tokyo", "2026-01-01", true},
+		{"next local day", time.Date(2026, 1, 1, 15, 0, 0, 0, time.UTC), "Asia/Tokyo", "2026-01-02", false},
+		{"half-hour offset, 20:59", time.Date(2026, 6, 1, 15, 29, 0, 0, time.UTC), "Asia/Kolkata", "2026-06-01", false},
+		{"half-hour offset, 21:00", time.Date(2026, 6, 1, 15, 30, 0, 0, time.UTC), "Asia/Kolkata", "2026-06-01", true},
+	}
+
+	for _, tt := range tests {
+		localDay, due := streakRiskDay(tt.now, zones[tt.zone])
+		if localDay != tt.wantDay || due != tt.wantDue {
+			t.Errorf("%s: streakRiskDay(%s, %s) = %s, %v; want %s, %v",
+				tt.name, tt.now.Format(time.RFC3339), tt.zone, localDay, due, tt.wantDay, tt.wantDue)
+		}
+	}
+}
+
+// Сердечко вскоре после полуночи закрывает пропущенный вчерашний день, но только в пределах grace.
+func TestStreakDaysGrace(t *testing.T) {
+	db := openTestDB(t)
+	pairID, userID, _ := createTestPair(t, db)
+
+	for _, at := range []time.Time{
+		time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		// 2 марта ничего нет; 3 марта только сразу после полуночи
+		time.Date(2026, 3, 3, 1, 0, 0, 0, time.UTC),
+	} {
+		_, err := db.Exec(
+			"INSERT INTO love_events (pair_id, sender_id, duration_seconds, created_at) VALUES ($1, $2, 3, $3)",
+			pairID, userID, at,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		grace time.Duration
+		want  map[time.Time]bool
+	}{
+		{2 * time.Hour, daySet(day(2026, 3, 1), day(2026, 3, 2))},
+		{30 * time.Minute, daySet(day(2026, 3, 1), day(2026, 3, 3))},
+		{0, daySet(day(2026, 3, 1), day(2026, 3, 3))},
+	}
+
+	for _, tt := range tests {
+		days, err := streakDays(db, time.UTC, tt.grace, userID, nil)
+		if err != nil {
+			t.Fatalf("streakDays: %v", err)
+		}
+		if len(days) != len(tt.want) {
+			t.Errorf("grace %s: days = %v, want %v", tt.grace, days, tt.want)
+			continue
+		}
+		for d := range tt.want {
+			if !days[d] {
+				t.Errorf("grace %s: days = %v, want %v", tt.grace, days, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// Пуш о круглой серии приходит один раз, когда серия доходит до отметки.
+func TestStreakMilestoneCrossing(t *testing.T) {
+	db := openTestDB(t)
+	pairID, userID, _ := createTestPair(t, db)
+
+	countMilestones := func() int {
+		var n int
+		err := db.QueryRow(
+			"SELECT COUNT(*) FROM streak_notifications WHERE user_id = $1 AND kind = 'user_milestone_7'",
+			userID,
+		).Scan(&n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	addDay := func(daysAgo int) {
+		now := time.Now().UTC()
+		at := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.UTC).AddDate(0, 0, -daysAgo)
+		_, err := db.Exec(
+			"INSERT INTO love_events (pair_id, sender_id, duration_seconds, created_at) VALUES ($1, $2, 3, $3)",
+			pairID, userID, at,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Шесть дней подряд до вчерашнего: отметки еще нет
+	for daysAgo := 6; daysAgo >= 1; daysAgo-- {
+		addDay(daysAgo)
+	}
+	CheckStreakMilestones(db, userID)
+	if n := countMilestones(); n != 0 {
+		t.Fatalf("6-day streak: %d milestone notifications, want 0", n)
+	}
+
+	addDay(7)
+	CheckStreakMilestones(db, userID)
+	CheckStreakMilestones(db, userID)
+	if n := countMilestones(); n != 1 {
+		t.Errorf("7-day streak: %d milestone notifications, want 1", n)
+	}
+}
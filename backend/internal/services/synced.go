@@ -0,0 +1,152 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Минимальное пересечение удержаний, которое считается "синхронным сердечком".
+const minSyncedOverlap = time.Second
+
+// Broadcaster отправляет realtime-сообщения пользователю через websocket-хаб.
+type Broadcaster interface {
+	SendToUser(userID uuid.UUID, messageType string, payload interface{}) bool
+}
+
+// DetectSyncedHearts ищет удержания партнера, которые пересекаются по времени с event.
+// Интервал удержания — [created_at - duration_seconds, created_at]: сердечко создается в момент отпускания.
+// Каждое пересечение сохраняется как отдельное synced heart; повторный вызов дубликатов не создает.
+func DetectSyncedHearts(db *sql.DB, event models.LoveEvent) ([]models.SyncedHeart, error) {
+	if event.PairID == nil {
+		return nil, nil
+	}
+
+	end := event.CreatedAt
+	start := end.Add(-time.Duration(event.DurationSeconds) * time.Second)
+
+	rows, err := db.Query(
+		`SELECT id, created_at - make_interval(secs => duration_seconds), created_at
+		FROM love_events
+		WHERE pair_id = $1 AND sender_id != $2 AND id != $3
+			AND created_at > $4
+			AND created_at - make_interval(secs => duration_seconds) < $5`,
+		*event.PairID, event.SenderID, event.ID, start, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type candidate struct {
+		id         uuid.UUID
+		start, end time.Time
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.id, &c.start, &c.end); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var synced []models.SyncedHeart
+	for _, c := range candidates {
+		overlapStart, overlap := holdOverlap(start, end, c.start, c.end)
+		if overlap < minSyncedOverlap {
+			continue
+		}
+
+		// Порядок событий канонический (по времени отпускания), чтобы при одновременной
+		// проверке с обеих сторон уникальный индекс отсек дубликат
+		firstID, secondID := c.id, event.ID
+		if end.Before(c.end) || (end.Equal(c.end) && event.ID.String() < c.id.String()) {
+			firstID, secondID = event.ID, c.id
+		}
+
+		sh := models.SyncedHeart{
+			PairID:           *event.PairID,
+			FirstEventID:     firstID,
+			SecondEventID:    secondID,
+			OverlapStartedAt: overlapStart,
+			OverlapSeconds:   int(overlap / time.Second),
+		}
+
+		err := db.QueryRow(
+			`INSERT INTO synced_hearts (pair_id, first_event_id, second_event_id, overlap_started_at, overlap_seconds)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (first_event_id, second_event_id) DO NOTHING
+			RETURNING id, created_at`,
+			sh.PairID, sh.FirstEventID, sh.SecondEventID, sh.OverlapStartedAt, sh.OverlapSeconds,
+		).Scan(&sh.ID, &sh.CreatedAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return synced, err
+		}
+
+		synced = append(synced, sh)
+	}
+
+	return synced, nil
+}
+
+// HandleSyncedHearts находит синхронные сердечка для нового события
+// и уведомляет обоих партнеров пушем и websocket-сообщением synced_heart.
+func HandleSyncedHearts(db *sql.DB, broadcaster Broadcaster, event models.LoveEvent, partnerID uuid.UUID) {
+	synced, err := DetectSyncedHearts(db, event)
+	if err != nil {
+		fmt.Printf("Failed to detect synced hearts for event %s: %v\n", event.ID, err)
+	}
+	if len(synced) == 0 {
+		return
+	}
+
+	var partnerUsername string
+	if err := db.QueryRow("SELECT username FROM users WHERE id = $1", partnerID).Scan(&partnerUsername); err != nil {
+		fmt.Printf("Failed to get username for user %s: %v\n", partnerID, err)
+		return
+	}
+
+	senderUsername := ""
+	if event.Sender != nil {
+		senderUsername = event.Sender.Username
+	}
+
+	for _, sh := range synced {
+		broadcaster.SendToUser(event.SenderID, "synced_heart", sh)
+		broadcaster.SendToUser(partnerID, "synced_heart", sh)
+
+		SendSyncedHeartNotification(db, event.SenderID, partnerUsername, sh.OverlapSeconds)
+		SendSyncedHeartNotification(db, partnerID, senderUsername, sh.OverlapSeconds)
+	}
+}
+
+// holdOverlap возвращает начало и длину пересечения удержаний [aStart, aEnd] и [bStart, bEnd];
+// длина не положительна, если удержания не пересекаются.
+func holdOverlap(aStart, aEnd, bStart, bEnd time.Time) (time.Time, time.Duration) {
+	start := maxTime(aStart, bStart)
+	return start, minTime(aEnd, bEnd).Sub(start)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"love-connection/backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHoldOverlap(t *testing.T) {
+	t0 := time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC)
+	at := func(seconds float64) time.Time { return t0.Add(time.Duration(seconds * float64(time.Second))) }
+
+	tests := []struct {
+		name        string
+		a, b        [2]float64
+		wantStart   float64
+		wantOverlap time.Duration
+		wantSynced  bool
+	}{
+		{"partial", [2]float64{0, 10}, [2]float64{8, 15}, 8, 2 * time.Second, true},
+		{"nested", [2]float64{0, 10}, [2]float64{3, 5}, 3, 2 * time.Second, true},
+		{"same hold", [2]float64{0, 4}, [2]float64{0, 4}, 0, 4 * time.Second, true},
+		{"exactly the minimum", [2]float64{0, 5}, [2]float64{4, 9}, 4, time.Second, true},
+		{"too short", [2]float64{0, 5}, [2]float64{4.5, 9}, 4.5, 500 * time.Millisecond, false},
+		{"touching", [2]float64{0, 5}, [2]float64{5, 9}, 5, 0, false},
+		{"apart", [2]float64{0, 5}, [2]float64{7, 9}, 7, -2 * time.Second, false},
+	}
+
+	for _, tt := range tests {
+		for _, swapped := range []bool{false, true} {
+			a, b := tt.a, tt.b
+			if swapped {
+				a, b = b, a
+			}
+			start, overlap := holdOverlap(at(a[0]), at(a[1]), at(b[0]), at(b[1]))
+			if !start.Equal(at(tt.wantStart)) || overlap != tt.wantOverlap {
+				t.Errorf("%s (swapped %v): start %s, overlap %s; want %s, %s",
+					tt.name, swapped, start.Sub(t0), overlap, at(tt.wantStart).Sub(t0), tt.wantOverlap)
+			}
+			if synced := overlap >= minSyncedOverlap; synced != tt.wantSynced {
+				t.Errorf("%s: synced = %v, want %v", tt.name, synced, tt.wantSynced)
+			}
+		}
+	}
+}
+
+// insertTestLoveEvent вставляет сердечко, отпущенное в createdAt после удержания в durationSeconds.
+func insertTestLoveEvent(t *testing.T, db queryer, pairID, senderID uuid.UUID, durationSeconds int, createdAt time.Time) models.LoveEvent {
+	t.Helper()
+
+	event := models.LoveEvent{PairID: &pairID, SenderID: senderID, DurationSeconds: durationSeconds, CreatedAt: createdAt}
+	err := db.QueryRow(
+		"INSERT INTO love_events (pair_id, sender_id, duration_seconds, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		pairID, senderID, durationSeconds, createdAt,
+	).Scan(&event.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestDetectSyncedHearts(t *testing.T) {
+	db := openTestDB(t)
+	pairID, user1ID, user2ID := createTestPair(t, db)
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	// user1 держит 10 секунд до t0, user2 — 5 секунд до t0+3: вместе 2 секунды с t0-2
+	first := insertTestLoveEvent(t, db, pairID, user1ID, 10, t0)
+	insertTestLoveEvent(t, db, pairID, user1ID, 3, t0.Add(time.Minute)) // не пересекается
+	// Свое сердечко user2 пересекается с second, но синхронным не считается
+	insertTestLoveEvent(t, db, pairID, user2ID, 3, t0.Add(4*time.Second))
+	second := insertTestLoveEvent(t, db, pairID, user2ID, 5, t0.Add(3*time.Second))
+
+	synced, err := DetectSyncedHearts(db, second)
+	if err != nil {
+		t.Fatalf("DetectSyncedHearts: %v", err)
+	}
+	if len(synced) != 1 {
+		t.Fatalf("got %d synced hearts, want 1", len(synced))
+	}
+	sh := synced[0]
+	if sh.FirstEventID != first.ID || sh.SecondEventID != second.ID || sh.PairID != pairID {
+		t.Errorf("synced heart %+v, want %s then %s", sh, first.ID, second.ID)
+	}
+	if sh.OverlapSeconds != 2 || !sh.OverlapStartedAt.Equal(t0.Add(-2*time.Second)) {
+		t.Errorf("overlap %d seconds from %s, want 2 from %s", sh.OverlapSeconds, sh.OverlapStartedAt, t0.Add(-2*time.Second))
+	}
+
+	// Проверка с другой стороны и повторная проверка дубликатов не создают
+	for _, event := range []models.LoveEvent{first, second} {
+		again, err := DetectSyncedHearts(db, event)
+		if err != nil {
+			t.Fatalf("DetectSyncedHearts: %v", err)
+		}
+		if len(again) != 0 {
+			t.Errorf("event %s: got %d new synced hearts, want 0", event.ID, len(again))
+		}
+	}
+}
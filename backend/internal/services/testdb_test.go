@@ -0,0 +1,69 @@
+package services
+
+import (
+	"database/sql"
+	"love-connection/backend/internal/database"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// openTestDB подключается к TEST_DATABASE_URL и применяет миграции. Без переменной тест пропускается:
+// тесты, которым нужен настоящий Postgres, запускаются через make test-backend-db.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.RunMigrations(db); err != nil {
+		t.Fatalf("migrations: %v", err)
+	}
+	return db
+}
+
+// createTestUser создает пользователя, который удаляется (вместе с его данными) после теста.
+func createTestUser(t *testing.T, db *sql.DB, timezone string) uuid.UUID {
+	t.Helper()
+
+	var userID uuid.UUID
+	name := "test-" + uuid.NewString()[:8]
+	err := db.QueryRow(
+		"INSERT INTO users (email, username, timezone) VALUES ($1, $2, $3) RETURNING id",
+		name+"@example.com", name, timezone,
+	).Scan(&userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM pairs WHERE user1_id = $1 OR user2_id = $1", userID)
+		db.Exec("DELETE FROM users WHERE id = $1", userID)
+	})
+	return userID
+}
+
+// createTestPair создает пару из двух новых пользователей.
+func createTestPair(t *testing.T, db *sql.DB) (pairID, user1ID, user2ID uuid.UUID) {
+	t.Helper()
+
+	user1ID = createTestUser(t, db, "UTC")
+	user2ID = createTestUser(t, db, "UTC")
+	err := db.QueryRow(
+		"INSERT INTO pairs (user1_id, user2_id) VALUES ($1, $2) RETURNING id",
+		user1ID, user2ID,
+	).Scan(&pairID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pairID, user1ID, user2ID
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const defaultUnsendWindow = 5 * time.Minute
+
+// UnsendWindow — время после отправки, в течение которого сердечко удаляется у обоих партнеров.
+// Настраивается через LOVE_UNSEND_WINDOW (например, "10m").
+func UnsendWindow() time.Duration {
+	value := os.Getenv("LOVE_UNSEND_WINDOW")
+	if value == "" {
+		return defaultUnsendWindow
+	}
+
+	window, err := time.ParseDuration(value)
+	if err != nil || window < 0 {
+		fmt.Printf("Invalid LOVE_UNSEND_WINDOW %q, using %s\n", value, defaultUnsendWindow)
+		return defaultUnsendWindow
+	}
+	return window
+}
+
+type DeleteResult struct {
+	// Deleted — событие удалено у обоих партнеров; иначе оно только скрыто у отправителя.
+	Deleted   bool
+	PartnerID uuid.UUID
+}
+
+// DeleteLoveEvent удаляет сердечко отправителя. В пределах UnsendWindow событие удаляется целиком,
+// после окна оно скрывается только из истории отправителя.
+func DeleteLoveEvent(db *sql.DB, senderID, eventID uuid.UUID) (DeleteResult, error) {
+	var result DeleteResult
+
+	// БЕЗОПАСНОСТЬ: удалить можно только свое сердечко
+	var createdAt time.Time
+	err := db.QueryRow(
+		"SELECT created_at FROM love_events WHERE id = $1 AND sender_id = $2",
+		eventID, senderID,
+	).Scan(&createdAt)
+	if err == sql.ErrNoRows {
+		return result, ErrLoveEventNotFound
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if time.Since(createdAt) > UnsendWindow() {
+		_, err = db.Exec(
+			"INSERT INTO love_event_hidden (love_event_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			eventID, senderID,
+		)
+		return result, err
+	}
+
+	partnerID, err := deleteLoveEvent(db, senderID, eventID)
+	if err != nil {
+		return result, err
+	}
+
+	result.Deleted = true
+	result.PartnerID = partnerID
+	return result, nil
+}
+
+// deleteLoveEvent удаляет событие и вычитает его из дневных агрегатов в одной транзакции.
+// Возвращает получателя сердечка — второго участника пары события (uuid.Nil, если пары уже нет).
+// Текущая пара отправителя не подходит: после смены партнера отзыв ушел бы не тому человеку.
+func deleteLoveEvent(db *sql.DB, senderID, eventID uuid.UUID) (uuid.UUID, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	var pairID uuid.NullUUID
+	var durationSeconds int
+	var createdAt time.Time
+	var rolledUp bool
+	err = tx.QueryRow(
+		`DELETE FROM love_events WHERE id = $1 AND sender_id = $2
+		RETURNING pair_id, duration_seconds, created_at, rolled_up`,
+		eventID, senderID,
+	).Scan(&pairID, &durationSeconds, &createdAt, &rolledUp)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, ErrLoveEventNotFound
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if rolledUp {
+		if err := removeRollup(tx, senderID, pairID, durationSeconds, createdAt); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	recipientID := uuid.Nil
+	if pairID.Valid {
+		err = tx.QueryRow(
+			"SELECT CASE WHEN user1_id = $2 THEN user2_id ELSE user1_id END FROM pairs WHERE id = $1",
+			pairID.UUID, senderID,
+		).Scan(&recipientID)
+		if err != nil && err != sql.ErrNoRows {
+			return uuid.Nil, err
+		}
+	}
+
+	return recipientID, tx.Commit()
+}
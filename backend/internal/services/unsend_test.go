@@ -0,0 +1,88 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnsendWindow(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"":      defaultUnsendWindow,
+		"10m":   10 * time.Minute,
+		"0":     0,
+		"-1m":   defaultUnsendWindow,
+		"a bit": defaultUnsendWindow,
+	} {
+		t.Setenv("LOVE_UNSEND_WINDOW", value)
+		if got := UnsendWindow(); got != want {
+			t.Errorf("LOVE_UNSEND_WINDOW=%q: UnsendWindow() = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestDeleteLoveEvent(t *testing.T) {
+	db := openTestDB(t)
+	_, user1ID, user2ID := createTestPair(t, db)
+	t.Setenv("LOVE_UNSEND_WINDOW", "5m")
+
+	totalEvents := func() int {
+		t.Helper()
+		stats, err := GetUserStats(db, user1ID)
+		if err != nil {
+			t.Fatalf("GetUserStats: %v", err)
+		}
+		return stats.TotalEvents
+	}
+
+	recent, _, err := CreateLoveEvent(db, user1ID, 3, "")
+	if err != nil {
+		t.Fatalf("CreateLoveEvent: %v", err)
+	}
+	old, _, err := CreateLoveEvent(db, user1ID, 3, "")
+	if err != nil {
+		t.Fatalf("CreateLoveEvent: %v", err)
+	}
+	if _, err := db.Exec("UPDATE love_events SET created_at = NOW() - INTERVAL '10 minutes' WHERE id = $1", old.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Удалить чужое сердечко нельзя
+	if _, err := DeleteLoveEvent(db, user2ID, recent.ID); err != ErrLoveEventNotFound {
+		t.Errorf("partner delete: err = %v, want ErrLoveEventNotFound", err)
+	}
+
+	// В пределах окна сердечко удаляется у обоих и вычитается из статистики
+	result, err := DeleteLoveEvent(db, user1ID, recent.ID)
+	if err != nil {
+		t.Fatalf("DeleteLoveEvent: %v", err)
+	}
+	if !result.Deleted || result.PartnerID != user2ID {
+		t.Errorf("result = %+v, want deleted for %s", result, user2ID)
+	}
+	if n := totalEvents(); n != 1 {
+		t.Errorf("total events after unsend = %d, want 1", n)
+	}
+
+	// После окна сердечко только скрывается из истории отправителя
+	result, err = DeleteLoveEvent(db, user1ID, old.ID)
+	if err != nil {
+		t.Fatalf("DeleteLoveEvent: %v", err)
+	}
+	if result.Deleted {
+		t.Error("event older than the window was deleted")
+	}
+	var hidden bool
+	err = db.QueryRow(
+		"SELECT EXISTS(SELECT 1 FROM love_event_hidden WHERE love_event_id = $1 AND user_id = $2)",
+		old.ID, user1ID,
+	).Scan(&hidden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hidden {
+		t.Error("event older than the window is not hidden from the sender")
+	}
+	if n := totalEvents(); n != 1 {
+		t.Errorf("total events after hiding = %d, want 1", n)
+	}
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/pkg/webpush"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWebPushNotConfigured = errors.New("web push is not configured")
+
+	webPushClient     *webpush.Client
+	webPushClientOnce sync.Once
+)
+
+// getWebPushClient лениво создает клиент Web Push из VAPID_PRIVATE_KEY и VAPID_SUBJECT.
+// Если задан VAPID_PUBLIC_KEY, он должен соответствовать закрытому ключу.
+func getWebPushClient() *webpush.Client {
+	privateKey := os.Getenv("VAPID_PRIVATE_KEY")
+	subject := os.Getenv("VAPID_SUBJECT")
+	if privateKey == "" || subject == "" {
+		return nil
+	}
+
+	webPushClientOnce.Do(func() {
+		var opts []webpush.Option
+		if webPushAllowHTTP() {
+			opts = append(opts, webpush.WithPrivateNetworks())
+		}
+		client, err := webpush.NewClient(privateKey, subject, opts...)
+		if err != nil {
+			fmt.Printf("Failed to create Web Push client: %v\n", err)
+			return
+		}
+		if publicKey := os.Getenv("VAPID_PUBLIC_KEY"); publicKey != "" && publicKey != client.PublicKey() {
+			fmt.Printf("❌ VAPID_PUBLIC_KEY does not match VAPID_PRIVATE_KEY, Web Push is disabled\n")
+			return
+		}
+		webPushClient = client
+	})
+
+	return webPushClient
+}
+
+// webPushAllowHTTP — WEBPUSH_ALLOW_HTTP=true разрешает endpoint по http и во внутренних сетях
+// для локального push-сервиса в тестах. В остальных случаях endpoint должен быть публичным https-адресом.
+func webPushAllowHTTP() bool {
+	return os.Getenv("WEBPUSH_ALLOW_HTTP") == "true"
+}
+
+// WebPushPublicKey — applicationServerKey для pushManager.subscribe в браузере.
+func WebPushPublicKey() (string, error) {
+	client := getWebPushClient()
+	if client == nil {
+		return "", ErrWebPushNotConfigured
+	}
+	return client.PublicKey(), nil
+}
+
+// RegisterWebPushSubscription сохраняет подписку браузера как устройство платформы web.
+// Endpoint по http или во внутренней сети принимается только с WEBPUSH_ALLOW_HTTP=true.
+func RegisterWebPushSubscription(db *sql.DB, userID uuid.UUID, req models.WebPushSubscriptionRequest) (models.Device, error) {
+	sub := webpush.Subscription{Endpoint: req.Endpoint, P256dh: req.Keys.P256dh, Auth: req.Keys.Auth}
+	if err := sub.Validate(); err != nil {
+		return models.Device{}, fmt.Errorf("%w: %v", ErrInvalidDevice, err)
+	}
+	if !webPushAllowHTTP() {
+		endpoint, _ := url.Parse(req.Endpoint)
+		if endpoint.Scheme != "https" {
+			return models.Device{}, fmt.Errorf("%w: endpoint must use https", ErrInvalidDevice)
+		}
+		if err := webpush.CheckHost(endpoint.Hostname()); err != nil {
+			return models.Device{}, fmt.Errorf("%w: %v", ErrInvalidDevice, err)
+		}
+	}
+
+	return scanDevice(db.QueryRow(
+		`INSERT INTO devices (user_id, token, platform, locale, web_push_p256dh, web_push_auth)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (token) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			platform = EXCLUDED.platform,
+			locale = EXCLUDED.locale,
+			web_push_p256dh = EXCLUDED.web_push_p256dh,
+			web_push_auth = EXCLUDED.web_push_auth,
+			active = TRUE,
+			last_seen_at = NOW()
+		RETURNING `+deviceColumns,
+		userID, req.Endpoint, models.DevicePlatformWeb, req.Locale, req.Keys.P256dh, req.Keys.Auth,
+	))
+}
+
+// UnregisterWebPushSubscription удаляет подписку по endpoint, например после pushSubscription.unsubscribe().
+func UnregisterWebPushSubscription(db *sql.DB, userID uuid.UUID, endpoint string) error {
+	result, err := db.Exec(
+		"DELETE FROM devices WHERE user_id = $1 AND token = $2 AND platform = $3",
+		userID, endpoint, models.DevicePlatformWeb,
+	)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+type webPushNotifier struct {
+	client *webpush.Client
+}
+
+// Send отправляет JSON, который разбирает service worker: {"title", "body", "badge", "data"}.
+func (n webPushNotifier) Send(device *models.Device, msg PushMessage) error {
+	if device.WebPushP256dh == nil || device.WebPushAuth == nil {
+		return fmt.Errorf("%w: web push subscription has no keys", ErrPushSkipped)
+	}
+
+	payload := map[string]interface{}{"data": msg.Data}
+	if !msg.Background {
+		payload["title"] = msg.Title
+		payload["body"] = msg.Body
+		payload["badge"] = msg.Badge
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	sub := webpush.Subscription{Endpoint: device.Token, P256dh: *device.WebPushP256dh, Auth: *device.WebPushAuth}
+	return n.client.Send(sub, body, !msg.Background)
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"errors"
+	"love-connection/backend/internal/models"
+	"love-connection/backend/pkg/webpush"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// recordingDriver — database/sql драйвер, который только запоминает выполненные запросы.
+type recordingDriver struct {
+	mu    sync.Mutex
+	execs []recordedExec
+}
+
+type recordedExec struct {
+	Query string
+	Args  []driver.Value
+}
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return recordingConn{d}, nil }
+
+func (d *recordingDriver) recorded() []recordedExec {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]recordedExec(nil), d.execs...)
+}
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{d: c.d, query: query}, nil
+}
+func (c recordingConn) Close() error { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	s.d.execs = append(s.d.execs, recordedExec{Query: s.query, Args: args})
+	s.d.mu.Unlock()
+	return driver.RowsAffected(1), nil
+}
+
+func (s recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	return nil, errors.New("queries are not supported")
+}
+
+func newRecordingDB(t *testing.T) (*sql.DB, *recordingDriver) {
+	t.Helper()
+
+	// sql.Register не дает перерегистрировать имя, поэтому у каждого теста свой драйвер
+	name := "recording-" + uuid.NewString()
+	d := &recordingDriver{}
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func newTestWebPushDevice(t *testing.T, endpoint string) models.Device {
+	t.Helper()
+
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	rand.Read(auth)
+	p256dh := base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes())
+	authSecret := base64.RawURLEncoding.EncodeToString(auth)
+
+	return models.Device{
+		ID:            uuid.New(),
+		Token:         endpoint,
+		Platform:      models.DevicePlatformWeb,
+		WebPushP256dh: &p256dh,
+		WebPushAuth:   &authSecret,
+	}
+}
+
+// Подписку, которую push-сервис считает удаленной (404/410), нужно удалить, а не повторять.
+func TestWebPushGoneDeletesDevice(t *testing.T) {
+	for _, tt := range []struct {
+		status     int
+		wantDelete bool
+	}{
+		{http.StatusNotFound, true},
+		{http.StatusGone, true},
+		{http.StatusInternalServerError, false},
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		defer server.Close()
+
+		_, privateKey, err := webpush.GenerateVAPIDKeys()
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := webpush.NewClient(privateKey, "mailto:push@example.com", webpush.WithPrivateNetworks())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db, recorder := newRecordingDB(t)
+		device := newTestWebPushDevice(t, server.URL+"/push/abc")
+
+		sendErr := webPushNotifier{client: client}.Send(&device, PushMessage{Title: "Love Connection", Body: "hi"})
+		if sendErr == nil {
+			t.Fatalf("status %d: Send succeeded", tt.status)
+		}
+		recordDelivery(db, device, sendErr)
+
+		execs := recorder.recorded()
+		if len(execs) != 1 {
+			t.Fatalf("status %d: got %d statements, want 1", tt.status, len(execs))
+		}
+		deleted := strings.HasPrefix(strings.TrimSpace(execs[0].Query), "DELETE FROM devices")
+		if deleted != tt.wantDelete {
+			t.Errorf("status %d: statement %q, want delete = %v", tt.status, execs[0].Query, tt.wantDelete)
+		}
+		if deleted && (execs[0].Args[0] != device.ID.String() || execs[0].Args[1] != device.Token) {
+			t.Errorf("status %d: deleted %v, want device %s", tt.status, execs[0].Args, device.ID)
+		}
+	}
+}
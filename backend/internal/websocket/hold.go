@@ -0,0 +1,195 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Типы кадров live-удержания. Клиент отправителя шлет их по /ws,
+// хаб пересылает их партнеру с данными, посчитанными на сервере.
+const (
+	HoldStart = "hold_start"
+	HoldTick  = "hold_tick"
+	HoldEnd   = "hold_end"
+)
+
+const (
+	// Если от клиента нет hold_tick дольше этого времени, удержание считается оборванным.
+	holdTickTimeout = 5 * time.Second
+	// Удержание длиннее этого значения принудительно завершается.
+	holdMaxDuration = 10 * time.Minute
+	holdSweepPeriod = time.Second
+)
+
+// HoldHandler связывает хаб с базой: ищет партнера и сохраняет завершенное удержание.
+type HoldHandler interface {
+	PartnerID(userID uuid.UUID) (uuid.UUID, error)
+	CompleteHold(senderID uuid.UUID, durationSeconds int) error
+}
+
+type activeHold struct {
+	partnerID uuid.UUID
+	startedAt time.Time
+	lastTick  time.Time
+}
+
+type inboundFrame struct {
+	Type string `json:"type"`
+}
+
+type holdFrameData struct {
+	SenderID        uuid.UUID `json:"sender_id"`
+	StartedAt       time.Time `json:"started_at"`
+	ElapsedMs       int64     `json:"elapsed_ms"`
+	DurationSeconds int       `json:"duration_seconds,omitempty"`
+	Cancelled       bool      `json:"cancelled,omitempty"`
+}
+
+func (h *Hub) SetHoldHandler(handler HoldHandler) {
+	h.holdHandler = handler
+}
+
+func (h *Hub) handleFrame(client *Client, message []byte) {
+	var frame inboundFrame
+	if err := json.Unmarshal(message, &frame); err != nil {
+		return
+	}
+
+	// Удержание принадлежит соединению: кадры другого соединения того же пользователя его не трогают
+	switch frame.Type {
+	case HoldStart:
+		h.startHold(client)
+	case HoldTick:
+		h.tickHold(client)
+	case HoldEnd:
+		h.endHold(client)
+	}
+}
+
+func (h *Hub) startHold(client *Client) {
+	if h.holdHandler == nil {
+		return
+	}
+
+	userID := client.userID
+	partnerID, err := h.holdHandler.PartnerID(userID)
+	if err != nil {
+		return
+	}
+
+	now := h.now()
+	hold := &activeHold{partnerID: partnerID, startedAt: now, lastTick: now}
+
+	h.holdsMu.Lock()
+	h.holds[client] = hold
+	h.holdsMu.Unlock()
+
+	h.SendToUser(partnerID, HoldStart, holdFrameData{SenderID: userID, StartedAt: now})
+}
+
+func (h *Hub) tickHold(client *Client) {
+	now := h.now()
+
+	h.holdsMu.Lock()
+	hold, ok := h.holds[client]
+	if ok {
+		hold.lastTick = now
+	}
+	h.holdsMu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	h.SendToUser(hold.partnerID, HoldTick, holdFrameData{
+		SenderID:  client.userID,
+		StartedAt: hold.startedAt,
+		ElapsedMs: now.Sub(hold.startedAt).Milliseconds(),
+	})
+}
+
+func (h *Hub) endHold(client *Client) {
+	hold := h.takeHold(client)
+	if hold == nil {
+		return
+	}
+	userID := client.userID
+
+	// Длительность измеряет сервер, клиент ее не передает
+	elapsed := h.now().Sub(hold.startedAt)
+	if elapsed > holdMaxDuration {
+		elapsed = holdMaxDuration
+	}
+	durationSeconds := int(elapsed.Round(time.Second) / time.Second)
+
+	if durationSeconds < 1 {
+		h.cancelHold(userID, hold)
+		return
+	}
+
+	// Сначала сохраняем сердечко: партнер не должен увидеть событие, которого нет в базе
+	if err := h.holdHandler.CompleteHold(userID, durationSeconds); err != nil {
+		log.Printf("Failed to complete hold for user %s: %v", userID, err)
+		h.cancelHold(userID, hold)
+		return
+	}
+
+	h.SendToUser(hold.partnerID, HoldEnd, holdFrameData{
+		SenderID:        userID,
+		StartedAt:       hold.startedAt,
+		ElapsedMs:       elapsed.Milliseconds(),
+		DurationSeconds: durationSeconds,
+	})
+}
+
+// cancelHold сообщает партнеру, что удержание прервано без отправки сердечка.
+func (h *Hub) cancelHold(userID uuid.UUID, hold *activeHold) {
+	h.SendToUser(hold.partnerID, HoldEnd, holdFrameData{
+		SenderID:  userID,
+		StartedAt: hold.startedAt,
+		ElapsedMs: h.now().Sub(hold.startedAt).Milliseconds(),
+		Cancelled: true,
+	})
+}
+
+// takeHold забирает удержание, начатое соединением client.
+func (h *Hub) takeHold(client *Client) *activeHold {
+	h.holdsMu.Lock()
+	defer h.holdsMu.Unlock()
+
+	hold, ok := h.holds[client]
+	if !ok {
+		return nil
+	}
+	delete(h.holds, client)
+	return hold
+}
+
+// expireHolds обрывает удержания без hold_tick и завершает слишком длинные удержания.
+func (h *Hub) expireHolds() {
+	now := h.now()
+	expired := make(map[*Client]*activeHold)
+	overlong := make([]*Client, 0)
+
+	h.holdsMu.Lock()
+	for client, hold := range h.holds {
+		if now.Sub(hold.lastTick) > holdTickTimeout {
+			expired[client] = hold
+			delete(h.holds, client)
+		} else if now.Sub(hold.startedAt) >= holdMaxDuration {
+			overlong = append(overlong, client)
+		}
+	}
+	h.holdsMu.Unlock()
+
+	for client, hold := range expired {
+		h.cancelHold(client.userID, hold)
+	}
+
+	for _, client := range overlong {
+		go h.endHold(client)
+	}
+}
@@ -0,0 +1,282 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type completedHold struct {
+	senderID        uuid.UUID
+	durationSeconds int
+}
+
+// fakeHoldHandler — партнер всегда partnerID, завершенные удержания пишутся в completed.
+type fakeHoldHandler struct {
+	partnerID uuid.UUID
+	err       error
+	completed chan completedHold
+}
+
+func (f *fakeHoldHandler) PartnerID(uuid.UUID) (uuid.UUID, error) {
+	return f.partnerID, nil
+}
+
+func (f *fakeHoldHandler) CompleteHold(senderID uuid.UUID, durationSeconds int) error {
+	f.completed <- completedHold{senderID, durationSeconds}
+	return f.err
+}
+
+type holdTest struct {
+	hub     *Hub
+	handler *fakeHoldHandler
+	sender  *Client
+	partner *Client
+	now     time.Time
+}
+
+// newHoldTest создает хаб с часами t.now и подключенным партнером; Run не запускается.
+func newHoldTest() *holdTest {
+	ht := &holdTest{hub: NewHub(), now: time.Date(2026, 2, 14, 12, 0, 0, 0, time.UTC)}
+	ht.hub.now = func() time.Time { return ht.now }
+
+	ht.sender = &Client{hub: ht.hub, userID: uuid.New(), send: make(chan []byte, 16)}
+	ht.partner = &Client{hub: ht.hub, userID: uuid.New(), send: make(chan []byte, 16)}
+	ht.hub.clients[ht.sender.userID] = ht.sender
+	ht.hub.clients[ht.partner.userID] = ht.partner
+
+	ht.handler = &fakeHoldHandler{partnerID: ht.partner.userID, completed: make(chan completedHold, 1)}
+	ht.hub.SetHoldHandler(ht.handler)
+	return ht
+}
+
+func (ht *holdTest) frame(client *Client, frameType string) {
+	ht.hub.handleFrame(client, []byte(`{"type": "`+frameType+`"}`))
+}
+
+type receivedFrame struct {
+	Type string        `json:"type"`
+	Data holdFrameData `json:"data"`
+}
+
+// received возвращает кадры, которые получил партнер.
+func (ht *holdTest) received(t *testing.T) []receivedFrame {
+	t.Helper()
+
+	var frames []receivedFrame
+	for {
+		select {
+		case data := <-ht.partner.send:
+			var frame receivedFrame
+			if err := json.Unmarshal(data, &frame); err != nil {
+				t.Fatal(err)
+			}
+			frames = append(frames, frame)
+		default:
+			return frames
+		}
+	}
+}
+
+func (ht *holdTest) completed() *completedHold {
+	select {
+	case c := <-ht.handler.completed:
+		return &c
+	default:
+		return nil
+	}
+}
+
+func TestHoldStartAndTick(t *testing.T) {
+	ht := newHoldTest()
+	startedAt := ht.now
+
+	ht.frame(ht.sender, HoldStart)
+	ht.now = ht.now.Add(1500 * time.Millisecond)
+	ht.frame(ht.sender, HoldTick)
+
+	frames := ht.received(t)
+	if len(frames) != 2 {
+		t.Fatalf("partner got %d frames, want 2", len(frames))
+	}
+	if frames[0].Type != HoldStart || frames[0].Data.SenderID != ht.sender.userID || !frames[0].Data.StartedAt.Equal(startedAt) {
+		t.Errorf("start frame = %+v", frames[0])
+	}
+	if frames[1].Type != HoldTick || frames[1].Data.ElapsedMs != 1500 || !frames[1].Data.StartedAt.Equal(startedAt) {
+		t.Errorf("tick frame = %+v", frames[1])
+	}
+
+	// Тик продлевает удержание
+	ht.now = ht.now.Add(holdTickTimeout)
+	ht.hub.expireHolds()
+	if frames := ht.received(t); len(frames) != 0 {
+		t.Errorf("hold expired despite the tick: %+v", frames)
+	}
+}
+
+func TestEndHold(t *testing.T) {
+	tests := []struct {
+		name         string
+		held         time.Duration
+		err          error
+		wantDuration int
+		wantComplete bool
+	}{
+		{"under half a second", 400 * time.Millisecond, nil, 0, false},
+		{"rounded up", 1500 * time.Millisecond, nil, 2, true},
+		{"rounded down", 3400 * time.Millisecond, nil, 3, true},
+		{"capped at ten minutes", 12 * time.Minute, nil, 600, true},
+		{"not saved", 3 * time.Second, errors.New("database is down"), 3, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ht := newHoldTest()
+			ht.handler.err = tt.err
+
+			ht.frame(ht.sender, HoldStart)
+			ht.now = ht.now.Add(tt.held)
+			ht.frame(ht.sender, HoldEnd)
+
+			completed := ht.completed()
+			if tt.wantComplete != (completed != nil) {
+				t.Fatalf("CompleteHold called: %v, want %v", completed != nil, tt.wantComplete)
+			}
+			if completed != nil && (completed.senderID != ht.sender.userID || completed.durationSeconds != tt.wantDuration) {
+				t.Errorf("CompleteHold(%s, %d), want (%s, %d)", completed.senderID, completed.durationSeconds, ht.sender.userID, tt.wantDuration)
+			}
+
+			frames := ht.received(t)
+			end := frames[len(frames)-1]
+			if end.Type != HoldEnd {
+				t.Fatalf("last frame = %s, want %s", end.Type, HoldEnd)
+			}
+			wantCancelled := !tt.wantComplete || tt.err != nil
+			if end.Data.Cancelled != wantCancelled {
+				t.Errorf("cancelled = %v, want %v", end.Data.Cancelled, wantCancelled)
+			}
+			if !wantCancelled && end.Data.DurationSeconds != tt.wantDuration {
+				t.Errorf("duration_seconds = %d, want %d", end.Data.DurationSeconds, tt.wantDuration)
+			}
+
+			// Удержание завершено: повторный hold_end ничего не делает
+			ht.frame(ht.sender, HoldEnd)
+			if ht.completed() != nil || len(ht.received(t)) != 0 {
+				t.Error("second hold_end was handled")
+			}
+		})
+	}
+}
+
+func TestExpireHolds(t *testing.T) {
+	tests := []struct {
+		name string
+		// advance сдвигает часы после hold_start; тики идут каждые tickEvery (0 — без тиков).
+		advance      time.Duration
+		tickEvery    time.Duration
+		wantEnd      bool
+		wantDuration int
+	}{
+		{"ticking", 30 * time.Second, 2 * time.Second, false, 0},
+		{"within tick timeout", holdTickTimeout, 0, false, 0},
+		{"tick timeout", holdTickTimeout + time.Millisecond, 0, true, 0},
+		{"ticks stopped", 20 * time.Second, 0, true, 0},
+		{"ten minute cap", holdMaxDuration, 2 * time.Second, true, 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ht := newHoldTest()
+			ht.frame(ht.sender, HoldStart)
+
+			if tt.tickEvery > 0 {
+				for elapsed := tt.tickEvery; elapsed <= tt.advance; elapsed += tt.tickEvery {
+					ht.now = ht.now.Add(tt.tickEvery)
+					ht.frame(ht.sender, HoldTick)
+					ht.received(t)
+				}
+			} else {
+				ht.now = ht.now.Add(tt.advance)
+			}
+			ht.received(t)
+
+			ht.hub.expireHolds()
+
+			var completed *completedHold
+			if tt.wantDuration > 0 {
+				// Слишком длинное удержание завершается в отдельной горутине
+				select {
+				case c := <-ht.handler.completed:
+					completed = &c
+				case <-time.After(time.Second):
+					t.Fatal("CompleteHold was not called")
+				}
+				if completed.durationSeconds != tt.wantDuration {
+					t.Errorf("CompleteHold duration = %d, want %d", completed.durationSeconds, tt.wantDuration)
+				}
+				// hold_end отправляется после CompleteHold; ждем, пока он дойдет
+				for deadline := time.Now().Add(time.Second); len(ht.partner.send) == 0 && time.Now().Before(deadline); {
+					time.Sleep(time.Millisecond)
+				}
+			} else if ht.completed() != nil {
+				t.Error("expired hold was saved")
+			}
+
+			frames := ht.received(t)
+			if !tt.wantEnd {
+				if len(frames) != 0 {
+					t.Errorf("partner got %+v, want nothing", frames)
+				}
+				return
+			}
+			if len(frames) != 1 || frames[0].Type != HoldEnd {
+				t.Fatalf("partner got %+v, want one %s", frames, HoldEnd)
+			}
+			if frames[0].Data.Cancelled != (tt.wantDuration == 0) {
+				t.Errorf("cancelled = %v", frames[0].Data.Cancelled)
+			}
+		})
+	}
+}
+
+// Кадры от другого соединения того же пользователя не завершают и не продлевают удержание.
+func TestHoldBelongsToConnection(t *testing.T) {
+	ht := newHoldTest()
+	other := &Client{hub: ht.hub, userID: ht.sender.userID, send: make(chan []byte, 16)}
+
+	ht.frame(ht.sender, HoldStart)
+	ht.received(t)
+
+	ht.now = ht.now.Add(3 * time.Second)
+	ht.frame(other, HoldTick)
+	ht.frame(other, HoldEnd)
+	if frames := ht.received(t); len(frames) != 0 {
+		t.Errorf("partner got %+v from another connection", frames)
+	}
+	if ht.completed() != nil {
+		t.Error("hold_end from another connection saved the hold")
+	}
+
+	// Тик другого соединения не продлил удержание
+	ht.now = ht.now.Add(3 * time.Second)
+	ht.hub.expireHolds()
+	frames := ht.received(t)
+	if len(frames) != 1 || !frames[0].Data.Cancelled {
+		t.Errorf("partner got %+v, want a cancelled %s", frames, HoldEnd)
+	}
+
+	// Разрыв другого соединения не трогает удержание этого
+	ht.frame(ht.sender, HoldStart)
+	ht.received(t)
+	if ht.hub.takeHold(other) != nil {
+		t.Error("takeHold returned the hold of another connection")
+	}
+	ht.now = ht.now.Add(2 * time.Second)
+	ht.frame(ht.sender, HoldEnd)
+	if completed := ht.completed(); completed == nil || completed.durationSeconds != 2 {
+		t.Errorf("CompleteHold = %+v, want 2 seconds", completed)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"love-connection/backend/internal/models"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -23,6 +24,12 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
+
+	holdHandler HoldHandler
+	holds       map[*Client]*activeHold
+	holdsMu     sync.Mutex
+	// now — часы для удержаний; в тестах подменяются.
+	now func() time.Time
 }
 
 type Client struct {
@@ -38,25 +45,40 @@ func NewHub() *Hub {
 		broadcast:  make(chan []byte),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		holds:      make(map[*Client]*activeHold),
+		now:        time.Now,
 	}
 }
 
 func (h *Hub) Run() {
+	holdSweep := time.NewTicker(holdSweepPeriod)
+	defer holdSweep.Stop()
+
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
+			// Переподключение: старое соединение закрывается, его unregister уже ничего не тронет
+			if old, ok := h.clients[client.userID]; ok {
+				close(old.send)
+			}
 			h.clients[client.userID] = client
 			h.mu.Unlock()
 
 		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[client.userID]; ok {
+			if current, ok := h.clients[client.userID]; ok && current == client {
 				delete(h.clients, client.userID)
 				close(client.send)
 			}
 			h.mu.Unlock()
 
+			// Соединение оборвалось посреди удержания. Удержание нового соединения
+			// того же пользователя не трогаем.
+			if hold := h.takeHold(client); hold != nil {
+				go h.cancelHold(client.userID, hold)
+			}
+
 		case message := <-h.broadcast:
 			h.mu.RLock()
 			for _, client := range h.clients {
@@ -68,6 +90,9 @@ func (h *Hub) Run() {
 				}
 			}
 			h.mu.RUnlock()
+
+		case <-holdSweep.C:
+			h.expireHolds()
 		}
 	}
 }
@@ -92,28 +117,36 @@ func (h *Hub) HandleWebSocket(w http.ResponseWriter, r *http.Request, userID uui
 	go client.readPump()
 }
 
-func (h *Hub) BroadcastLoveEvent(event models.LoveEvent, recipientID uuid.UUID) {
+// BroadcastLoveEvent возвращает true, если событие передано подключенному клиенту получателя.
+func (h *Hub) BroadcastLoveEvent(event models.LoveEvent, recipientID uuid.UUID) bool {
+	return h.SendToUser(recipientID, "love_event", event)
+}
+
+func (h *Hub) SendToUser(recipientID uuid.UUID, messageType string, payload interface{}) bool {
 	message := map[string]interface{}{
-		"type": "love_event",
-		"data": event,
+		"type": messageType,
+		"data": payload,
 	}
 
 	data, err := json.Marshal(message)
 	if err != nil {
-		return
+		return false
 	}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
 	if client, ok := h.clients[recipientID]; ok {
 		select {
 		case client.send <- data:
+			return true
 		default:
 			close(client.send)
 			delete(h.clients, recipientID)
 		}
 	}
+
+	return false
 }
 
 func (c *Client) readPump() {
@@ -123,13 +156,15 @@ func (c *Client) readPump() {
 	}()
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
+
+		c.hub.handleFrame(c, message)
 	}
 }
 
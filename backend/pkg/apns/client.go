@@ -1,26 +1,108 @@
 package apns
 
 import (
+	"bytes"
 	"crypto/ecdsa"
+	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+const (
+	ProductionURL  = "https://api.push.apple.com"
+	DevelopmentURL = "https://api.sandbox.push.apple.com"
+
+	defaultExpiration = 24 * time.Hour
+	requestTimeout    = 30 * time.Second
+)
+
+// Environment — окружение APNs, для которого выдан токен устройства.
+// Сборки из Xcode получают токены sandbox, App Store и TestFlight — production.
+type Environment string
+
+const (
+	Production  Environment = "production"
+	Development Environment = "development"
+)
+
+// ParseEnvironment разбирает окружение, присланное приложением; пустая строка — production.
+func ParseEnvironment(s string) (Environment, error) {
+	switch Environment(strings.ToLower(strings.TrimSpace(s))) {
+	case "", Production:
+		return Production, nil
+	case Development, "sandbox":
+		return Development, nil
+	}
+	return "", fmt.Errorf("unknown APNs environment %q", s)
+}
+
+func (e Environment) other() Environment {
+	if e == Development {
+		return Production
+	}
+	return Development
+}
+
 type Client struct {
-	keyID     string
-	teamID    string
-	bundleID  string
+	keyID      string
+	teamID     string
+	bundleID   string
 	privateKey *ecdsa.PrivateKey
-	token     string
-	tokenExp  time.Time
+
+	// baseURLs — хосты по окружениям; в тестах заменяются локальным HTTP/2 сервером.
+	baseURLs   map[Environment]string
+	expiration time.Duration
+	httpClient *http.Client
+
+	tokenMu  sync.Mutex
+	token    string
+	tokenExp time.Time
+}
+
+type Option func(*Client)
+
+// WithBaseURL отправляет пуши обоих окружений на указанный адрес вместо хостов Apple.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURLs[Production] = strings.TrimRight(baseURL, "/")
+		c.baseURLs[Development] = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithEnvironmentURL заменяет хост Apple только для одного окружения.
+func WithEnvironmentURL(env Environment, baseURL string) Option {
+	return func(c *Client) {
+		c.baseURLs[env] = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithHTTPClient задает HTTP-клиент; у него должен быть включен HTTP/2.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithExpiration задает, сколько APNs хранит пуш, если устройство недоступно (0 — не хранить).
+func WithExpiration(expiration time.Duration) Option {
+	return func(c *Client) {
+		c.expiration = expiration
+	}
 }
 
-func NewClient(keyPath, keyID, teamID, bundleID string) (*Client, error) {
+func NewClient(keyPath, keyID, teamID, bundleID string, opts ...Option) (*Client, error) {
 	keyData, err := os.ReadFile(keyPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read key file: %w", err)
@@ -41,15 +123,41 @@ func NewClient(keyPath, keyID, teamID, bundleID string) (*Client, error) {
 		return nil, fmt.Errorf("key is not ECDSA private key")
 	}
 
-	return &Client{
-		keyID:     keyID,
-		teamID:    teamID,
-		bundleID:  bundleID,
+	c := &Client{
+		keyID:      keyID,
+		teamID:     teamID,
+		bundleID:   bundleID,
 		privateKey: ecdsaKey,
-	}, nil
+		baseURLs: map[Environment]string{
+			Production:  ProductionURL,
+			Development: DevelopmentURL,
+		},
+		expiration: defaultExpiration,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.httpClient == nil {
+		// Один Transport на клиента: соединение HTTP/2 с APNs переиспользуется между пушами
+		c.httpClient = &http.Client{
+			Timeout: requestTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig:     &tls.Config{MinVersion: tls.VersionTLS12},
+				ForceAttemptHTTP2:   true,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     time.Hour,
+			},
+		}
+	}
+
+	return c, nil
 }
 
 func (c *Client) generateToken() (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
 	if time.Now().Before(c.tokenExp) {
 		return c.token, nil
 	}
@@ -74,17 +182,16 @@ func (c *Client) generateToken() (string, error) {
 	return tokenString, nil
 }
 
-func (c *Client) SendNotification(deviceToken, title, body string) error {
-	token, err := c.generateToken()
-	if err != nil {
-		return err
-	}
-
-	url := fmt.Sprintf("https://api.sandbox.push.apple.com/3/device/%s", deviceToken)
-	if c.isProduction() {
-		url = fmt.Sprintf("https://api.push.apple.com/3/device/%s", deviceToken)
-	}
+// resetToken сбрасывает закэшированный JWT, если APNs счел его просроченным.
+func (c *Client) resetToken() {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.tokenExp = time.Time{}
+}
 
+// SendNotification отправляет alert-пуш на хост окружения env. Ключи data добавляются в payload рядом с "aps".
+// Возвращает окружение, в котором APNs принял токен: оно отличается от env, если сработал fallback.
+func (c *Client) SendNotification(deviceToken string, env Environment, title, body string, badge int, data map[string]interface{}) (Environment, error) {
 	payload := map[string]interface{}{
 		"aps": map[string]interface{}{
 			"alert": map[string]string{
@@ -92,22 +199,105 @@ func (c *Client) SendNotification(deviceToken, title, body string) error {
 				"body":  body,
 			},
 			"sound": "default",
-			"badge": 1,
+			"badge": badge,
 		},
 	}
 
-	return c.sendHTTP2Request(url, token, payload)
+	return c.send(deviceToken, env, "alert", 10, payload, data)
 }
 
-func (c *Client) isProduction() bool {
-	return true
+// SendBackgroundNotification отправляет тихий пуш (content-available), который будит приложение без alert.
+// Apple требует для таких пушей приоритет 5.
+func (c *Client) SendBackgroundNotification(deviceToken string, env Environment, data map[string]interface{}) (Environment, error) {
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"content-available": 1,
+		},
+	}
+
+	return c.send(deviceToken, env, "background", 5, payload, data)
 }
 
-func (c *Client) sendHTTP2Request(url, token string, payload map[string]interface{}) error {
-	// TODO: Implement HTTP/2 client for APNs
-	// This requires http2 package and proper TLS configuration
-	// For now, this is a placeholder that logs the notification
-	fmt.Printf("APNs Notification to %s: %+v\n", url, payload)
-	return nil
+// send отправляет пуш в окружение env. Если APNs отвечает BadDeviceToken, токен, скорее всего,
+// из другого окружения (например, сборка из Xcode отметилась как production), поэтому пуш
+// повторяется на втором хосте.
+func (c *Client) send(deviceToken string, env Environment, pushType string, priority int, payload, data map[string]interface{}) (Environment, error) {
+	if env != Development {
+		env = Production
+	}
+
+	for key, value := range data {
+		if key != "aps" {
+			payload[key] = value
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return env, err
+	}
+
+	err = c.sendTo(env, deviceToken, pushType, priority, body)
+	if !errors.Is(err, ErrBadDeviceToken) || c.baseURLs[env] == c.baseURLs[env.other()] {
+		return env, err
+	}
+
+	// Окружение меняется только после успешной доставки: сбой второго хоста (например, 503)
+	// не доказывает, что токен из другого окружения
+	fallbackErr := c.sendTo(env.other(), deviceToken, pushType, priority, body)
+	if fallbackErr == nil {
+		return env.other(), nil
+	}
+	if !errors.Is(fallbackErr, ErrBadDeviceToken) {
+		return env, fallbackErr
+	}
+	return env, err
 }
 
+func (c *Client) sendTo(env Environment, deviceToken, pushType string, priority int, body []byte) error {
+	url := fmt.Sprintf("%s/3/device/%s", c.baseURLs[env], deviceToken)
+	err := c.sendHTTP2Request(url, pushType, priority, body)
+	if errors.Is(err, ErrExpiredProviderToken) {
+		// JWT истек раньше, чем мы ожидали: выпускаем новый и пробуем еще раз
+		c.resetToken()
+		err = c.sendHTTP2Request(url, pushType, priority, body)
+	}
+	return err
+}
+
+func (c *Client) sendHTTP2Request(url, pushType string, priority int, body []byte) error {
+	token, err := c.generateToken()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	expiration := int64(0)
+	if c.expiration > 0 {
+		expiration = time.Now().Add(c.expiration).Unix()
+	}
+
+	req.Header.Set("authorization", "bearer "+token)
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("apns-topic", c.bundleID)
+	req.Header.Set("apns-push-type", pushType)
+	req.Header.Set("apns-priority", strconv.Itoa(priority))
+	req.Header.Set("apns-expiration", strconv.FormatInt(expiration, 10))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("apns request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	return parseError(resp)
+}
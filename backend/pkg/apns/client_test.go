@@ -0,0 +1,352 @@
+package apns
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testKeyID    = "KEY1234567"
+	testTeamID   = "TEAM123456"
+	testBundleID = "com.example.love"
+	testToken    = "a1b2c3d4e5f6"
+)
+
+// apnsRequest — то, что увидел локальный APNs.
+type apnsRequest struct {
+	Path   string
+	Proto  int
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// fakeAPNs — HTTP/2 сервер вместо api.push.apple.com; respond возвращает статус и тело для n-го запроса (с 0).
+type fakeAPNs struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	requests []apnsRequest
+}
+
+func newFakeAPNs(t *testing.T, respond func(n int) (int, string)) *fakeAPNs {
+	t.Helper()
+
+	f := &fakeAPNs{}
+	f.server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		json.Unmarshal(data, &body)
+
+		f.mu.Lock()
+		n := len(f.requests)
+		f.requests = append(f.requests, apnsRequest{Path: r.URL.Path, Proto: r.ProtoMajor, Header: r.Header.Clone(), Body: body})
+		f.mu.Unlock()
+
+		status, response := respond(n)
+		w.Header().Set("apns-id", "apns-id-"+strconv.Itoa(n))
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	}))
+	f.server.EnableHTTP2 = true
+	f.server.StartTLS()
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeAPNs) received() []apnsRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]apnsRequest(nil), f.requests...)
+}
+
+func newTestClient(t *testing.T, f *fakeAPNs, opts ...Option) (*Client, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "AuthKey.p8")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	opts = append([]Option{WithBaseURL(f.server.URL), WithHTTPClient(f.server.Client())}, opts...)
+	client, err := NewClient(keyPath, testKeyID, testTeamID, testBundleID, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, key
+}
+
+func ok(int) (int, string) { return http.StatusOK, "" }
+
+func TestSendNotificationHeaders(t *testing.T) {
+	f := newFakeAPNs(t, ok)
+	client, key := newTestClient(t, f)
+
+	before := time.Now()
+	env, err := client.SendNotification(testToken, Production, "Love Connection", "hi", 3, map[string]interface{}{"love_event_id": "42"})
+	if err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+	if env != Production {
+		t.Errorf("environment = %s, want production", env)
+	}
+
+	requests := f.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+
+	if req.Proto != 2 {
+		t.Errorf("request used HTTP/%d, want HTTP/2", req.Proto)
+	}
+	if req.Path != "/3/device/"+testToken {
+		t.Errorf("path = %s", req.Path)
+	}
+	for header, want := range map[string]string{
+		"apns-topic":     testBundleID,
+		"apns-push-type": "alert",
+		"apns-priority":  "10",
+		"content-type":   "application/json",
+	} {
+		if got := req.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	expiration, err := strconv.ParseInt(req.Header.Get("apns-expiration"), 10, 64)
+	if err != nil {
+		t.Fatalf("apns-expiration: %v", err)
+	}
+	if want := before.Add(defaultExpiration).Unix(); expiration < want || expiration > want+5 {
+		t.Errorf("apns-expiration = %d, want about %d", expiration, want)
+	}
+
+	bearer, found := strings.CutPrefix(req.Header.Get("authorization"), "bearer ")
+	if !found {
+		t.Fatalf("authorization = %q, want bearer token", req.Header.Get("authorization"))
+	}
+	token, err := jwt.Parse(bearer, func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil },
+		jwt.WithValidMethods([]string{"ES256"}))
+	if err != nil {
+		t.Fatalf("provider token: %v", err)
+	}
+	if kid := token.Header["kid"]; kid != testKeyID {
+		t.Errorf("kid = %v, want %s", kid, testKeyID)
+	}
+	if iss, _ := token.Claims.GetIssuer(); iss != testTeamID {
+		t.Errorf("iss = %s, want %s", iss, testTeamID)
+	}
+
+	aps, _ := req.Body["aps"].(map[string]interface{})
+	alert, _ := aps["alert"].(map[string]interface{})
+	if alert["title"] != "Love Connection" || alert["body"] != "hi" || aps["badge"] != float64(3) {
+		t.Errorf("aps = %v", aps)
+	}
+	if req.Body["love_event_id"] != "42" {
+		t.Errorf("custom data missing from payload: %v", req.Body)
+	}
+}
+
+func TestSendBackgroundNotificationHeaders(t *testing.T) {
+	f := newFakeAPNs(t, ok)
+	client, _ := newTestClient(t, f, WithExpiration(0))
+
+	if _, err := client.SendBackgroundNotification(testToken, Production, map[string]interface{}{"deleted_love_event_id": "42"}); err != nil {
+		t.Fatalf("SendBackgroundNotification: %v", err)
+	}
+
+	req := f.received()[0]
+	if got := req.Header.Get("apns-push-type"); got != "background" {
+		t.Errorf("apns-push-type = %q, want background", got)
+	}
+	if got := req.Header.Get("apns-priority"); got != "5" {
+		t.Errorf("apns-priority = %q, want 5", got)
+	}
+	if got := req.Header.Get("apns-expiration"); got != "0" {
+		t.Errorf("apns-expiration = %q, want 0", got)
+	}
+	if aps, _ := req.Body["aps"].(map[string]interface{}); aps["content-available"] != float64(1) || aps["alert"] != nil {
+		t.Errorf("aps = %v", aps)
+	}
+}
+
+func TestUnregisteredError(t *testing.T) {
+	invalidSince := time.UnixMilli(1700000000123)
+	f := newFakeAPNs(t, func(int) (int, string) {
+		return http.StatusGone, `{"reason":"Unregistered","timestamp":` + strconv.FormatInt(invalidSince.UnixMilli(), 10) + `}`
+	})
+	client, _ := newTestClient(t, f)
+
+	_, err := client.SendNotification(testToken, Production, "t", "b", 0, nil)
+	if !errors.Is(err, ErrUnregistered) {
+		t.Fatalf("err = %v, want ErrUnregistered", err)
+	}
+	if errors.Is(err, ErrBadDeviceToken) {
+		t.Errorf("err matches ErrBadDeviceToken")
+	}
+
+	var apnsErr *Error
+	if !errors.As(err, &apnsErr) {
+		t.Fatalf("err is %T, want *Error", err)
+	}
+	if apnsErr.StatusCode != http.StatusGone {
+		t.Errorf("StatusCode = %d, want 410", apnsErr.StatusCode)
+	}
+	if !apnsErr.Timestamp.Equal(invalidSince) {
+		t.Errorf("Timestamp = %s, want %s", apnsErr.Timestamp, invalidSince)
+	}
+	if apnsErr.APNsID != "apns-id-0" {
+		t.Errorf("APNsID = %q", apnsErr.APNsID)
+	}
+	if n := len(f.received()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestErrorWithoutBody(t *testing.T) {
+	f := newFakeAPNs(t, func(int) (int, string) { return http.StatusServiceUnavailable, "" })
+	client, _ := newTestClient(t, f)
+
+	_, err := client.SendNotification(testToken, Production, "t", "b", 0, nil)
+	var apnsErr *Error
+	if !errors.As(err, &apnsErr) || apnsErr.StatusCode != http.StatusServiceUnavailable || apnsErr.Reason != "Service Unavailable" {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestExpiredProviderTokenRetriedOnce(t *testing.T) {
+	f := newFakeAPNs(t, func(n int) (int, string) {
+		if n == 0 {
+			return http.StatusForbidden, `{"reason":"ExpiredProviderToken"}`
+		}
+		return http.StatusOK, ""
+	})
+	client, _ := newTestClient(t, f)
+
+	if _, err := client.SendNotification(testToken, Production, "t", "b", 0, nil); err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+
+	requests := f.received()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if requests[0].Header.Get("authorization") == requests[1].Header.Get("authorization") {
+		t.Errorf("retry reused the expired provider token")
+	}
+}
+
+func TestExpiredProviderTokenNotRetriedTwice(t *testing.T) {
+	f := newFakeAPNs(t, func(int) (int, string) {
+		return http.StatusForbidden, `{"reason":"ExpiredProviderToken"}`
+	})
+	client, _ := newTestClient(t, f)
+
+	_, err := client.SendNotification(testToken, Production, "t", "b", 0, nil)
+	if !errors.Is(err, ErrExpiredProviderToken) {
+		t.Fatalf("err = %v, want ErrExpiredProviderToken", err)
+	}
+	if n := len(f.received()); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+}
+
+func TestProviderTokenCached(t *testing.T) {
+	f := newFakeAPNs(t, ok)
+	client, _ := newTestClient(t, f)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.SendNotification(testToken, Production, "t", "b", 0, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requests := f.received()
+	if requests[0].Header.Get("authorization") != requests[1].Header.Get("authorization") {
+		t.Errorf("provider token was not reused between pushes")
+	}
+}
+
+// newEnvironmentServers поднимает отдельные production и sandbox хосты.
+func newEnvironmentServers(t *testing.T, production, development func(n int) (int, string)) (*Client, *fakeAPNs, *fakeAPNs) {
+	t.Helper()
+
+	prod := newFakeAPNs(t, production)
+	dev := newFakeAPNs(t, development)
+
+	// Оба сервера подписаны одним сертификатом httptest, поэтому клиент одного подходит и для второго
+	client, _ := newTestClient(t, prod, WithEnvironmentURL(Development, dev.server.URL))
+	return client, prod, dev
+}
+
+func badDeviceToken(int) (int, string) {
+	return http.StatusBadRequest, `{"reason":"BadDeviceToken"}`
+}
+
+func TestBadDeviceTokenFallsBackToOtherEnvironment(t *testing.T) {
+	client, prod, dev := newEnvironmentServers(t, badDeviceToken, ok)
+
+	env, err := client.SendNotification(testToken, Production, "t", "b", 0, nil)
+	if err != nil {
+		t.Fatalf("SendNotification: %v", err)
+	}
+	if env != Development {
+		t.Errorf("environment = %s, want development", env)
+	}
+	if len(prod.received()) != 1 || len(dev.received()) != 1 {
+		t.Errorf("requests: production %d, development %d; want 1 and 1", len(prod.received()), len(dev.received()))
+	}
+}
+
+func TestFailedFallbackKeepsEnvironment(t *testing.T) {
+	unavailable := func(int) (int, string) {
+		return http.StatusServiceUnavailable, `{"reason":"ServiceUnavailable"}`
+	}
+	client, _, _ := newEnvironmentServers(t, badDeviceToken, unavailable)
+
+	env, err := client.SendNotification(testToken, Production, "t", "b", 0, nil)
+	if !errors.Is(err, ErrServiceUnavailable) {
+		t.Fatalf("err = %v, want ErrServiceUnavailable", err)
+	}
+	if env != Production {
+		t.Errorf("environment = %s, want production: a failed fallback proves nothing about the token", env)
+	}
+}
+
+func TestBadDeviceTokenInBothEnvironments(t *testing.T) {
+	client, _, _ := newEnvironmentServers(t, badDeviceToken, badDeviceToken)
+
+	env, err := client.SendNotification(testToken, Development, "t", "b", 0, nil)
+	if !errors.Is(err, ErrBadDeviceToken) {
+		t.Fatalf("err = %v, want ErrBadDeviceToken", err)
+	}
+	if env != Development {
+		t.Errorf("environment = %s, want development", env)
+	}
+}
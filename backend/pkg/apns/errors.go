@@ -0,0 +1,70 @@
+package apns
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Error — ответ APNs с ошибкой. Сравнивается с Err* по причине через errors.Is.
+type Error struct {
+	StatusCode int
+	Reason     string
+	// Timestamp — для 410 Unregistered время, с которого токен недействителен.
+	Timestamp time.Time
+	APNsID    string
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return "apns: " + e.Reason
+	}
+	return fmt.Sprintf("apns: %d %s", e.StatusCode, e.Reason)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Reason == e.Reason
+}
+
+// Причины ошибок APNs, на которые имеет смысл реагировать.
+var (
+	ErrBadDeviceToken         = &Error{Reason: "BadDeviceToken"}
+	ErrUnregistered           = &Error{Reason: "Unregistered"}
+	ErrDeviceTokenNotForTopic = &Error{Reason: "DeviceTokenNotForTopic"}
+	ErrBadTopic               = &Error{Reason: "BadTopic"}
+	ErrTopicDisallowed        = &Error{Reason: "TopicDisallowed"}
+	ErrPayloadTooLarge        = &Error{Reason: "PayloadTooLarge"}
+	ErrExpiredProviderToken   = &Error{Reason: "ExpiredProviderToken"}
+	ErrInvalidProviderToken   = &Error{Reason: "InvalidProviderToken"}
+	ErrTooManyRequests        = &Error{Reason: "TooManyRequests"}
+	ErrInternalServerError    = &Error{Reason: "InternalServerError"}
+	ErrServiceUnavailable     = &Error{Reason: "ServiceUnavailable"}
+)
+
+// parseError разбирает тело ответа APNs: {"reason": "...", "timestamp": <мс>}.
+func parseError(resp *http.Response) error {
+	apnsErr := &Error{
+		StatusCode: resp.StatusCode,
+		APNsID:     resp.Header.Get("apns-id"),
+	}
+
+	var body struct {
+		Reason    string `json:"reason"`
+		Timestamp int64  `json:"timestamp"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := json.Unmarshal(data, &body); err == nil {
+		apnsErr.Reason = body.Reason
+		if body.Timestamp > 0 {
+			apnsErr.Timestamp = time.UnixMilli(body.Timestamp)
+		}
+	}
+	if apnsErr.Reason == "" {
+		apnsErr.Reason = http.StatusText(resp.StatusCode)
+	}
+
+	return apnsErr
+}
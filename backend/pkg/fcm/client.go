@@ -0,0 +1,257 @@
+package fcm
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	DefaultEndpoint = "https://fcm.googleapis.com"
+	DefaultTokenURL = "https://oauth2.googleapis.com/token"
+
+	messagingScope = "https://www.googleapis.com/auth/firebase.messaging"
+	requestTimeout = 30 * time.Second
+	// Токен доступа обновляется заранее, чтобы не истек посреди запроса.
+	tokenRefreshMargin = time.Minute
+)
+
+// serviceAccount — нужные поля JSON-ключа сервисного аккаунта Google.
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+type Client struct {
+	projectID   string
+	clientEmail string
+	privateKey  *rsa.PrivateKey
+
+	endpoint   string
+	tokenURL   string
+	httpClient *http.Client
+
+	tokenMu  sync.Mutex
+	token    string
+	tokenExp time.Time
+}
+
+type Option func(*Client)
+
+// WithEndpoint отправляет сообщения на указанный адрес вместо fcm.googleapis.com.
+func WithEndpoint(endpoint string) Option {
+	return func(c *Client) {
+		c.endpoint = strings.TrimRight(endpoint, "/")
+	}
+}
+
+// WithTokenURL получает токены доступа по указанному адресу вместо token_uri из ключа.
+func WithTokenURL(tokenURL string) Option {
+	return func(c *Client) {
+		c.tokenURL = tokenURL
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// NewClient создает клиент FCM HTTP v1 по JSON-ключу сервисного аккаунта.
+func NewClient(credentialsPath string, opts ...Option) (*Client, error) {
+	data, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var account serviceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+	if account.ProjectID == "" || account.ClientEmail == "" || account.PrivateKey == "" {
+		return nil, fmt.Errorf("credentials file must contain project_id, client_email and private_key")
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	c := &Client{
+		projectID:   account.ProjectID,
+		clientEmail: account.ClientEmail,
+		privateKey:  privateKey,
+		endpoint:    DefaultEndpoint,
+		tokenURL:    account.TokenURI,
+	}
+	if c.tokenURL == "" {
+		c.tokenURL = DefaultTokenURL
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{
+			Timeout: requestTimeout,
+			Transport: &http.Transport{
+				TLSClientConfig:     &tls.Config{MinVersion: tls.VersionTLS12},
+				ForceAttemptHTTP2:   true,
+				MaxIdleConnsPerHost: 10,
+				IdleConnTimeout:     time.Hour,
+			},
+		}
+	}
+
+	return c, nil
+}
+
+// accessToken возвращает OAuth-токен, обменивая подписанный RS256 JWT сервисного аккаунта.
+func (c *Client) accessToken() (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if time.Now().Before(c.tokenExp) {
+		return c.token, nil
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   c.clientEmail,
+		"scope": messagingScope,
+		"aud":   c.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(c.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	resp, err := c.httpClient.PostForm(c.tokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("fcm token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4*1024))
+		return "", fmt.Errorf("fcm token request failed: %d %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode fcm token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("fcm token response has no access_token")
+	}
+
+	c.token = token.AccessToken
+	c.tokenExp = now.Add(time.Duration(token.ExpiresIn)*time.Second - tokenRefreshMargin)
+
+	return c.token, nil
+}
+
+func (c *Client) resetToken() {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.tokenExp = time.Time{}
+}
+
+// Message — сообщение на одно устройство. Без Title и Body отправляется только data.
+type Message struct {
+	Token string
+	Title string
+	Body  string
+	// Badge — число на иконке приложения (поддерживают не все лаунчеры).
+	Badge int
+	Data  map[string]string
+}
+
+// Send отправляет сообщение через FCM HTTP v1.
+func (c *Client) Send(msg Message) error {
+	android := map[string]interface{}{"priority": "high"}
+	message := map[string]interface{}{
+		"token":   msg.Token,
+		"android": android,
+	}
+	if len(msg.Data) > 0 {
+		message["data"] = msg.Data
+	}
+	if msg.Title != "" || msg.Body != "" {
+		message["notification"] = map[string]string{
+			"title": msg.Title,
+			"body":  msg.Body,
+		}
+		if msg.Badge > 0 {
+			android["notification"] = map[string]interface{}{"notification_count": msg.Badge}
+		}
+	} else {
+		// Тихие сообщения с данными не должны будить устройство вне очереди
+		android["priority"] = "normal"
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"message": message})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/v1/projects/%s/messages:send", c.endpoint, c.projectID)
+	err = c.sendRequest(url, body)
+	if errors.Is(err, ErrUnauthenticated) {
+		// Токен отозван или истек раньше срока: получаем новый и пробуем еще раз
+		c.resetToken()
+		err = c.sendRequest(url, body)
+	}
+	return err
+}
+
+func (c *Client) sendRequest(url string, body []byte) error {
+	token, err := c.accessToken()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "Bearer "+token)
+	req.Header.Set("content-type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	return parseError(resp)
+}
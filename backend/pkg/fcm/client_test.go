@@ -0,0 +1,334 @@
+package fcm
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testProjectID   = "love-connection-test"
+	testClientEmail = "push@love-connection-test.iam.gserviceaccount.com"
+	testDeviceToken = "fcm-device-token"
+)
+
+// messageRequest — то, что увидел локальный FCM.
+type messageRequest struct {
+	Path          string
+	Authorization string
+	Body          map[string]interface{}
+}
+
+// fakeFCM — сервер вместо oauth2.googleapis.com и fcm.googleapis.com.
+// respond возвращает статус и тело для n-го сообщения (с 0); токены выдаются всегда.
+type fakeFCM struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu          sync.Mutex
+	assertions  []jwt.MapClaims
+	assertErrs  []error
+	messages    []messageRequest
+	tokenIssued int
+}
+
+func newFakeFCM(t *testing.T, respond func(n int) (int, string)) *fakeFCM {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeFCM{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		claims := jwt.MapClaims{}
+		_, err := jwt.ParseWithClaims(r.PostForm.Get("assertion"), claims, func(token *jwt.Token) (interface{}, error) {
+			return &key.PublicKey, nil
+		}, jwt.WithValidMethods([]string{"RS256"}))
+		if err == nil && r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			err = fmt.Errorf("grant_type = %q", r.PostForm.Get("grant_type"))
+		}
+
+		f.mu.Lock()
+		f.assertions = append(f.assertions, claims)
+		f.assertErrs = append(f.assertErrs, err)
+		f.tokenIssued++
+		n := f.tokenIssued
+		f.mu.Unlock()
+
+		if err != nil {
+			http.Error(w, `{"error": "invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("content-type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "access-token-%d", "expires_in": 3600, "token_type": "Bearer"}`, n)
+	})
+	mux.HandleFunc("/v1/projects/", func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		json.Unmarshal(data, &body)
+
+		f.mu.Lock()
+		n := len(f.messages)
+		f.messages = append(f.messages, messageRequest{Path: r.URL.Path, Authorization: r.Header.Get("authorization"), Body: body})
+		f.mu.Unlock()
+
+		status, response := respond(n)
+		w.Header().Set("content-type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, response)
+	})
+
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeFCM) received() ([]jwt.MapClaims, []error, []messageRequest) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]jwt.MapClaims(nil), f.assertions...),
+		append([]error(nil), f.assertErrs...),
+		append([]messageRequest(nil), f.messages...)
+}
+
+// newTestClient пишет ключ сервисного аккаунта с token_uri локального сервера.
+func newTestClient(t *testing.T, f *fakeFCM) *Client {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(f.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := json.Marshal(serviceAccount{
+		ProjectID:   testProjectID,
+		ClientEmail: testClientEmail,
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    f.server.URL + "/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "service-account.json")
+	if err := os.WriteFile(path, credentials, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(path, WithEndpoint(f.server.URL), WithHTTPClient(f.server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func ok(int) (int, string) {
+	return http.StatusOK, `{"name": "projects/love-connection-test/messages/1"}`
+}
+
+// fcmError — тело ошибки Google API с errorCode из FcmError.
+func fcmError(code int, status, errorCode string) string {
+	details := "[]"
+	if errorCode != "" {
+		details = fmt.Sprintf(`[{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": %q}]`, errorCode)
+	}
+	return fmt.Sprintf(`{"error": {"code": %d, "message": "%s", "status": %q, "details": %s}}`, code, errorCode, status, details)
+}
+
+func TestSendNotification(t *testing.T) {
+	f := newFakeFCM(t, ok)
+	client := newTestClient(t, f)
+
+	err := client.Send(Message{Token: testDeviceToken, Title: "Love Connection", Body: "hi", Badge: 3, Data: map[string]string{"love_event_id": "42"}})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	assertions, assertErrs, messages := f.received()
+	if len(assertions) != 1 {
+		t.Fatalf("got %d token requests, want 1", len(assertions))
+	}
+	if assertErrs[0] != nil {
+		t.Fatalf("assertion is invalid: %v", assertErrs[0])
+	}
+	for claim, want := range map[string]string{
+		"iss":   testClientEmail,
+		"scope": messagingScope,
+		"aud":   f.server.URL + "/token",
+	} {
+		if got, _ := assertions[0][claim].(string); got != want {
+			t.Errorf("assertion %s = %q, want %q", claim, got, want)
+		}
+	}
+
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+	req := messages[0]
+	if req.Path != "/v1/projects/"+testProjectID+"/messages:send" {
+		t.Errorf("path = %s", req.Path)
+	}
+	if req.Authorization != "Bearer access-token-1" {
+		t.Errorf("authorization = %q", req.Authorization)
+	}
+
+	message, _ := req.Body["message"].(map[string]interface{})
+	if message["token"] != testDeviceToken {
+		t.Errorf("token = %v", message["token"])
+	}
+	notification, _ := message["notification"].(map[string]interface{})
+	if notification["title"] != "Love Connection" || notification["body"] != "hi" {
+		t.Errorf("notification = %v", notification)
+	}
+	if data, _ := message["data"].(map[string]interface{}); data["love_event_id"] != "42" {
+		t.Errorf("data = %v", message["data"])
+	}
+	android, _ := message["android"].(map[string]interface{})
+	if android["priority"] != "high" {
+		t.Errorf("android priority = %v, want high", android["priority"])
+	}
+	if badge, _ := android["notification"].(map[string]interface{}); badge["notification_count"] != 3.0 {
+		t.Errorf("android notification = %v", android["notification"])
+	}
+}
+
+func TestSendDataMessage(t *testing.T) {
+	f := newFakeFCM(t, ok)
+	client := newTestClient(t, f)
+
+	if err := client.Send(Message{Token: testDeviceToken, Data: map[string]string{"deleted_love_event_id": "42"}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	_, _, messages := f.received()
+	message, _ := messages[0].Body["message"].(map[string]interface{})
+	if _, ok := message["notification"]; ok {
+		t.Errorf("data message has a notification: %v", message["notification"])
+	}
+	if android, _ := message["android"].(map[string]interface{}); android["priority"] != "normal" {
+		t.Errorf("android priority = %v, want normal", android["priority"])
+	}
+}
+
+func TestAccessTokenCached(t *testing.T) {
+	f := newFakeFCM(t, ok)
+	client := newTestClient(t, f)
+
+	for i := 0; i < 3; i++ {
+		if err := client.Send(Message{Token: testDeviceToken, Body: "hi"}); err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+	}
+
+	assertions, _, messages := f.received()
+	if len(assertions) != 1 {
+		t.Errorf("got %d token requests, want 1", len(assertions))
+	}
+	for i, req := range messages {
+		if req.Authorization != "Bearer access-token-1" {
+			t.Errorf("message %d authorization = %q", i, req.Authorization)
+		}
+	}
+}
+
+func TestUnauthenticatedRefreshesToken(t *testing.T) {
+	f := newFakeFCM(t, func(n int) (int, string) {
+		if n == 0 {
+			return http.StatusUnauthorized, fcmError(http.StatusUnauthorized, "UNAUTHENTICATED", "")
+		}
+		return ok(n)
+	})
+	client := newTestClient(t, f)
+
+	if err := client.Send(Message{Token: testDeviceToken, Body: "hi"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	assertions, _, messages := f.received()
+	if len(assertions) != 2 {
+		t.Errorf("got %d token requests, want 2", len(assertions))
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	if messages[1].Authorization != "Bearer access-token-2" {
+		t.Errorf("retry authorization = %q, want the new token", messages[1].Authorization)
+	}
+
+	// Новый токен закеширован
+	if err := client.Send(Message{Token: testDeviceToken, Body: "hi"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if assertions, _, _ := f.received(); len(assertions) != 2 {
+		t.Errorf("got %d token requests after the retry, want 2", len(assertions))
+	}
+}
+
+func TestUnauthenticatedNotRetriedTwice(t *testing.T) {
+	f := newFakeFCM(t, func(int) (int, string) {
+		return http.StatusUnauthorized, fcmError(http.StatusUnauthorized, "UNAUTHENTICATED", "")
+	})
+	client := newTestClient(t, f)
+
+	err := client.Send(Message{Token: testDeviceToken, Body: "hi"})
+	if !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("err = %v, want ErrUnauthenticated", err)
+	}
+	if _, _, messages := f.received(); len(messages) != 2 {
+		t.Errorf("got %d messages, want 2", len(messages))
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   *Error
+	}{
+		{"unregistered", http.StatusNotFound, fcmError(http.StatusNotFound, "NOT_FOUND", "UNREGISTERED"), ErrUnregistered},
+		{"sender id mismatch", http.StatusForbidden, fcmError(http.StatusForbidden, "PERMISSION_DENIED", "SENDER_ID_MISMATCH"), ErrSenderIDMismatch},
+		{"invalid argument", http.StatusBadRequest, fcmError(http.StatusBadRequest, "INVALID_ARGUMENT", "INVALID_ARGUMENT"), ErrInvalidArgument},
+		{"status without details", http.StatusServiceUnavailable, fcmError(http.StatusServiceUnavailable, "UNAVAILABLE", ""), ErrUnavailable},
+		{"no body", http.StatusBadGateway, "", &Error{Code: http.StatusText(http.StatusBadGateway)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeFCM(t, func(int) (int, string) { return tt.status, tt.body })
+			client := newTestClient(t, f)
+
+			err := client.Send(Message{Token: testDeviceToken, Body: "hi"})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %s", err, tt.want.Code)
+			}
+			var fcmErr *Error
+			if !errors.As(err, &fcmErr) || fcmErr.StatusCode != tt.status {
+				t.Errorf("err = %#v, want status %d", err, tt.status)
+			}
+			if _, _, messages := f.received(); len(messages) != 1 {
+				t.Errorf("got %d messages, want 1", len(messages))
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package fcm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Error — ответ FCM с ошибкой. Сравнивается с Err* по коду через errors.Is.
+type Error struct {
+	StatusCode int
+	// Code — errorCode из FcmError, а если его нет — status ответа Google API.
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return "fcm: " + e.Code
+	}
+	return fmt.Sprintf("fcm: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Коды ошибок FCM HTTP v1, на которые имеет смысл реагировать.
+var (
+	ErrUnregistered     = &Error{Code: "UNREGISTERED"}
+	ErrInvalidArgument  = &Error{Code: "INVALID_ARGUMENT"}
+	ErrSenderIDMismatch = &Error{Code: "SENDER_ID_MISMATCH"}
+	ErrQuotaExceeded    = &Error{Code: "QUOTA_EXCEEDED"}
+	ErrUnavailable      = &Error{Code: "UNAVAILABLE"}
+	ErrInternal         = &Error{Code: "INTERNAL"}
+	ErrThirdPartyAuth   = &Error{Code: "THIRD_PARTY_AUTH_ERROR"}
+	ErrUnauthenticated  = &Error{Code: "UNAUTHENTICATED"}
+	ErrPermissionDenied = &Error{Code: "PERMISSION_DENIED"}
+	ErrNotFound         = &Error{Code: "NOT_FOUND"}
+)
+
+// parseError разбирает тело ошибки Google API:
+// {"error": {"code": 404, "message": "...", "status": "NOT_FOUND", "details": [{"errorCode": "UNREGISTERED"}]}}.
+func parseError(resp *http.Response) error {
+	fcmErr := &Error{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := json.Unmarshal(data, &body); err == nil {
+		fcmErr.Message = body.Error.Message
+		fcmErr.Code = body.Error.Status
+		for _, detail := range body.Error.Details {
+			if detail.ErrorCode != "" {
+				fcmErr.Code = detail.ErrorCode
+				break
+			}
+		}
+	}
+	if fcmErr.Code == "" {
+		fcmErr.Code = http.StatusText(resp.StatusCode)
+	}
+
+	return fcmErr
+}
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage — хранилище файлов (например, архивов выгрузки данных).
+// Чтобы хранить файлы в S3 или другом облаке, достаточно реализовать этот интерфейс
+// и добавить его в NewFromEnv.
+type Storage interface {
+	Put(key string, r io.Reader) error
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// NewFromEnv создает хранилище по переменным окружения с префиксом prefix:
+// <prefix>_STORAGE выбирает бэкенд (по умолчанию local), <prefix>_STORAGE_DIR — каталог для local.
+func NewFromEnv(prefix, defaultDir string) (Storage, error) {
+	kind := os.Getenv(prefix + "_STORAGE")
+	switch kind {
+	case "", "local":
+		dir := os.Getenv(prefix + "_STORAGE_DIR")
+		if dir == "" {
+			dir = defaultDir
+		}
+		return NewLocal(dir)
+	}
+	return nil, fmt.Errorf("unknown %s_STORAGE %q", prefix, kind)
+}
@@ -0,0 +1,70 @@
+package webpush
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress — endpoint ведет во внутреннюю сеть. Endpoint присылает клиент,
+// поэтому без проверки сервер можно заставить слать запросы на свои же внутренние адреса.
+var ErrForbiddenAddress = errors.New("webpush: endpoint address is not public")
+
+// nonPublicPrefixes — диапазоны, которых нет среди IsPrivate/IsLoopback/IsLinkLocal*.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHost отклоняет localhost и IP-адреса внутренних сетей в адресе подписки.
+// Имена хостов окончательно проверяются при соединении: DNS может вернуть любой адрес.
+func CheckHost(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil && !isPublicAddr(addr) {
+		return ErrForbiddenAddress
+	}
+	return nil
+}
+
+// publicDialer соединяется только с публичными адресами. Проверяется адрес, к которому
+// идет соединение после разрешения имени, так что подмена DNS не помогает.
+func publicDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   requestTimeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+			}
+			if !isPublicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+}
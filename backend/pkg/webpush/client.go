@@ -0,0 +1,263 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultTTL     = 24 * time.Hour
+	requestTimeout = 30 * time.Second
+	// Push-сервисы принимают VAPID JWT со сроком не больше суток.
+	vapidTokenTTL = 12 * time.Hour
+)
+
+// Subscription — PushSubscription браузера: endpoint push-сервиса и ключи из getKey("p256dh") и getKey("auth").
+type Subscription struct {
+	Endpoint string
+	P256dh   string
+	Auth     string
+}
+
+// Validate проверяет адрес и ключи подписки.
+func (s Subscription) Validate() error {
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "https" && endpoint.Scheme != "http") {
+		return errors.New("webpush: endpoint must be an absolute URL")
+	}
+	_, _, err = s.keys()
+	return err
+}
+
+func (s Subscription) keys() (p256dh, auth []byte, err error) {
+	p256dh, err = decodeBase64(s.P256dh)
+	if err != nil {
+		return nil, nil, errors.New("webpush: p256dh is not valid base64url")
+	}
+	if _, err := ecdh.P256().NewPublicKey(p256dh); err != nil {
+		return nil, nil, errors.New("webpush: p256dh is not an uncompressed P-256 public key")
+	}
+
+	auth, err = decodeBase64(s.Auth)
+	if err != nil || len(auth) != 16 {
+		return nil, nil, errors.New("webpush: auth must be 16 bytes of base64url")
+	}
+
+	return p256dh, auth, nil
+}
+
+// decodeBase64 принимает base64url с дополнением и без: браузеры и библиотеки отдают оба варианта.
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+type Client struct {
+	privateKey   *ecdsa.PrivateKey
+	publicKey    string
+	subject      string
+	ttl          time.Duration
+	allowPrivate bool
+	httpClient   *http.Client
+}
+
+type Option func(*Client)
+
+// WithTTL задает, сколько push-сервис хранит сообщение для недоступного браузера.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Client) {
+		c.ttl = ttl
+	}
+}
+
+// WithPrivateNetworks разрешает endpoint во внутренних сетях и на localhost — только для локального push-сервиса.
+func WithPrivateNetworks() Option {
+	return func(c *Client) {
+		c.allowPrivate = true
+	}
+}
+
+// WithHTTPClient заменяет HTTP-клиент; проверка адресов при соединении тогда на его стороне.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// NewClient создает клиент по VAPID-ключу: privateKey — 32 байта скаляра в base64url.
+// subject — контакт отправителя (mailto: или https:), который push-сервис может использовать при проблемах.
+func NewClient(privateKey, subject string, opts ...Option) (*Client, error) {
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https:") {
+		return nil, errors.New("webpush: VAPID subject must be a mailto: or https: URL")
+	}
+
+	c := &Client{
+		privateKey: key,
+		publicKey:  encodePublicKey(&key.PublicKey),
+		subject:    subject,
+		ttl:        defaultTTL,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.httpClient == nil {
+		transport := &http.Transport{
+			TLSClientConfig:     &tls.Config{MinVersion: tls.VersionTLS12},
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     time.Hour,
+		}
+		if !c.allowPrivate {
+			transport.DialContext = publicDialer().DialContext
+		}
+		c.httpClient = &http.Client{
+			Timeout:   requestTimeout,
+			Transport: transport,
+			// Push-сервисы не перенаправляют запросы; редирект мог бы увести запрос на другой адрес
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	return c, nil
+}
+
+// PublicKey — VAPID-ключ сервера в base64url; браузер передает его в pushManager.subscribe как applicationServerKey.
+func (c *Client) PublicKey() string {
+	return c.publicKey
+}
+
+// GenerateVAPIDKeys создает новую пару VAPID-ключей в base64url.
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return encodePublicKey(&key.PublicKey), base64.RawURLEncoding.EncodeToString(key.D.FillBytes(make([]byte, 32))), nil
+}
+
+func parsePrivateKey(privateKey string) (*ecdsa.PrivateKey, error) {
+	d, err := decodeBase64(privateKey)
+	if err != nil || len(d) != 32 {
+		return nil, errors.New("webpush: VAPID private key must be 32 bytes of base64url")
+	}
+
+	// ecdh проверяет, что скаляр лежит в допустимом диапазоне, и вычисляет публичный ключ
+	ecdhKey, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("webpush: invalid VAPID private key: %w", err)
+	}
+	public := ecdhKey.PublicKey().Bytes()
+
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(public[1:33]),
+			Y:     new(big.Int).SetBytes(public[33:]),
+		},
+		D: new(big.Int).SetBytes(d),
+	}, nil
+}
+
+func encodePublicKey(key *ecdsa.PublicKey) string {
+	public := make([]byte, 65)
+	public[0] = 0x04
+	key.X.FillBytes(public[1:33])
+	key.Y.FillBytes(public[33:])
+	return base64.RawURLEncoding.EncodeToString(public)
+}
+
+// vapidHeader подписывает JWT для origin push-сервиса (RFC 8292).
+func (c *Client) vapidHeader(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(vapidTokenTTL).Unix(),
+		"sub": c.subject,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, claims).SignedString(c.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("vapid t=%s, k=%s", token, c.publicKey), nil
+}
+
+// Send шифрует payload и отправляет его push-сервису подписки.
+// urgent сообщения (пуши с текстом) push-сервис доставляет сразу, даже если браузер экономит батарею.
+func (c *Client) Send(sub Subscription, payload []byte, urgent bool) error {
+	if !c.allowPrivate {
+		endpoint, err := url.Parse(sub.Endpoint)
+		if err != nil {
+			return err
+		}
+		if err := CheckHost(endpoint.Hostname()); err != nil {
+			return err
+		}
+	}
+
+	body, err := encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := c.vapidHeader(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	urgency := "normal"
+	if urgent {
+		urgency = "high"
+	}
+
+	req.Header.Set("authorization", authorization)
+	req.Header.Set("content-type", "application/octet-stream")
+	req.Header.Set("content-encoding", "aes128gcm")
+	req.Header.Set("ttl", strconv.Itoa(int(c.ttl.Seconds())))
+	req.Header.Set("urgency", urgency)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webpush request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 4*1024))
+	return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(message))}
+}
@@ -0,0 +1,271 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSubject = "mailto:push@example.com"
+
+// pushRequest — то, что увидел локальный push-сервис.
+type pushRequest struct {
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// fakePushService — сервер вместо push-сервиса браузера; отвечает статусом status.
+type fakePushService struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	requests []pushRequest
+}
+
+func newFakePushService(t *testing.T, status int) *fakePushService {
+	t.Helper()
+
+	f := &fakePushService{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		f.mu.Lock()
+		f.requests = append(f.requests, pushRequest{Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+		f.mu.Unlock()
+
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakePushService) received() []pushRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]pushRequest(nil), f.requests...)
+}
+
+func newTestClient(t *testing.T, opts ...Option) *Client {
+	t.Helper()
+
+	_, privateKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(privateKey, testSubject, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// testSubscription — подписка браузера с ключами из RFC 8291, чтобы тест мог расшифровать сообщение.
+func testSubscription(endpoint string) Subscription {
+	return Subscription{Endpoint: endpoint, P256dh: rfcUAPublic, Auth: rfcAuth}
+}
+
+// parseVAPIDPublicKey — публичный ключ из k= заголовка authorization.
+func parseVAPIDPublicKey(t *testing.T, k string) *ecdsa.PublicKey {
+	t.Helper()
+
+	public := mustDecode(t, k)
+	if _, err := ecdh.P256().NewPublicKey(public); err != nil {
+		t.Fatalf("k is not a P-256 key: %v", err)
+	}
+	return &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(public[1:33]),
+		Y:     new(big.Int).SetBytes(public[33:]),
+	}
+}
+
+func TestSendHeaders(t *testing.T) {
+	f := newFakePushService(t, http.StatusCreated)
+	client := newTestClient(t, WithPrivateNetworks(), WithTTL(time.Hour))
+	payload := []byte(`{"title": "Love Connection", "body": "hi"}`)
+
+	before := time.Now()
+	if err := client.Send(testSubscription(f.server.URL+"/push/abc"), payload, true); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	requests := f.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+
+	if req.Path != "/push/abc" {
+		t.Errorf("path = %s", req.Path)
+	}
+	for header, want := range map[string]string{
+		"content-encoding": "aes128gcm",
+		"content-type":     "application/octet-stream",
+		"ttl":              "3600",
+		"urgency":          "high",
+	} {
+		if got := req.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// authorization: vapid t=<JWT>, k=<публичный ключ> (RFC 8292)
+	authorization := req.Header.Get("authorization")
+	params, ok := strings.CutPrefix(authorization, "vapid ")
+	if !ok {
+		t.Fatalf("authorization = %q, want the vapid scheme", authorization)
+	}
+	var token, k string
+	for _, param := range strings.Split(params, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		switch name {
+		case "t":
+			token = value
+		case "k":
+			k = value
+		}
+	}
+	if k != client.PublicKey() {
+		t.Errorf("k = %q, want %q", k, client.PublicKey())
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return parseVAPIDPublicKey(t, k), nil
+	}, jwt.WithValidMethods([]string{"ES256"}))
+	if err != nil {
+		t.Fatalf("VAPID token: %v", err)
+	}
+	if claims["aud"] != f.server.URL {
+		t.Errorf("aud = %v, want %s", claims["aud"], f.server.URL)
+	}
+	if claims["sub"] != testSubject {
+		t.Errorf("sub = %v", claims["sub"])
+	}
+	exp, _ := claims.GetExpirationTime()
+	if exp == nil || exp.After(before.Add(24*time.Hour)) {
+		t.Errorf("exp = %v, want within 24 hours", exp)
+	}
+
+	uaPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcUAPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decrypt(t, req.Body, uaPrivate, mustDecode(t, rfcAuth)); string(got) != string(payload) {
+		t.Errorf("payload = %q, want %q", got, payload)
+	}
+}
+
+func TestSendBackgroundUrgency(t *testing.T) {
+	f := newFakePushService(t, http.StatusCreated)
+	client := newTestClient(t, WithPrivateNetworks())
+
+	if err := client.Send(testSubscription(f.server.URL+"/push/abc"), []byte(`{}`), false); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	req := f.received()[0]
+	if got := req.Header.Get("urgency"); got != "normal" {
+		t.Errorf("urgency = %q, want normal", got)
+	}
+	if got := req.Header.Get("ttl"); got != strconv.Itoa(int(defaultTTL.Seconds())) {
+		t.Errorf("ttl = %q, want the default", got)
+	}
+}
+
+func TestSubscriptionGone(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusGone} {
+		f := newFakePushService(t, status)
+		client := newTestClient(t, WithPrivateNetworks())
+
+		err := client.Send(testSubscription(f.server.URL+"/push/abc"), []byte(`{}`), true)
+		if !errors.Is(err, ErrSubscriptionGone) {
+			t.Errorf("status %d: err = %v, want ErrSubscriptionGone", status, err)
+		}
+		var pushErr *Error
+		if !errors.As(err, &pushErr) || pushErr.StatusCode != status {
+			t.Errorf("status %d: err = %#v", status, err)
+		}
+	}
+}
+
+func TestServerErrorKeepsSubscription(t *testing.T) {
+	f := newFakePushService(t, http.StatusInternalServerError)
+	client := newTestClient(t, WithPrivateNetworks())
+
+	err := client.Send(testSubscription(f.server.URL+"/push/abc"), []byte(`{}`), true)
+	if err == nil || errors.Is(err, ErrSubscriptionGone) {
+		t.Errorf("err = %v, want a retryable error", err)
+	}
+}
+
+func TestPrivateEndpointRefused(t *testing.T) {
+	f := newFakePushService(t, http.StatusCreated)
+	client := newTestClient(t)
+
+	for _, endpoint := range []string{
+		f.server.URL + "/push/abc",
+		"https://localhost/push/abc",
+		"https://169.254.169.254/latest/meta-data",
+		"https://10.0.0.1/push/abc",
+		"https://[::1]/push/abc",
+	} {
+		err := client.Send(testSubscription(endpoint), []byte(`{}`), true)
+		if !errors.Is(err, ErrForbiddenAddress) {
+			t.Errorf("%s: err = %v, want ErrForbiddenAddress", endpoint, err)
+		}
+	}
+	if requests := f.received(); len(requests) != 0 {
+		t.Errorf("push service got %d requests, want none", len(requests))
+	}
+}
+
+// Имя хоста может разрешиться во внутренний адрес, поэтому адрес проверяется еще и при соединении.
+func TestDialerRefusesPrivateAddresses(t *testing.T) {
+	f := newFakePushService(t, http.StatusCreated)
+
+	_, err := publicDialer().Dial("tcp", strings.TrimPrefix(f.server.URL, "http://"))
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("dial %s: err = %v, want ErrForbiddenAddress", f.server.URL, err)
+	}
+	if requests := f.received(); len(requests) != 0 {
+		t.Errorf("push service got %d requests, want none", len(requests))
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for host, want := range map[string]error{
+		"fcm.googleapis.com":                nil,
+		"updates.push.services.mozilla.com": nil,
+		"8.8.8.8":                           nil,
+		"localhost":                         ErrForbiddenAddress,
+		"push.localhost":                    ErrForbiddenAddress,
+		"127.0.0.1":                         ErrForbiddenAddress,
+		"192.168.1.10":                      ErrForbiddenAddress,
+		"172.16.0.1":                        ErrForbiddenAddress,
+		"100.100.100.200":                   ErrForbiddenAddress,
+		"0.0.0.0":                           ErrForbiddenAddress,
+		"::1":                               ErrForbiddenAddress,
+		"fe80::1":                           ErrForbiddenAddress,
+		"fd00::1":                           ErrForbiddenAddress,
+		"::ffff:127.0.0.1":                  ErrForbiddenAddress,
+	} {
+		if err := CheckHost(host); err != want {
+			t.Errorf("CheckHost(%q) = %v, want %v", host, err, want)
+		}
+	}
+}
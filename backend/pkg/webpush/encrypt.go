@@ -0,0 +1,107 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// recordSize — размер записи aes128gcm; все сообщение укладывается в одну запись.
+	recordSize = 4096
+	// headerSize — salt (16) + rs (4) + idlen (1) + ключ сервера (65).
+	headerSize = 16 + 4 + 1 + 65
+	// MaxPayloadSize — сколько байт данных помещается в одну запись вместе с разделителем и тегом GCM.
+	MaxPayloadSize = recordSize - headerSize - 1 - 16
+)
+
+var ErrPayloadTooLarge = errors.New("webpush: payload too large")
+
+// encrypt шифрует payload для подписки по RFC 8291 (Content-Encoding: aes128gcm, RFC 8188).
+func encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	// Одноразовая пара ключей сервера и соль на каждое сообщение
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return encryptWith(sub, payload, salt, asPrivate)
+}
+
+// encryptWith — encrypt с заданными солью и ключом сервера.
+func encryptWith(sub Subscription, payload, salt []byte, asPrivate *ecdh.PrivateKey) ([]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+
+	uaPublicBytes, authSecret, err := sub.keys()
+	if err != nil {
+		return nil, err
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public)
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublicBytes...)
+	keyInfo = append(keyInfo, asPublicBytes...)
+	ikm, err := hkdfRead(authSecret, ecdhSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	cek, err := hkdfRead(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdfRead(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0x02 — разделитель последней записи, без дополнения
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	body := make([]byte, 0, headerSize+len(plaintext)+gcm.Overhead())
+	body = append(body, salt...)
+	body = binary.BigEndian.AppendUint32(body, recordSize)
+	body = append(body, byte(len(asPublicBytes)))
+	body = append(body, asPublicBytes...)
+
+	return gcm.Seal(body, nonce, plaintext, nil), nil
+}
+
+func hkdfRead(salt, secret, info []byte, length int) ([]byte, error) {
+	out := make([]byte, length)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
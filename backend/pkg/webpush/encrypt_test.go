@@ -0,0 +1,135 @@
+package webpush
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// Тестовый вектор из RFC 8291, Appendix A.
+const (
+	rfcPlaintext = "When I grow up, I want to be a watermelon"
+	rfcASPrivate = "yfWPiYE-n46HLnH0KqZOF1fJJU3MYrct3AELtAQ-oRw"
+	rfcUAPublic  = "BCVxsr7N_eNgVRqvHtD0zTZsEc6-VV-JvLexhqUzORcxaOzi6-AYWXvTBHm4bjyPjs7Vd8pZGH6SRpkNtoIAiw4"
+	rfcUAPrivate = "q1dXpw3UpT5VOmu_cf_v6ih07Aems3njxI-JWgLcM94"
+	rfcSalt      = "DGv6ra1nlYgDCS1FRnbzlw"
+	rfcAuth      = "BTBZMqHH6r4Tts7J_aSIgg"
+	rfcMessage   = "DGv6ra1nlYgDCS1FRnbzlwAAEABBBP4z9KsN6nGRTbVYI_c7VJSPQTBtkgcy27mlmlMoZIIgDll6e3vCYLocInmYWAmS6TlzAC8wEqKK6PBru3jl7A_yl95bQpu6cVPTpK4Mqgkf1CXztLVBSt2Ks3oZwbuwXPXLWyouBWLVWGNWQexSgSxsj_Qulcy4a-fN"
+)
+
+func mustDecode(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := decodeBase64(s)
+	if err != nil {
+		t.Fatalf("decode %q: %v", s, err)
+	}
+	return data
+}
+
+func TestEncryptRFC8291Vector(t *testing.T) {
+	asPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcASPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := Subscription{Endpoint: "https://push.example.net/push/JzLQ3raZJfFBR0aqvOMsLrt54w4rJUsV", P256dh: rfcUAPublic, Auth: rfcAuth}
+
+	body, err := encryptWith(sub, []byte(rfcPlaintext), mustDecode(t, rfcSalt), asPrivate)
+	if err != nil {
+		t.Fatalf("encryptWith: %v", err)
+	}
+	if got := base64.RawURLEncoding.EncodeToString(body); got != rfcMessage {
+		t.Errorf("message =\n%s\nwant\n%s", got, rfcMessage)
+	}
+}
+
+// decrypt расшифровывает сообщение на стороне браузера с ключом uaPrivate и секретом auth.
+func decrypt(t *testing.T, body []byte, uaPrivate *ecdh.PrivateKey, auth []byte) []byte {
+	t.Helper()
+
+	if len(body) < headerSize {
+		t.Fatalf("message is %d bytes, shorter than the header", len(body))
+	}
+	salt := body[:16]
+	if rs := binary.BigEndian.Uint32(body[16:20]); rs != recordSize {
+		t.Errorf("record size = %d, want %d", rs, recordSize)
+	}
+	idlen := int(body[20])
+	asPublicBytes := body[21 : 21+idlen]
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		t.Fatalf("keyid is not a P-256 key: %v", err)
+	}
+
+	ecdhSecret, err := uaPrivate.ECDH(asPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyInfo := append([]byte("WebPush: info\x00"), uaPrivate.PublicKey().Bytes()...)
+	keyInfo = append(keyInfo, asPublicBytes...)
+	ikm, _ := hkdfRead(auth, ecdhSecret, keyInfo, 32)
+	cek, _ := hkdfRead(salt, ikm, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce, _ := hkdfRead(salt, ikm, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, body[21+idlen:], nil)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+
+	// Последняя запись заканчивается разделителем 0x02 и, возможно, нулевым дополнением
+	plaintext = bytes.TrimRight(plaintext, "\x00")
+	if len(plaintext) == 0 || plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatalf("record has no last-record delimiter")
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	uaPrivate, err := ecdh.P256().NewPrivateKey(mustDecode(t, rfcUAPrivate))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := Subscription{Endpoint: "https://push.example.net/push/1", P256dh: rfcUAPublic, Auth: rfcAuth}
+	payload := []byte(`{"title": "Love Connection", "body": "hi"}`)
+
+	first, err := encrypt(sub, payload)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	second, err := encrypt(sub, payload)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if bytes.Equal(first[:headerSize], second[:headerSize]) {
+		t.Error("salt and server key are reused between messages")
+	}
+
+	if got := decrypt(t, first, uaPrivate, mustDecode(t, rfcAuth)); !bytes.Equal(got, payload) {
+		t.Errorf("decrypted %q, want %q", got, payload)
+	}
+}
+
+func TestEncryptPayloadTooLarge(t *testing.T) {
+	sub := Subscription{Endpoint: "https://push.example.net/push/1", P256dh: rfcUAPublic, Auth: rfcAuth}
+
+	payload := make([]byte, MaxPayloadSize)
+	rand.Read(payload)
+	body, err := encrypt(sub, payload)
+	if err != nil {
+		t.Fatalf("encrypt of %d bytes: %v", len(payload), err)
+	}
+	if len(body) != recordSize {
+		t.Errorf("message is %d bytes, want one full record of %d", len(body), recordSize)
+	}
+
+	if _, err := encrypt(sub, append(payload, 0)); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Errorf("err = %v, want ErrPayloadTooLarge", err)
+	}
+}
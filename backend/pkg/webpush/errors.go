@@ -0,0 +1,27 @@
+package webpush
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrSubscriptionGone — push-сервис ответил 404 или 410: подписка удалена или истекла, слать на нее больше нельзя.
+var ErrSubscriptionGone = errors.New("webpush: subscription is gone")
+
+// Error — ответ push-сервиса с ошибкой.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("webpush: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("webpush: %d %s", e.StatusCode, e.Message)
+}
+
+func (e *Error) Is(target error) bool {
+	return target == ErrSubscriptionGone && (e.StatusCode == http.StatusNotFound || e.StatusCode == http.StatusGone)
+}
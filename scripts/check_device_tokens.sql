@@ -5,11 +5,12 @@ SELECT
     u.username,
     u.email,
     CASE
-        WHEN u.device_token IS NULL OR u.device_token = '' THEN 'NO TOKEN'
+        WHEN COUNT(d.id) = 0 THEN 'NO TOKEN'
         ELSE 'HAS TOKEN'
     END as token_status,
-    LENGTH(u.device_token) as token_length,
-    LEFT(u.device_token, 20) || '...' as token_preview
+    COUNT(d.id) as active_devices,
+    STRING_AGG(LEFT(d.token, 20) || '... (' || d.platform || ', ' || d.environment || ')', E'\n') as token_preview
 FROM users u
+LEFT JOIN devices d ON d.user_id = u.id AND d.active
+GROUP BY u.id, u.username, u.email
 ORDER BY u.username;
-